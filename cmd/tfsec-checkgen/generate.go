@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	generateFrom        string
+	generateFromGood    string
+	generateCode        string
+	generateDescription string
+	generateSeverity    string
+	generateOutput      string
+)
+
+func init() {
+	generateCmd.Flags().StringVar(&generateFrom, "from", "", "Terraform file or directory containing resources which should fail the check")
+	generateCmd.Flags().StringVar(&generateFromGood, "from-good", "", "Terraform file or directory containing resources which should pass the check")
+	generateCmd.Flags().StringVar(&generateCode, "code", "CUS001", "Code of the generated check")
+	generateCmd.Flags().StringVar(&generateDescription, "description", "", "Description of the generated check")
+	generateCmd.Flags().StringVar(&generateSeverity, "severity", string(severity.Medium), "Severity of the generated check")
+	generateCmd.Flags().StringVar(&generateOutput, "output", "", "Write the generated check to the given _tfchecks.yaml file rather than to stdout")
+	_ = generateCmd.MarkFlagRequired("from")
+	_ = generateCmd.MarkFlagRequired("from-good")
+	rootCmd.AddCommand(generateCmd)
+}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate --from bad.tf --from-good good.tf",
+	Short: "Generate a custom check from examples of bad and good terraform",
+	Long: `Compare the evaluated attributes and nested blocks of the resources in the bad and good terraform, and propose a check
+with the requiredTypes, requiredLabels and matchSpec which distinguish them. The generated check is validated before it is written,
+and should be reviewed and given a meaningful description before it is used.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := generate(); err != nil {
+			fmt.Fprint(os.Stderr, err)
+			os.Exit(-1)
+		}
+		os.Exit(0)
+	},
+}
+
+func generate() error {
+	check, err := custom.Generate(generateCode, generateFrom, generateFromGood)
+	if err != nil {
+		return err
+	}
+	if generateDescription != "" {
+		check.Description = generateDescription
+	}
+	check.Severity = severity.StringToSeverity(generateSeverity)
+
+	content, err := yaml.Marshal(custom.ChecksFile{Checks: []*custom.Check{check}})
+	if err != nil {
+		return err
+	}
+
+	if generateOutput != "" {
+		if ext := strings.ToLower(filepath.Ext(generateOutput)); ext != ".yaml" && ext != ".yml" {
+			return fmt.Errorf("the generated check can only be written to a .yaml or .yml file")
+		}
+		if err := ioutil.WriteFile(generateOutput, content, 0600); err != nil {
+			return err
+		}
+		if err := custom.Validate(generateOutput); err != nil {
+			return fmt.Errorf("the generated check written to %s is not valid:\n%w", generateOutput, err)
+		}
+		fmt.Printf("Check %s written to %s\n", check.Code, generateOutput)
+		return nil
+	}
+
+	// the check is validated from a temporary file, as it would be loaded by tfsec
+	file, err := ioutil.TempFile("", "*_tfchecks.yaml")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := custom.Validate(file.Name()); err != nil {
+		return fmt.Errorf("the generated check is not valid:\n%w", err)
+	}
+	fmt.Print(string(content))
+	return nil
+}
@@ -21,9 +21,9 @@ func main() {
 
 var rootCmd = &cobra.Command{
 	Use:   "tfsec-checkgen",
-	Short: "tfsec-checkgen is a tfsec tool for generating and validating custom check files.",
+	Short: "tfsec-checkgen is a tfsec tool for generating, validating and testing custom check files.",
 	Long: `tfsec is a simple tool for generating and validating custom checks file.
-Custom checks are defined as json and stored in the .tfsec directory of the folder being checked.
+Custom checks are defined as json, yaml or hcl and stored in the .tfsec directory of the folder being checked.
 `,
 }
 
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/defsec/formatters"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
+	"github.com/spf13/cobra"
+)
+
+var junitPath string
+
+func init() {
+	testCmd.Flags().StringVar(&junitPath, "junit", "", "Write the test results as JUnit XML to the given file")
+	rootCmd.AddCommand(testCmd)
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test [checkfile...]",
+	Short: "Test custom checks against their fixtures",
+	Long: `Run each custom check against its inline goodExamples and badExamples, and against the fixtures in
+testdata/<code>/pass and testdata/<code>/fail beside the check file, which should pass and fail the check respectively.
+Each .tf file in those directories is a separate fixture, while each subdirectory is a fixture made up of all of its files.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var results []custom.TestResult
+		for _, checkFile := range args {
+			fileResults, err := custom.RunTests(checkFile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				os.Exit(-1)
+			}
+			results = append(results, fileResults...)
+		}
+
+		failed := printTestResults(os.Stdout, results)
+
+		if junitPath != "" {
+			if err := writeJUnit(junitPath, results); err != nil {
+				fmt.Fprint(os.Stderr, err)
+				os.Exit(-1)
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+// printTestResults writes a line for each test, with the failures of any which did not behave as expected, returning how many failed
+func printTestResults(w io.Writer, results []custom.TestResult) int {
+	var failed int
+	for _, result := range results {
+		if result.Passed() {
+			_, _ = fmt.Fprintf(w, "PASS %s %s\n", result.Code, result.Fixture)
+			continue
+		}
+		failed++
+		_, _ = fmt.Fprintf(w, "FAIL %s %s\n  %s\n", result.Code, result.Fixture, testFailureMessage(result))
+		for _, failure := range result.Failures {
+			_, _ = fmt.Fprintf(w, "    %s\n", failure)
+		}
+	}
+	if len(results) == 0 {
+		_, _ = fmt.Fprintln(w, "No tests were found")
+		return 0
+	}
+	_, _ = fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
+
+func testFailureMessage(result custom.TestResult) string {
+	switch {
+	case result.Err != nil:
+		return fmt.Sprintf("the fixture could not be scanned: %s", result.Err)
+	case result.ExpectFailure:
+		return "expected the check to fail, but it passed"
+	default:
+		return fmt.Sprintf("expected the check to pass, but it failed %d time(s)", len(result.Failures))
+	}
+}
+
+// writeJUnit writes a test case for each test, named by fixture and grouped by check code
+func writeJUnit(path string, results []custom.TestResult) error {
+	suite := formatters.JUnitTestSuite{
+		Name: "tfsec-checkgen",
+	}
+	var failed int
+	for _, result := range results {
+		testCase := formatters.JUnitTestCase{
+			Classname: result.Code,
+			Name:      result.Fixture,
+			Time:      "0",
+		}
+		if !result.Passed() {
+			failed++
+			testCase.Failure = &formatters.JUnitFailure{
+				Message:  testFailureMessage(result),
+				Contents: strings.Join(result.Failures, "\n"),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = fmt.Sprintf("%d", len(results))
+	suite.Failures = fmt.Sprintf("%d", failed)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := file.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "\t")
+	return encoder.Encode(suite)
+}
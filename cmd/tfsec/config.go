@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configShowCmd.Flags().StringVar(&configFile, "config-file", configFile, "Config file to show, instead of discovering config files from the directory")
+	configValidateCmd.Flags().StringVar(&configFile, "config-file", configFile, "Config file to validate, instead of discovering config files from the directory")
+	configValidateCmd.Flags().StringVar(&customCheckDir, "custom-check-dir", customCheckDir, "Explicitly the custom checks dir location")
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// scanDirectoryArgs keeps a directory named after a subcommand, such as config, being scanned as it was before tfsec had subcommands.
+// When the directory exists and is the only positional argument, it is given as a path so it is not taken as the subcommand.
+func scanDirectoryArgs(args []string) []string {
+	positional := positionalArgs(args)
+	if len(positional) != 1 {
+		return args
+	}
+	name := args[positional[0]]
+	if !isSubcommand(name) {
+		return args
+	}
+	if info, err := os.Stat(name); err != nil || !info.IsDir() {
+		return args
+	}
+	rewritten := append([]string{}, args...)
+	rewritten[positional[0]] = "." + string(filepath.Separator) + name
+	return rewritten
+}
+
+// positionalArgs returns the indexes of the arguments which are not flags or the values of flags
+func positionalArgs(args []string) []int {
+	var positional []int
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			for j := i + 1; j < len(args); j++ {
+				positional = append(positional, j)
+			}
+			return positional
+		case strings.HasPrefix(arg, "--"):
+			if flag := rootCmd.Flags().Lookup(strings.TrimPrefix(arg, "--")); flag != nil && flag.NoOptDefVal == "" {
+				i++
+			}
+		case len(arg) == 2 && arg[0] == '-':
+			if flag := rootCmd.Flags().ShorthandLookup(arg[1:]); flag != nil && flag.NoOptDefVal == "" {
+				i++
+			}
+		case strings.HasPrefix(arg, "-"):
+		default:
+			positional = append(positional, i)
+		}
+	}
+	return positional
+}
+
+func isSubcommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect tfsec configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [directory]",
+	Short: "Show the effective config for a directory, merged from every config file which applies to it",
+	Long: `Show the effective config for a directory, merged from the config files found in the .tfsec directories of the directory
+and its parents up to the repository root, along with any files they extend. Each value is followed by the file it came from.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := os.Getwd()
+		if len(args) == 1 {
+			dir, err = filepath.Abs(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		conf, err := loadConfig(dir)
+		if err != nil {
+			return err
+		}
+		return config.WriteWithOrigins(os.Stdout, conf)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [directory]",
+	Short: "Check the config files for a directory for errors, exiting with a non-zero status if any problems are found",
+	Long: `Check the config files for a directory, any files they extend and any ignores.yml files, for unknown keys, invalid values
+and severities, and rule IDs which do not match any built in check or custom check, loaded from the .tfsec directory unless
+--custom-check-dir is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := os.Getwd()
+		if len(args) == 1 {
+			dir, err = filepath.Abs(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		configFiles := config.DiscoverConfigFiles(dir)
+		if len(configFile) > 0 {
+			configFiles = []string{configFile}
+		}
+		ignoreFiles := config.DiscoverIgnoreFiles(dir)
+		if len(configFiles) == 0 && len(ignoreFiles) == 0 {
+			fmt.Println("No config files found.")
+			return nil
+		}
+
+		// custom checks are loaded as they are for a scan, so rule IDs which refer to them are known. Problems in the config itself
+		// are reported by config.Validate, so the checks are loaded without its parameters and path overrides if it cannot be loaded.
+		if tfsecConfig, err = config.LoadConfigs(configFiles); err != nil {
+			tfsecConfig = &config.Config{}
+		}
+		knownRules, err := custom.Load(getCustomCheckDir(dir), getCustomCheckOptions()...)
+		if err != nil {
+			return fmt.Errorf("failed to load custom checks: %w", err)
+		}
+		if pathScopes, err = getPathScopes(dir); err != nil {
+			return fmt.Errorf("failed to load custom checks: %w", err)
+		}
+		knownRules = append(knownRules, scopedRules()...)
+
+		problems := config.Validate(configFiles, ruleMatcher(knownRules))
+		problems = append(problems, config.ValidateIgnoreFiles(ignoreFiles, ruleMatcher(knownRules))...)
+		for _, problem := range problems {
+			fmt.Println(problem.Error())
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("No problems found in %d config file(s).\n", len(configFiles)+len(ignoreFiles))
+		return nil
+	},
+}
+
+// ruleMatcher returns a matcher for rule IDs, legacy IDs and patterns used in config files, which knows about the registered checks and the given custom checks
+func ruleMatcher(customRules []rule.Rule) config.RuleMatcher {
+	return func(id string) bool {
+		if _, err := scanner.GetRuleById(id); err == nil {
+			return true
+		}
+		if _, err := scanner.GetRuleByLegacyID(id); err == nil {
+			return true
+		}
+		for _, r := range append(scanner.GetRegisteredRules(), customRules...) {
+			if scanner.MatchRuleID(id, r.ID()) || (r.LegacyID != "" && scanner.MatchRuleID(id, r.LegacyID)) {
+				return true
+			}
+		}
+		for _, r := range []rules.Rule{scanner.UnusedIgnoreRule, scanner.ExpiredIgnoreRule, scanner.UnknownIgnoreRule} {
+			if scanner.MatchRuleID(id, r.LongID()) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoriesNamedAfterSubcommandsAreScanned(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() { _ = os.Chdir(wd) }()
+	require.NoError(t, os.Chdir(t.TempDir()))
+	require.NoError(t, os.Mkdir("config", 0700))
+
+	dir := "." + string(filepath.Separator) + "config"
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{args: []string{"config"}, expected: []string{dir}},
+		{args: []string{"--format", "json", "config"}, expected: []string{"--format", "json", dir}},
+		{args: []string{"-f", "json", "config", "--soft-fail"}, expected: []string{"-f", "json", dir, "--soft-fail"}},
+		{args: []string{"config", "show"}, expected: []string{"config", "show"}},
+		{args: []string{"completion"}, expected: []string{"completion"}},
+		{args: []string{"modules"}, expected: []string{"modules"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, scanDirectoryArgs(test.args), test.args)
+	}
+}
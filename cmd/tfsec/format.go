@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/aquasecurity/defsec/formatters"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+	"github.com/owenrumney/go-sarif/v2/sarif"
+)
+
+type jsonOutput struct {
+	Results []jsonResult `json:"results"`
+}
+
+// jsonResult adds why a result was ignored to the flattened result, for results included by --include-ignored
+type jsonResult struct {
+	rules.FlatResult
+	Suppression *scanner.Suppression `json:"suppression,omitempty"`
+}
+
+// formatJSON writes results as formatters.FormatJSON does, with the suppression of any ignored results
+func formatJSON(w io.Writer, results []rules.Result, baseDir string, options ...formatters.FormatterOption) error {
+	if len(suppressions) == 0 {
+		return formatters.FormatJSON(w, results, baseDir, options...)
+	}
+	output := jsonOutput{}
+	for _, result := range results {
+		output.Results = append(output.Results, jsonResult{
+			FlatResult:  result.Flatten(),
+			Suppression: suppressions.Lookup(result),
+		})
+	}
+	jsonWriter := json.NewEncoder(w)
+	jsonWriter.SetIndent("", "\t")
+	return jsonWriter.Encode(output)
+}
+
+// formatSarif writes results as formatters.FormatSarif does, adding a suppression with its justification to any ignored results,
+// and the compliance tags of custom checks to their rules
+func formatSarif(w io.Writer, results []rules.Result, baseDir string, options ...formatters.FormatterOption) error {
+	tags := complianceTags(append(customRules, scopedRules()...))
+	if len(suppressions) == 0 && len(tags) == 0 {
+		return formatters.FormatSarif(w, results, baseDir, options...)
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := formatters.FormatSarif(buffer, results, baseDir, options...); err != nil {
+		return err
+	}
+	report, err := sarif.FromBytes(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// the report has a result for each failed result, in the same order
+	var failed []rules.Result
+	for _, result := range results {
+		if result.Status() != rules.StatusPassed {
+			failed = append(failed, result)
+		}
+	}
+	for _, run := range report.Runs {
+		for _, sarifRule := range run.Tool.Driver.Rules {
+			if ruleTags, ok := tags[sarifRule.ID]; ok {
+				if sarifRule.Properties == nil {
+					sarifRule.Properties = sarif.Properties{}
+				}
+				sarifRule.Properties["tags"] = ruleTags
+			}
+		}
+		for i, sarifResult := range run.Results {
+			if i >= len(failed) {
+				break
+			}
+			if suppression := suppressions.Lookup(failed[i]); suppression != nil {
+				sarifSuppression := sarif.NewSuppression(suppression.Kind).WithStatus("accepted")
+				if suppression.Reason != "" {
+					sarifSuppression.WithJustifcation(suppression.Reason)
+				}
+				sarifResult.AddSuppression(sarifSuppression)
+			}
+		}
+	}
+	return report.PrettyWrite(w)
+}
+
+// complianceTags returns the compliance tags of each rule which has them, keyed by long ID
+func complianceTags(candidates []rule.Rule) map[string][]string {
+	tags := make(map[string][]string)
+	for _, r := range candidates {
+		if len(r.ComplianceTags) > 0 {
+			tags[r.ID()] = r.ComplianceTags
+		}
+	}
+	return tags
+}
@@ -6,19 +6,24 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aquasecurity/defsec/formatters"
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/aquasecurity/defsec/rules"
 	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/cache"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/gate"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/ignores"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
 	_ "github.com/aquasecurity/tfsec/internal/app/tfsec/rules"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/settings"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/updater"
+	"github.com/aquasecurity/tfsec/pkg/rule"
 	"github.com/aquasecurity/tfsec/version"
 	"github.com/liamg/tml"
 	"github.com/spf13/cobra"
@@ -46,32 +51,48 @@ var ignoreWarnings = false
 var ignoreInfo = false
 var allDirs = false
 var migrateIgnores = false
+var addIgnores = false
+var ignoreExpiry string
+var ignoreReason string
 var runStatistics bool
 var ignoreHCLErrors bool
 var stopOnCheckError bool
 var workspace string
 var passingGif bool
 var singleThreadedMode bool
+var useCache bool
+var cacheDir string
+var debugEnabled bool
+var gateThresholds []string
+var reportIgnores bool
+var customRules []rule.Rule
+var pathScopes []scanner.PathScope
+var ignoreFiles []string
+var ignorePolicies []scanner.IgnorePolicy
+var suppressions scanner.Suppressions
 
 func init() {
-	rootCmd.Flags().BoolVar(&singleThreadedMode, "single-thread", singleThreadedMode, "Run checks using a single thread")
+	rootCmd.Flags().BoolVar(&singleThreadedMode, "single-thread", singleThreadedMode, "Run parsing and checks using a single thread")
 	rootCmd.Flags().BoolVar(&ignoreHCLErrors, "ignore-hcl-errors", ignoreHCLErrors, "Stop and report an error if an HCL parse error is encountered")
 	rootCmd.Flags().BoolVar(&disableColours, "no-colour", disableColours, "Disable coloured output")
 	rootCmd.Flags().BoolVar(&disableColours, "no-color", disableColours, "Disable colored output (American style!)")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", showVersion, "Show version information and exit")
 	rootCmd.Flags().BoolVar(&runUpdate, "update", runUpdate, "Update to latest version")
 	rootCmd.Flags().BoolVar(&migrateIgnores, "migrate-ignores", migrateIgnores, "Migrate ignore codes to the new ID structure")
+	rootCmd.Flags().BoolVar(&addIgnores, "add-ignores", addIgnores, "Add an ignore comment above the code causing each current result, or above the module call for results within modules")
+	rootCmd.Flags().StringVar(&ignoreExpiry, "expiry", ignoreExpiry, "Expiry date, in the form YYYY-MM-DD, for the ignores added by --add-ignores")
+	rootCmd.Flags().StringVar(&ignoreReason, "reason", ignoreReason, "Reason for the ignores added by --add-ignores")
 	rootCmd.Flags().StringVarP(&format, "format", "f", format, "Select output format: default, json, csv, checkstyle, junit, sarif")
-	rootCmd.Flags().StringVarP(&excludedRuleIDs, "exclude", "e", excludedRuleIDs, "Provide comma-separated list of rule IDs to exclude from run.")
-	rootCmd.Flags().StringVar(&filterResults, "filter-results", filterResults, "Filter results to return specific checks only (supports comma-delimited input).")
+	rootCmd.Flags().StringVarP(&excludedRuleIDs, "exclude", "e", excludedRuleIDs, "Provide comma-separated list of rule IDs to exclude from run. IDs may contain wildcards, e.g. aws-s3-*")
+	rootCmd.Flags().StringVar(&filterResults, "filter-results", filterResults, "Filter results to return specific checks only (supports comma-delimited input and wildcards, e.g. aws-s3-*).")
 	rootCmd.Flags().BoolVarP(&softFail, "soft-fail", "s", softFail, "Runs checks but suppresses error code")
 	rootCmd.Flags().StringSliceVar(&tfvarsPaths, "tfvars-file", tfvarsPaths, "Path to .tfvars file, can be used multiple times and evaluated in order of specification")
 	rootCmd.Flags().StringSliceVar(&excludePaths, "exclude-path", excludePaths, "Folder path to exclude, can be used multiple times and evaluated in order of specification")
 	rootCmd.Flags().StringVar(&outputFlag, "out", outputFlag, "Set output file")
 	rootCmd.Flags().StringVar(&customCheckDir, "custom-check-dir", customCheckDir, "Explicitly the custom checks dir location")
 	rootCmd.Flags().StringVar(&configFile, "config-file", configFile, "Config file to use during run")
-	rootCmd.Flags().BoolVar(&debug.Enabled, "debug", debug.Enabled, "Enable debug logging (same as verbose)")
-	rootCmd.Flags().BoolVar(&debug.Enabled, "verbose", debug.Enabled, "Enable verbose logging (same as debug)")
+	rootCmd.Flags().BoolVar(&debugEnabled, "debug", debugEnabled, "Enable debug logging (same as verbose)")
+	rootCmd.Flags().BoolVar(&debugEnabled, "verbose", debugEnabled, "Enable verbose logging (same as debug)")
 	rootCmd.Flags().BoolVar(&conciseOutput, "concise-output", conciseOutput, "Reduce the amount of output and no statistics")
 	rootCmd.Flags().BoolVar(&excludeDownloaded, "exclude-downloaded-modules", excludeDownloaded, "Remove results for downloaded modules in .terraform folder")
 	rootCmd.Flags().BoolVar(&detailedExitCode, "detailed-exit-code", detailedExitCode, "Produce more detailed exit status codes.")
@@ -83,10 +104,15 @@ func init() {
 	rootCmd.Flags().BoolVar(&ignoreInfo, "ignore-info", ignoreWarnings, "[DEPRECATED] Don't show info results in the output.")
 	rootCmd.Flags().BoolVarP(&stopOnCheckError, "allow-checks-to-panic", "p", stopOnCheckError, "Allow panics to propagate up from rule checking")
 	rootCmd.Flags().StringVarP(&workspace, "workspace", "w", workspace, "Specify a workspace for ignore limits")
+	rootCmd.Flags().BoolVar(&useCache, "cache", useCache, "Reuse results, or the values of unchanged modules, from previous scans")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", cacheDir, "Directory to store cached results in (defaults to .tfsec/cache in the scanned directory)")
+	rootCmd.Flags().StringSliceVar(&gateThresholds, "gate", gateThresholds, "Fail when a threshold is exceeded, given as SEVERITY=max, provider:NAME=max or rule:ID=max, e.g. CRITICAL=0,HIGH=5. Overrides thresholds set in the config gate")
+	rootCmd.Flags().BoolVar(&reportIgnores, "report-ignores", reportIgnores, "Report ignore comments and ignore file entries which are unused, expired or refer to unknown rules")
 	rootCmd.Flags().BoolVar(&passingGif, "gif", passingGif, "Show a celebratory gif in the terminal if no problems are found (default formatter only)")
 }
 
 func main() {
+	rootCmd.SetArgs(scanDirectoryArgs(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -103,8 +129,13 @@ var rootCmd = &cobra.Command{
 	Use:   "tfsec [directory]",
 	Short: "tfsec is a terraform security scanner",
 	Long:  `tfsec is a simple tool to detect potential security vulnerabilities in your terraformed infrastructure.`,
+	Args:  cobra.MaximumNArgs(1),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 
+		if debugEnabled {
+			debug.Enable(os.Stdout)
+		}
+
 		// disable colour if running on windows - colour formatting doesn't work
 		if disableColours || runtime.GOOS == "windows" {
 			debug.Log("Disabled formatting.")
@@ -187,48 +218,49 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		tfsecDir := fmt.Sprintf("%s/.tfsec", dir)
-
-		if len(configFile) > 0 {
-			tfsecConfig, err = loadConfigFile(configFile)
-			if err != nil {
-				return err
-			}
-		} else {
-			jsonConfigFile := fmt.Sprintf("%s/%s", tfsecDir, "config.json")
-			yamlConfigFile := fmt.Sprintf("%s/%s", tfsecDir, "config.yml")
-			if _, err = os.Stat(jsonConfigFile); err == nil {
-				tfsecConfig, err = loadConfigFile(jsonConfigFile)
-				if err != nil {
-					return err
-				}
-			} else if _, err = os.Stat(yamlConfigFile); err == nil {
-				tfsecConfig, err = loadConfigFile(yamlConfigFile)
-				if err != nil {
-					return err
-				}
-			} else {
-				tfsecConfig = &config.Config{}
-			}
+		tfsecConfig, err = loadConfig(dir)
+		if err != nil {
+			return err
 		}
 
 		debug.Log("Loading custom checks...")
-		if len(customCheckDir) == 0 {
-			debug.Log("Using the default custom check folder")
-			customCheckDir = tfsecDir
-		}
+		customCheckDir = getCustomCheckDir(dir)
 		debug.Log("custom check directory set to %s", customCheckDir)
-		err = custom.Load(customCheckDir)
+		customRules, err = custom.Load(customCheckDir, getCustomCheckOptions()...)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "There were errors while processing custom check files. %s", err)
+			os.Exit(1)
+		}
+		pathScopes, err = getPathScopes(dir)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "There were errors while processing custom check files. %s", err)
 			os.Exit(1)
 		}
 		debug.Log("Custom checks loaded")
 
+		ignoreFiles = config.DiscoverIgnoreFiles(dir)
+		ignorePolicies, err = getIgnorePolicies(ignoreFiles)
+		if err != nil {
+			return err
+		}
+
+		gateConfig, err := gate.Override(tfsecConfig.Gate, gateThresholds)
+		if err != nil {
+			return err
+		}
+
+		for _, problem := range config.Validate(tfsecConfig.Files(), ruleMatcher(append(customRules, scopedRules()...))) {
+			_, _ = fmt.Fprintf(os.Stderr, "WARNING: %s\n", problem)
+		}
+
 		if len(filterResults) > 0 {
 			filterResultsList = strings.Split(filterResults, ",")
 		}
 
+		if addIgnores {
+			return runAddIgnores(dir, filterResultsList)
+		}
+
 		formats := strings.Split(format, ",")
 		if outputFlag != "" {
 			if format == "" {
@@ -294,35 +326,15 @@ var rootCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Warning: A tfvars file was found but not automatically used. Did you mean to specify the --tfvars-file flag?\n")
 		}
 
-		debug.Log("Starting parser...")
-		modules, err := parser.New(dir, getParserOptions()...).ParseDirectory()
+		results, err := getResults(dir)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		debug.Log("Starting scanner...")
-		results, err := scanner.New(getScannerOptions()...).Scan(modules)
-		if err != nil {
-			return fmt.Errorf("fatal error during scan: %s", err)
-		}
-		results = updateResultSeverity(results)
-		results = removeExcludedResults(results, ignoreWarnings, excludeDownloaded)
-		if len(filterResultsList) > 0 {
-			var filteredResult []rules.Result
-			for _, result := range results {
-				for _, ruleID := range filterResultsList {
-					if result.Rule().LongID() == ruleID {
-						filteredResult = append(filteredResult, result)
-					}
-				}
-			}
-			results = filteredResult
+			return err
 		}
+		results = filterResultsByRule(removeExcludedResults(results, ignoreWarnings, excludeDownloaded), filterResultsList)
 
 		metrics.Counter("counts", "blocks").Increment(0)
 		metrics.Counter("counts", "modules").Increment(0)
-		metrics.Counter("counts", "files").Increment(parser.CountFiles())
+		metrics.Counter("counts", "files").Increment(0)
 		metrics.Counter("results", "critical")
 		metrics.Counter("results", "high")
 		metrics.Counter("results", "medium")
@@ -351,12 +363,28 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
+		// If a gate is set, it decides whether the scan passed instead of the
+		// default behaviour below, and explains its decision.
+		var outcome *gate.Outcome
+		if gateConfig.IsSet() {
+			evaluated := gate.New(gateConfig, append(customRules, scopedRules()...)).Evaluate(results, suppressions)
+			evaluated.WriteSummary(os.Stderr)
+			outcome = &evaluated
+		}
+
 		// Soft fail always takes precedence. If set, only execution errors
 		// produce a failure exit code (1).
 		if softFail {
 			return nil
 		}
 
+		if outcome != nil {
+			if !outcome.Passed() {
+				os.Exit(1)
+			}
+			return nil
+		}
+
 		if detailedExitCode {
 			os.Exit(getDetailedExitCode(results))
 		}
@@ -372,6 +400,175 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+func getResults(dir string) (rules.Results, error) {
+
+	var resultCache *cache.Cache
+	var cacheInputs cache.Inputs
+	if useCache {
+		resultCache, cacheInputs = getCache(dir)
+		if results, cachedSuppressions, ok := resultCache.Load(cacheInputs); ok {
+			suppressions = cachedSuppressions
+			return results, nil
+		}
+	}
+
+	debug.Log("Starting parser...")
+	parserOptions := getParserOptions()
+	if resultCache != nil {
+		// modules which are unaffected by whatever changed since the cached results are not evaluated again
+		parserOptions = append(parserOptions, parser.OptionWithValueCache(resultCache))
+	}
+	p := parser.New(dir, parserOptions...)
+	modules, err := p.ParseDirectory()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	metrics.Counter("counts", "files").Increment(p.CountFiles())
+
+	debug.Log("Starting scanner...")
+	results, scanSuppressions, err := scanner.New(getScannerOptions(dir)...).ScanWithSuppressions(modules)
+	if err != nil {
+		return nil, fmt.Errorf("fatal error during scan: %s", err)
+	}
+	suppressions = scanSuppressions
+
+	if resultCache != nil {
+		if err := resultCache.Store(cacheInputs, results, suppressions, p.ParsedFiles()); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "WARNING: Failed to write to cache: %s\n", err)
+		}
+	}
+
+	return results, nil
+}
+
+// filterResultsByRule keeps only the results for rules matching any of the given IDs or patterns, if any are given
+func filterResultsByRule(results rules.Results, filterResultsList []string) rules.Results {
+	if len(filterResultsList) == 0 {
+		return results
+	}
+	var filteredResult rules.Results
+	for _, result := range results {
+		for _, ruleID := range filterResultsList {
+			if scanner.MatchRuleID(strings.TrimSpace(ruleID), result.Rule().LongID()) {
+				filteredResult = append(filteredResult, result)
+				break
+			}
+		}
+	}
+	return filteredResult
+}
+
+// runAddIgnores scans the directory and adds an ignore comment for each result, limited to the given rules if any
+func runAddIgnores(dir string, filterResultsList []string) error {
+	var expiry *time.Time
+	if ignoreExpiry != "" {
+		parsed, err := time.Parse("2006-01-02", ignoreExpiry)
+		if err != nil {
+			return fmt.Errorf("invalid expiry '%s', must be in the form YYYY-MM-DD", ignoreExpiry)
+		}
+		expiry = &parsed
+	}
+
+	p := parser.New(dir, getParserOptions()...)
+	modules, err := p.ParseDirectory()
+	if err != nil {
+		return err
+	}
+	results, err := scanner.New(getScannerOptions(dir)...).Scan(modules)
+	if err != nil {
+		return fmt.Errorf("fatal error during scan: %s", err)
+	}
+	results = filterResultsByRule(removeExcludedResults(results, ignoreWarnings, excludeDownloaded), filterResultsList)
+
+	stats, err := ignores.AddIgnores(modules, results, expiry, ignoreReason)
+	if err != nil {
+		return err
+	}
+	for _, stat := range stats {
+		_ = tml.Printf("%s:%d ignored %s\n", stat.Filename, stat.LineNo, strings.Join(stat.RuleIDs, ", "))
+	}
+	_ = tml.Printf("Added %d ignore comment(s)\n", len(stats))
+	return nil
+}
+
+func getCache(dir string) (*cache.Cache, cache.Inputs) {
+
+	resultCacheDir := filepath.Join(dir, cache.DefaultDir)
+	if cacheDir != "" {
+		if abs, err := filepath.Abs(cacheDir); err == nil {
+			resultCacheDir = abs
+		}
+	}
+
+	var files []string
+	files = append(files, tfvarsPaths...)
+	files = append(files, tfsecConfig.Files()...)
+	files = append(files, ignoreFiles...)
+	checkDirs := []string{customCheckDir}
+	for _, override := range tfsecConfig.PathOverrides {
+		if override.CustomCheckDir == "" {
+			continue
+		}
+		scopedCheckDir := override.CustomCheckDir
+		if !filepath.IsAbs(scopedCheckDir) {
+			scopedCheckDir = filepath.Join(dir, scopedCheckDir)
+		}
+		checkDirs = append(checkDirs, scopedCheckDir)
+	}
+	for _, checkDir := range checkDirs {
+		if checkDir == "" {
+			continue
+		}
+		_ = filepath.Walk(checkDir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && path != resultCacheDir && !strings.HasPrefix(path, resultCacheDir+string(os.PathSeparator)) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+
+	options := []string{
+		fmt.Sprintf("force-all-dirs=%t", allDirs),
+		fmt.Sprintf("exclude=%s", excludedRuleIDs),
+		fmt.Sprintf("exclude-path=%s", strings.Join(excludePaths, ",")),
+		fmt.Sprintf("exclude-downloaded-modules=%t", excludeDownloaded),
+		fmt.Sprintf("ignore-hcl-errors=%t", ignoreHCLErrors),
+		fmt.Sprintf("include-passed=%t", includePassed),
+		fmt.Sprintf("include-ignored=%t", includeIgnored),
+		fmt.Sprintf("workspace=%s", workspace),
+		fmt.Sprintf("report-ignores=%t", reportIgnores),
+	}
+
+	var cacheOptions []cache.Option
+	if debugEnabled {
+		cacheOptions = append(cacheOptions, cache.OptionWithDebugWriter(os.Stdout))
+	}
+
+	return cache.New(resultCacheDir, cacheOptions...), cache.Inputs{
+		Dir:     dir,
+		Files:   files,
+		Options: options,
+	}
+}
+
+// getCustomCheckDir returns the directory custom checks are loaded from, which is the .tfsec folder of dir unless --custom-check-dir is set
+func getCustomCheckDir(dir string) string {
+	if len(customCheckDir) == 0 {
+		debug.Log("Using the default custom check folder")
+		return fmt.Sprintf("%s/.tfsec", dir)
+	}
+	return customCheckDir
+}
+
+func getCustomCheckOptions() []custom.Option {
+	options := []custom.Option{custom.OptionWithParams(tfsecConfig.CustomCheckParams)}
+	if debugEnabled {
+		options = append(options, custom.OptionWithDebugWriter(os.Stdout))
+	}
+	return options
+}
+
 func getParserOptions() []parser.Option {
 	var opts []parser.Option
 	if allDirs {
@@ -412,6 +609,14 @@ func getParserOptions() []parser.Option {
 		opts = append(opts, parser.OptionWithWorkspaceName(workspace))
 	}
 
+	if singleThreadedMode {
+		opts = append(opts, parser.OptionWithConcurrency(1))
+	}
+
+	if debugEnabled {
+		opts = append(opts, parser.OptionWithDebugWriter(os.Stdout))
+	}
+
 	return opts
 }
 
@@ -460,13 +665,32 @@ func getFormatterOptions() []formatters.FormatterOption {
 	if passingGif {
 		options = append(options, formatters.PassingGif)
 	}
-	if debug.Enabled {
+	if debugEnabled {
 		options = append(options, formatters.WithDebug)
 	}
 	return options
 }
 
-func getScannerOptions() []scanner.Option {
+// getPathScopes converts the path overrides in the config into scopes for the scanner, loading any custom checks they specify
+func getPathScopes(dir string) ([]scanner.PathScope, error) {
+	return settings.PathScopes(dir, tfsecConfig.PathOverrides, debug.Default(), getCustomCheckOptions()...)
+}
+
+// getIgnorePolicies loads the entries from the given ignore files as policies for the scanner
+func getIgnorePolicies(ignoreFilePaths []string) ([]scanner.IgnorePolicy, error) {
+	return settings.IgnorePolicies(ignoreFilePaths, debug.Default())
+}
+
+// scopedRules returns the custom checks which only apply within path overrides
+func scopedRules() []rule.Rule {
+	var scoped []rule.Rule
+	for _, scope := range pathScopes {
+		scoped = append(scoped, scope.CustomRules...)
+	}
+	return scoped
+}
+
+func getScannerOptions(dir string) []scanner.Option {
 	var options []scanner.Option
 	if includePassed {
 		options = append(options, scanner.OptionIncludePassed())
@@ -481,6 +705,20 @@ func getScannerOptions() []scanner.Option {
 	if stopOnCheckError {
 		options = append(options, scanner.OptionStopOnErrors())
 	}
+	if debugEnabled {
+		options = append(options, scanner.OptionWithDebugWriter(os.Stdout))
+	}
+	options = append(options, scanner.OptionWithCustomRules(customRules))
+	options = append(options, scanner.OptionWithSeverityOverrides(tfsecConfig.SeverityOverrides))
+	options = append(options, scanner.OptionWithMinimumSeverity(severity.StringToSeverity(tfsecConfig.MinimumSeverity)))
+	options = append(options, scanner.OptionWithPathScopes(dir, pathScopes))
+	options = append(options, scanner.OptionWithIgnorePolicies(ignorePolicies))
+	if reportIgnores {
+		options = append(options, scanner.OptionReportIgnores())
+	}
+	if tfsecConfig.RequiresIgnoreReason() {
+		options = append(options, scanner.OptionRequireIgnoreReason())
+	}
 
 	var allExcludedRuleIDs []string
 	for _, exclude := range strings.Split(excludedRuleIDs, ",") {
@@ -489,6 +727,11 @@ func getScannerOptions() []scanner.Option {
 	allExcludedRuleIDs = mergeWithoutDuplicates(allExcludedRuleIDs, tfsecConfig.ExcludedChecks)
 
 	options = append(options, scanner.OptionExcludeRules(allExcludedRuleIDs))
+	options = append(options, scanner.OptionIncludeRules(tfsecConfig.IncludedChecks))
+	options = append(options, scanner.OptionIncludeProviders(tfsecConfig.IncludedProviders))
+	options = append(options, scanner.OptionExcludeProviders(tfsecConfig.ExcludedProviders))
+	options = append(options, scanner.OptionIncludeServices(tfsecConfig.IncludedServices))
+	options = append(options, scanner.OptionExcludeServices(tfsecConfig.ExcludedServices))
 	return options
 }
 
@@ -515,36 +758,12 @@ func allInfo(results []rules.Result) bool {
 	return true
 }
 
-func updateResultSeverity(results []rules.Result) []rules.Result {
-	overrides := tfsecConfig.SeverityOverrides
-
-	if len(overrides) == 0 {
-		return results
-	}
-
-	var overriddenResults []rules.Result
-	for _, res := range results {
-		for code, sev := range overrides {
-			if res.Rule().LongID() == code || scanner.FindLegacyID(res.Rule().LongID()) == code {
-				overrides := rules.Results([]rules.Result{res})
-				override := res.Rule()
-				override.Severity = severity.Severity(sev)
-				overrides.SetRule(override)
-				res = overrides[0]
-			}
-		}
-		overriddenResults = append(overriddenResults, res)
-	}
-
-	return overriddenResults
-}
-
 func getFormatter(fileFormat string) (formatters.Formatter, error) {
 	switch strings.ToLower(fileFormat) {
 	case "", "default":
 		return formatters.FormatDefault, nil
 	case "json":
-		return formatters.FormatJSON, nil
+		return formatJSON, nil
 	case "csv":
 		return formatters.FormatCSV, nil
 	case "checkstyle":
@@ -554,7 +773,7 @@ func getFormatter(fileFormat string) (formatters.Formatter, error) {
 	case "text":
 		return formatters.FormatText, nil
 	case "sarif":
-		return formatters.FormatSarif, nil
+		return formatSarif, nil
 	case "gif":
 		return formatters.FormatGif, nil
 	default:
@@ -571,9 +790,17 @@ func defaultExtensionForFormatter(fileFormat string) string {
 	}
 }
 
-func loadConfigFile(configFilePath string) (*config.Config, error) {
-	debug.Log("loading config file %s", configFilePath)
-	return config.LoadConfig(configFilePath)
+// loadConfig loads the config file given by --config-file, or otherwise merges the config files found in the directory and its parents
+func loadConfig(dir string) (*config.Config, error) {
+	if len(configFile) > 0 {
+		debug.Log("loading config file %s", configFile)
+		return config.LoadConfig(configFile)
+	}
+	configFiles := config.DiscoverConfigFiles(dir)
+	for _, configFilePath := range configFiles {
+		debug.Log("loading config file %s", configFilePath)
+	}
+	return config.LoadConfigs(configFiles)
 }
 
 func countPassedResults(results []rules.Result) int {
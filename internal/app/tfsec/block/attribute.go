@@ -3,12 +3,14 @@ package block
 import (
 	"github.com/aquasecurity/defsec/rules"
 	"github.com/aquasecurity/defsec/types"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
 )
 
 type Attribute interface {
 	rules.MetadataProvider
 	IsLiteral() bool
+	Traversals() []hcl.Traversal
 	Type() cty.Type
 	Value() cty.Value
 	Range() HCLRange
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/gocty"
 )
 
@@ -135,6 +136,14 @@ func (attr *HCLAttribute) IsLiteral() bool {
 	return len(attr.hclAttribute.Expr.Variables()) == 0
 }
 
+// Traversals returns the variable traversals referenced by the attribute expression
+func (attr *HCLAttribute) Traversals() []hcl.Traversal {
+	if attr == nil {
+		return nil
+	}
+	return attr.hclAttribute.Expr.Variables()
+}
+
 func (attr *HCLAttribute) IsResolvable() bool {
 	if attr == nil {
 		return false
@@ -286,9 +295,17 @@ func (attr *HCLAttribute) listContains(val cty.Value, stringToLookFor string, ig
 			// References without a value can't logically "contain" a some string to check against.
 			return false
 		}
-		if !value.IsKnown() {
+		if !value.IsKnown() || stringToTest.IsNull() {
 			continue
 		}
+		if stringToTest.Type() != cty.String {
+			// numbers and bools are compared by their string form, as the value to look for is
+			converted, err := convert.Convert(stringToTest, cty.String)
+			if err != nil {
+				continue
+			}
+			stringToTest = converted
+		}
 		if ignoreCase && strings.EqualFold(stringToTest.AsString(), stringToLookFor) {
 			return true
 		}
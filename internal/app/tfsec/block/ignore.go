@@ -6,12 +6,26 @@ import (
 	"github.com/aquasecurity/defsec/types"
 )
 
+// IgnoreScope is the extent of the code covered by an ignore
+type IgnoreScope string
+
+const (
+	// IgnoreScopeLine covers the line of the ignore and the line after it, and is given by tfsec:ignore
+	IgnoreScopeLine IgnoreScope = ""
+	// IgnoreScopeBlock covers the whole of the block following the ignore, and is given by tfsec:ignore-block
+	IgnoreScopeBlock IgnoreScope = "block"
+	// IgnoreScopeFile covers the whole file containing the ignore, and is given by tfsec:ignore-file
+	IgnoreScopeFile IgnoreScope = "file"
+)
+
 type Ignore struct {
 	ModuleKey string //  whether the ignore applies to the whole module
-	Range     HCLRange
+	Scope     IgnoreScope
+	Range     HCLRange // for block ignores, this ends at the end of the block
 	RuleID    string
 	Expiry    *time.Time
 	Workspace string
+	Reason    string // why the ignore was added, given with reason="..."
 }
 
 type Ignores []Ignore
@@ -51,9 +65,25 @@ func (ignore Ignore) Covering(r types.Range, workspace string, ids ...string) bo
 	if ignore.Range.GetFilename() != r.GetFilename() {
 		return false
 	}
+	switch ignore.Scope {
+	case IgnoreScopeFile:
+		return true
+	case IgnoreScopeBlock:
+		if r.GetStartLine() >= ignore.Range.GetStartLine() && r.GetStartLine() <= ignore.Range.GetEndLine() {
+			return true
+		}
+	}
 	if r.GetStartLine() == ignore.Range.GetStartLine()+1 || r.GetStartLine() == ignore.Range.GetStartLine() {
 		return true
 	}
 	return false
 
 }
+
+// Directive returns the comment directive for the scope of the ignore, e.g. ignore-block
+func (ignore Ignore) Directive() string {
+	if ignore.Scope == IgnoreScopeLine {
+		return "ignore"
+	}
+	return "ignore-" + string(ignore.Scope)
+}
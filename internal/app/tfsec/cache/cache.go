@@ -0,0 +1,366 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+)
+
+// DefaultDir is the location of the cache, relative to the directory being scanned
+const DefaultDir = ".tfsec/cache"
+
+// valuesDir holds the evaluated values of modules, relative to the cache directory
+const valuesDir = "values"
+
+const (
+	// maxAge is how long an entry is kept after it was last used
+	maxAge = 7 * 24 * time.Hour
+	// maxEntries is how many results entries are kept - each scan stores one results entry, but one values entry per module
+	maxEntries      = 50
+	maxValueEntries = 1000
+)
+
+// Cache stores the results of previous scans on disk, so that unchanged projects can skip parsing, evaluation and scanning entirely.
+// It also stores the evaluated values of each module, so that when a project has changed only the modules affected are evaluated again.
+type Cache struct {
+	dir   string
+	now   func() time.Time
+	debug debug.Logger
+}
+
+// Option configures a Cache
+type Option func(c *Cache)
+
+// OptionWithDebugWriter enables debug logging for this cache only, writing to the given writer
+func OptionWithDebugWriter(writer io.Writer) Option {
+	return func(c *Cache) {
+		c.debug = debug.New(writer)
+	}
+}
+
+type entry struct {
+	// External holds the hashes of any files which were parsed from outside of the scanned directory, such as local modules
+	External map[string]string `json:"external"`
+	Results  []result          `json:"results"`
+}
+
+type result struct {
+	Rule        rules.Rule `json:"rule"`
+	Description string     `json:"description"`
+	Annotation  string     `json:"annotation,omitempty"`
+	Passed      bool       `json:"passed,omitempty"`
+	Code        metadata   `json:"code"`
+	Issue       *metadata  `json:"issue,omitempty"`
+	// Suppression is set for ignored results, which are only present when ignored results are included
+	Suppression *scanner.Suppression `json:"suppression,omitempty"`
+}
+
+type metadata struct {
+	Filename  string `json:"filename"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Module    string `json:"module,omitempty"`
+	Reference string `json:"reference"`
+	LogicalID string `json:"logical_id"`
+}
+
+// New creates a cache which stores entries in the given directory
+func New(dir string, options ...Option) *Cache {
+	c := &Cache{
+		dir: filepath.Clean(dir),
+		now: time.Now,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Load returns the cached results for the given inputs, and the suppressions of any ignored results, if present and still valid
+func (c *Cache) Load(inputs Inputs) (rules.Results, scanner.Suppressions, bool) {
+
+	key, err := c.key(inputs)
+	if err != nil {
+		c.debug.Log("Cache key could not be calculated: %s", err)
+		return nil, nil, false
+	}
+
+	data, ok := c.read(c.entryPath(key))
+	if !ok {
+		c.debug.Log("No cache entry found for %s", inputs.Dir)
+		return nil, nil, false
+	}
+
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		c.debug.Log("Cache entry for %s is corrupt: %s", inputs.Dir, err)
+		return nil, nil, false
+	}
+
+	for path, expected := range cached.External {
+		if actual, err := hashFile(path); err != nil || actual != expected {
+			c.debug.Log("Cache entry for %s is stale: %s has changed", inputs.Dir, path)
+			return nil, nil, false
+		}
+	}
+
+	var results rules.Results
+	suppressions := make(scanner.Suppressions)
+	for _, r := range cached.Results {
+		restored := r.restore()
+		if r.Suppression != nil {
+			suppressions[scanner.SuppressionKey(restored)] = *r.Suppression
+		}
+		results = append(results, restored)
+	}
+
+	c.debug.Log("Loaded %d results from cache for %s", len(results), inputs.Dir)
+	return results, suppressions, true
+}
+
+// Store saves the results of a scan, along with the suppressions of any ignored results. Any parsed files which are outside of the scanned directory are recorded so that the entry is invalidated if they change.
+func (c *Cache) Store(inputs Inputs, results rules.Results, suppressions scanner.Suppressions, parsedFiles []string) error {
+
+	key, err := c.key(inputs)
+	if err != nil {
+		return err
+	}
+
+	cached := entry{
+		External: make(map[string]string),
+	}
+
+	for _, path := range parsedFiles {
+		if strings.HasPrefix(path, inputs.Dir+string(os.PathSeparator)) {
+			continue
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		cached.External[path] = hash
+	}
+
+	for _, r := range results {
+		cached.Results = append(cached.Results, newResult(r, suppressions.Lookup(r)))
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := c.write(c.entryPath(key), data); err != nil {
+		return err
+	}
+	return c.Prune()
+}
+
+// LoadValues returns the evaluated values of a module stored under the given key, for use by the parser
+func (c *Cache) LoadValues(key string) ([]byte, bool) {
+	return c.read(c.valuesPath(key))
+}
+
+// StoreValues saves the evaluated values of a module under the given key, for use by the parser
+func (c *Cache) StoreValues(key string, data []byte) error {
+	return c.write(c.valuesPath(key), data)
+}
+
+// Prune removes entries which have not been used for a week, along with the least recently used entries once there are too many
+func (c *Cache) Prune() error {
+	if err := c.prune(c.dir, maxEntries); err != nil {
+		return err
+	}
+	return c.prune(filepath.Join(c.dir, valuesDir), maxValueEntries)
+}
+
+func (c *Cache) prune(dir string, limit int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var infos []fs.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	for i, info := range infos {
+		if i < limit && c.now().Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		c.debug.Log("Removing cache entry %s", info.Name())
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// read returns the content of an entry, marking it as used so that it is pruned last
+func (c *Cache) read(path string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := c.now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (c *Cache) write(path string, data []byte) error {
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a concurrent run never reads a partially written entry
+	tmp, err := ioutil.TempFile(dir, "entry")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	now := c.now()
+	_ = os.Chtimes(tmp.Name(), now, now)
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s.json", key))
+}
+
+// valuesPath returns the path of the values stored under the given key, which only changes with the version of tfsec as the key
+// already describes everything else the values depend on
+func (c *Cache) valuesPath(key string) string {
+	hasher := sha256.New()
+	_, _ = fmt.Fprintf(hasher, "format:%s\n", formatVersion)
+	_, _ = fmt.Fprintf(hasher, "version:%s\n", binaryVersion())
+	_, _ = fmt.Fprintf(hasher, "key:%s\n", key)
+	return filepath.Join(c.dir, valuesDir, fmt.Sprintf("%s.json", hex.EncodeToString(hasher.Sum(nil))))
+}
+
+func newResult(r rules.Result, suppression *scanner.Suppression) result {
+	cached := result{
+		Suppression: suppression,
+		Rule:        r.Rule(),
+		Description: r.Description(),
+		Annotation:  r.Annotation(),
+		Passed:      r.Status() == rules.StatusPassed,
+		Code:        newMetadata(r.CodeBlockMetadata()),
+	}
+	if r.IssueBlockMetadata() != nil {
+		issue := newMetadata(r.IssueBlockMetadata())
+		cached.Issue = &issue
+	}
+	return cached
+}
+
+func newMetadata(m *types.Metadata) metadata {
+	rng := m.Range()
+	cached := metadata{
+		Filename:  rng.GetFilename(),
+		StartLine: rng.GetStartLine(),
+		EndLine:   rng.GetEndLine(),
+		Reference: m.Reference().String(),
+		LogicalID: m.Reference().LogicalID(),
+	}
+	if hclRange, ok := rng.(block.HCLRange); ok {
+		cached.Module = hclRange.GetModule()
+	}
+	return cached
+}
+
+func (r result) restore() rules.Result {
+	var results rules.Results
+	code := r.Code.restore()
+	if r.Passed {
+		results.AddPassed(code, r.Description)
+	} else if r.Issue != nil {
+		results.Add(r.Description, code, r.Issue.restore())
+	} else {
+		results.Add(r.Description, code)
+	}
+
+	results.SetRule(r.Rule)
+
+	restored := results[0]
+	restored.OverrideAnnotation(r.Annotation)
+	return restored
+}
+
+func (m metadata) restore() *metadataProvider {
+	return &metadataProvider{
+		metadata: types.NewMetadata(
+			block.NewRange(m.Filename, m.StartLine, m.EndLine, m.Module),
+			&reference{value: m.Reference, logicalID: m.LogicalID},
+		),
+	}
+}
+
+// metadataProvider supplies restored metadata when rebuilding results
+type metadataProvider struct {
+	metadata types.Metadata
+}
+
+func (p *metadataProvider) GetMetadata() *types.Metadata {
+	return &p.metadata
+}
+
+func (p *metadataProvider) GetRawValue() interface{} {
+	return nil
+}
+
+// reference is a restored resource reference, which only needs to describe itself
+type reference struct {
+	value     string
+	logicalID string
+}
+
+func (r *reference) String() string {
+	return r.value
+}
+
+func (r *reference) LogicalID() string {
+	return r.logicalID
+}
+
+func (r *reference) RefersTo(other types.Reference) bool {
+	return other != nil && other.String() == r.value
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/defsec/provider"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	metadata types.Metadata
+}
+
+func (p *testProvider) GetMetadata() *types.Metadata {
+	return &p.metadata
+}
+
+func (p *testProvider) GetRawValue() interface{} {
+	return nil
+}
+
+func Test_CacheRoundTrip(t *testing.T) {
+
+	dir, external := createProject(t)
+	c := New(filepath.Join(dir, DefaultDir))
+	inputs := Inputs{Dir: dir, Options: []string{"workspace=default"}}
+
+	_, _, ok := c.Load(inputs)
+	require.False(t, ok)
+
+	mainFile := filepath.Join(dir, "main.tf")
+	var results rules.Results
+	results.Add("bucket is public", &testProvider{
+		metadata: types.NewMetadata(block.NewRange(mainFile, 1, 3, "root"), &reference{value: "aws_s3_bucket.public", logicalID: "aws_s3_bucket.public"}),
+	})
+	results.SetRule(rules.Rule{
+		ShortCode: "no-public-buckets",
+		Provider:  provider.AWSProvider,
+		Service:   "s3",
+		Severity:  severity.High,
+		Links:     []string{"https://example.com"},
+	})
+
+	suppression := scanner.Suppression{
+		Kind:     scanner.SuppressionInSource,
+		Reason:   "static assets bucket",
+		Filename: mainFile,
+		Line:     1,
+	}
+	suppressions := scanner.Suppressions{scanner.SuppressionKey(results[0]): suppression}
+	require.NoError(t, c.Store(inputs, results, suppressions, []string{mainFile, external}))
+
+	loaded, loadedSuppressions, ok := c.Load(inputs)
+	require.True(t, ok)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, results.Flatten(), loaded.Flatten())
+	assert.Equal(t, "root", loaded[0].NarrowestRange().(block.HCLRange).GetModule())
+	require.NotNil(t, loadedSuppressions.Lookup(loaded[0]))
+	assert.Equal(t, suppression, *loadedSuppressions.Lookup(loaded[0]))
+
+	// options are part of the key
+	_, _, ok = c.Load(Inputs{Dir: dir, Options: []string{"workspace=other"}})
+	assert.False(t, ok)
+
+	// changes to files outside of the scanned directory invalidate the entry
+	require.NoError(t, ioutil.WriteFile(external, []byte(`variable "changed" {}`), 0600))
+	_, _, ok = c.Load(inputs)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Store(inputs, results, nil, []string{mainFile, external}))
+	_, _, ok = c.Load(inputs)
+	require.True(t, ok)
+
+	// as do changes to module metadata within it
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".terraform", "modules", "modules.json"), []byte(`{"Modules":[{"Key":"x"}]}`), 0600))
+	_, _, ok = c.Load(inputs)
+	assert.False(t, ok)
+}
+
+func Test_CacheKeyChangesDaily(t *testing.T) {
+
+	dir, _ := createProject(t)
+	c := New(filepath.Join(dir, DefaultDir))
+	today := time.Date(2022, 1, 31, 12, 0, 0, 0, time.Local)
+	c.now = func() time.Time { return today }
+	inputs := Inputs{Dir: dir}
+
+	require.NoError(t, c.Store(inputs, nil, nil, nil))
+	_, _, ok := c.Load(inputs)
+	require.True(t, ok)
+
+	// an ignore which expires today no longer applies tomorrow, so results from today cannot be reused
+	c.now = func() time.Time { return today.Add(24 * time.Hour) }
+	_, _, ok = c.Load(inputs)
+	assert.False(t, ok)
+}
+
+func Test_CachePrunesOldAndExcessEntries(t *testing.T) {
+
+	dir, _ := createProject(t)
+	c := New(filepath.Join(dir, DefaultDir))
+	now := time.Date(2022, 1, 31, 12, 0, 0, 0, time.Local)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.StoreValues("stale", []byte("{}")))
+	stale := c.valuesPath("stale")
+	require.NoError(t, os.Chtimes(stale, now.Add(-maxAge-time.Hour), now.Add(-maxAge-time.Hour)))
+	require.NoError(t, c.StoreValues("recent", []byte("{}")))
+
+	for i := 0; i < maxEntries+5; i++ {
+		inputs := Inputs{Dir: dir, Options: []string{fmt.Sprintf("option=%d", i)}}
+		c.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		require.NoError(t, c.Store(inputs, nil, nil, nil))
+	}
+
+	entries, err := ioutil.ReadDir(c.dir)
+	require.NoError(t, err)
+	var count int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			count++
+		}
+	}
+	assert.Equal(t, maxEntries, count)
+
+	_, _, ok := c.Load(Inputs{Dir: dir, Options: []string{"option=0"}})
+	assert.False(t, ok)
+	_, _, ok = c.Load(Inputs{Dir: dir, Options: []string{fmt.Sprintf("option=%d", maxEntries+4)}})
+	assert.True(t, ok)
+
+	_, ok = c.LoadValues("stale")
+	assert.False(t, ok)
+	_, ok = c.LoadValues("recent")
+	assert.True(t, ok)
+}
+
+func createProject(t *testing.T) (string, string) {
+	root, err := ioutil.TempDir(os.TempDir(), "tfsec-cache")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(root) })
+
+	dir := filepath.Join(root, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform", "modules"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "modules"), 0700))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_s3_bucket" "public" {}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".terraform", "modules", "modules.json"), []byte(`{"Modules":[]}`), 0600))
+
+	external := filepath.Join(root, "modules", "main.tf")
+	require.NoError(t, ioutil.WriteFile(external, []byte(`variable "name" {}`), 0600))
+	return dir, external
+}
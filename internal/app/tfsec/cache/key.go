@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/tfsec/version"
+)
+
+// formatVersion must be incremented whenever the layout of a cache entry changes
+const formatVersion = "4"
+
+// Inputs describes everything a scan depends on
+type Inputs struct {
+	// Dir is the directory being scanned - all terraform, tfvars, module metadata and tfsec files beneath it are hashed
+	Dir string
+	// Files are any additional files which affect the scan, such as tfvars or config files outside of Dir
+	Files []string
+	// Options describes any settings which affect the results, such as the workspace or excluded rules
+	Options []string
+}
+
+// isInput returns true if the file at the given path could affect the results of a scan
+func isInput(path string) bool {
+	name := filepath.Base(path)
+	switch {
+	case strings.HasSuffix(name, ".tf"), strings.HasSuffix(name, ".tf.json"):
+		return true
+	case strings.HasSuffix(name, ".tfvars"), strings.HasSuffix(name, ".tfvars.json"):
+		return true
+	case name == "modules.json":
+		return true
+	case strings.Contains(filepath.ToSlash(path), "/.tfsec/"):
+		return true
+	}
+	return false
+}
+
+// key calculates a key which changes whenever any of the inputs, or the version of tfsec itself, changes. Ignores and ignore
+// policies can expire, so the key also changes every day.
+func (c *Cache) key(inputs Inputs) (string, error) {
+
+	hasher := sha256.New()
+	_, _ = fmt.Fprintf(hasher, "format:%s\n", formatVersion)
+	_, _ = fmt.Fprintf(hasher, "version:%s\n", binaryVersion())
+	_, _ = fmt.Fprintf(hasher, "date:%s\n", c.now().Format("2006-01-02"))
+	_, _ = fmt.Fprintf(hasher, "dir:%s\n", inputs.Dir)
+	for _, option := range inputs.Options {
+		_, _ = fmt.Fprintf(hasher, "option:%s\n", option)
+	}
+
+	var paths []string
+	if err := filepath.WalkDir(inputs.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path == c.dir || entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isInput(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	paths = append(paths, inputs.Files...)
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		hash, err := hashFile(path)
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(hasher, "file:%s:%s\n", path, hash)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// binaryVersion identifies the running tfsec binary. Locally built binaries have no version, so the executable itself is used instead.
+func binaryVersion() string {
+	if version.Version != "" {
+		return version.Version
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "unknown"
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%d", executable, info.Size(), info.ModTime().UnixNano())
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
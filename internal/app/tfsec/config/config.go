@@ -6,19 +6,164 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/aquasecurity/defsec/severity"
 	"gopkg.in/yaml.v2"
 )
 
+// Config is loaded from a tfsec config file. Checks, providers and services can be selected by ID or by patterns containing wildcards, e.g. aws-s3-*
 type Config struct {
+	// Extends lists other config files which this file builds on, relative to this file if not absolute. See merge for how values are combined.
+	Extends           []string          `json:"extends,omitempty" yaml:"extends,omitempty"`
 	SeverityOverrides map[string]string `json:"severity_overrides,omitempty" yaml:"severity_overrides,omitempty"`
 	ExcludedChecks    []string          `json:"exclude,omitempty" yaml:"exclude,omitempty"`
 	IncludedChecks    []string          `json:"include,omitempty" yaml:"include,omitempty"`
+	// IncludedProviders and ExcludedProviders select checks by provider, e.g. aws
+	IncludedProviders []string `json:"include_providers,omitempty" yaml:"include_providers,omitempty"`
+	ExcludedProviders []string `json:"exclude_providers,omitempty" yaml:"exclude_providers,omitempty"`
+	// IncludedServices and ExcludedServices select checks by service, given as provider-service e.g. aws-s3
+	IncludedServices []string `json:"include_services,omitempty" yaml:"include_services,omitempty"`
+	ExcludedServices []string `json:"exclude_services,omitempty" yaml:"exclude_services,omitempty"`
+	// MinimumSeverity removes results with a lower severity, after any overrides have been applied
+	MinimumSeverity string `json:"minimum_severity,omitempty" yaml:"minimum_severity,omitempty"`
+	// RequireIgnoreReason disregards ignore comments which do not give a reason="...". It is a pointer so that a later config can unset it.
+	RequireIgnoreReason *bool `json:"require_ignore_reason,omitempty" yaml:"require_ignore_reason,omitempty"`
+	// PathOverrides apply different settings to results found in files matching their paths, in the order given
+	PathOverrides []PathOverride `json:"path_overrides,omitempty" yaml:"path_overrides,omitempty"`
+	// Gate sets how many failed results are allowed before the scan fails, replacing the default exit behaviour when set
+	Gate Gate `json:"gate,omitempty" yaml:"gate,omitempty"`
+	// CustomCheckParams override the params of custom checks, keyed by check code and then by param name
+	CustomCheckParams map[string]map[string]interface{} `json:"custom_check_params,omitempty" yaml:"custom_check_params,omitempty"`
+
+	// origins records the file each value was loaded from, keyed as described by Origin
+	origins map[string]string
+	// files lists every file the config was loaded from, in the order they were merged
+	files []string
+}
+
+// PathOverride applies to results found in files matching any of its paths, which are patterns relative to the scanned directory such as prod/** or **/sandbox/*.tf
+type PathOverride struct {
+	Paths             []string          `json:"paths" yaml:"paths"`
+	ExcludedChecks    []string          `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	SeverityOverrides map[string]string `json:"severity_overrides,omitempty" yaml:"severity_overrides,omitempty"`
+	MinimumSeverity   string            `json:"minimum_severity,omitempty" yaml:"minimum_severity,omitempty"`
+	// CustomCheckDir is a directory of custom checks which are only reported for matching files, relative to the scanned directory if not absolute
+	CustomCheckDir string `json:"custom_check_dir,omitempty" yaml:"custom_check_dir,omitempty"`
+}
+
+// Gate gives the maximum number of failed results allowed by severity, provider or rule, where 0 fails the scan on any result.
+// Rules may be given by ID, legacy ID or pattern, e.g. aws-s3-*. The scan fails if any threshold is exceeded.
+type Gate struct {
+	Severities map[string]int `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Providers  map[string]int `json:"providers,omitempty" yaml:"providers,omitempty"`
+	Rules      map[string]int `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// RequiresIgnoreReason returns true if ignore comments must give a reason to take effect
+func (c *Config) RequiresIgnoreReason() bool {
+	return c.RequireIgnoreReason != nil && *c.RequireIgnoreReason
+}
+
+// IsSet returns true if the gate has any thresholds
+func (g Gate) IsSet() bool {
+	return len(g.Severities) > 0 || len(g.Providers) > 0 || len(g.Rules) > 0
+}
+
+// FindDefaultConfig returns the path of the config file within the .tfsec directory of the given directory, or an empty string if there is none
+func FindDefaultConfig(dir string) string {
+	for _, name := range []string{"config.json", "config.yml"} {
+		path := filepath.Join(dir, ".tfsec", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// DiscoverConfigFiles returns the config files within the .tfsec directories of the given directory and each of its parents, up to the root of the git repository containing it.
+// Files are ordered from the repository root down, so that files closer to the directory take precedence when loaded with LoadConfigs.
+// If the directory is not within a git repository, only its own config file is returned.
+func DiscoverConfigFiles(dir string) []string {
+	return discover(dir, FindDefaultConfig)
+}
+
+// discover returns the files found by find in the given directory and each of its parents up to the root of the git repository, ordered from the repository root down
+func discover(dir string, find func(dir string) string) []string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	var found []string
+	for current := dir; ; current = filepath.Dir(current) {
+		if path := find(current); path != "" {
+			found = append([]string{path}, found...)
+		}
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return found
+		}
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if path := find(dir); path != "" {
+		return []string{path}
+	}
+	return nil
 }
 
+// LoadConfigs loads and merges the given config files in order, so that values from later files take precedence
+func LoadConfigs(configFilePaths []string) (*Config, error) {
+	merged := &Config{}
+	for _, path := range configFilePaths {
+		config, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(config)
+	}
+	return merged, nil
+}
+
+// LoadConfig loads the given config file, merged with any files it extends
 func LoadConfig(configFilePath string) (*Config, error) {
+	return loadWithExtends(configFilePath, nil)
+}
+
+func loadWithExtends(configFilePath string, chain []string) (*Config, error) {
+	if abs, err := filepath.Abs(configFilePath); err == nil {
+		configFilePath = abs
+	}
+	for _, previous := range chain {
+		if previous == configFilePath {
+			return nil, fmt.Errorf("config file '%s' extends itself: %s", configFilePath, strings.Join(append(chain, configFilePath), " -> "))
+		}
+	}
+
+	file, err := loadFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Config{}
+	for _, base := range file.Extends {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(configFilePath), base)
+		}
+		baseConfig, err := loadWithExtends(base, append(chain, configFilePath))
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(baseConfig)
+	}
+	merged.merge(file)
+	return merged, nil
+}
+
+func loadFile(configFilePath string) (*Config, error) {
 	var config = &Config{}
 
 	if _, err := os.Stat(configFilePath); err != nil {
@@ -30,6 +175,10 @@ func LoadConfig(configFilePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file '%s': %s", configFilePath, err)
 	}
 
+	if _, problems := validateContent(configFilePath, configFileContent, reflect.TypeOf(Config{}), nil); problems.HasErrors() {
+		return nil, fmt.Errorf("invalid config file '%s':\n%s", configFilePath, problems)
+	}
+
 	ext := filepath.Ext(configFilePath)
 	switch strings.ToLower(ext) {
 	case ".json":
@@ -46,16 +195,38 @@ func LoadConfig(configFilePath string) (*Config, error) {
 		return nil, fmt.Errorf("couldn't process the file %s", configFilePath)
 	}
 
-	rewriteSeverityOverrides(config)
+	config.SeverityOverrides = rewriteSeverityOverrides(config.SeverityOverrides)
+	config.MinimumSeverity = rewriteSeverity(config.MinimumSeverity)
+	config.Gate.Severities = rewriteSeverityKeys(config.Gate.Severities)
+	for i := range config.PathOverrides {
+		config.PathOverrides[i].SeverityOverrides = rewriteSeverityOverrides(config.PathOverrides[i].SeverityOverrides)
+		config.PathOverrides[i].MinimumSeverity = rewriteSeverity(config.PathOverrides[i].MinimumSeverity)
+	}
 
+	config.setOrigin(configFilePath)
 	return config, nil
 }
 
-func rewriteSeverityOverrides(config *Config) error {
+// rewriteSeverityOverrides returns the overrides with legacy severities, such as WARNING, replaced by their current equivalents
+func rewriteSeverityOverrides(overrides map[string]string) map[string]string {
+	for k, s := range overrides {
+		overrides[k] = rewriteSeverity(s)
+	}
+	return overrides
+}
 
-	for k, s := range config.SeverityOverrides {
-		config.SeverityOverrides[k] = string(severity.StringToSeverity(s))
+// rewriteSeverityKeys returns the thresholds with legacy severities as keys replaced by their current equivalents
+func rewriteSeverityKeys(thresholds map[string]int) map[string]int {
+	if thresholds == nil {
+		return nil
 	}
+	rewritten := make(map[string]int)
+	for sev, max := range thresholds {
+		rewritten[rewriteSeverity(sev)] = max
+	}
+	return rewritten
+}
 
-	return nil
+func rewriteSeverity(sev string) string {
+	return string(severity.StringToSeverity(sev))
 }
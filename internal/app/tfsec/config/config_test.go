@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
@@ -55,6 +56,67 @@ func TestExcludesElementsFromJSON(t *testing.T) {
 	assert.Contains(t, c.ExcludedChecks, "DP001")
 }
 
+func TestIncludesElementsFromYAML(t *testing.T) {
+	content := `
+include:
+  - aws-s3-*
+include_providers:
+  - aws
+exclude_providers:
+  - google
+include_services:
+  - aws-s3
+exclude_services:
+  - aws-ec2
+`
+	c := load(t, "config.yaml", content)
+
+	assert.Equal(t, []string{"aws-s3-*"}, c.IncludedChecks)
+	assert.Equal(t, []string{"aws"}, c.IncludedProviders)
+	assert.Equal(t, []string{"google"}, c.ExcludedProviders)
+	assert.Equal(t, []string{"aws-s3"}, c.IncludedServices)
+	assert.Equal(t, []string{"aws-ec2"}, c.ExcludedServices)
+}
+
+func TestPathOverridesFromYAML(t *testing.T) {
+	content := `
+minimum_severity: low
+path_overrides:
+  - paths:
+      - sandbox/**
+    exclude:
+      - aws-s3-enable-versioning
+    severity_overrides:
+      AWS001: warning
+    minimum_severity: HIGH
+    custom_check_dir: sandbox/.checks
+`
+	c := load(t, "config.yaml", content)
+
+	assert.Equal(t, "LOW", c.MinimumSeverity)
+	require.Len(t, c.PathOverrides, 1)
+	override := c.PathOverrides[0]
+	assert.Equal(t, []string{"sandbox/**"}, override.Paths)
+	assert.Equal(t, []string{"aws-s3-enable-versioning"}, override.ExcludedChecks)
+	assert.Equal(t, "MEDIUM", override.SeverityOverrides["AWS001"])
+	assert.Equal(t, "HIGH", override.MinimumSeverity)
+	assert.Equal(t, "sandbox/.checks", override.CustomCheckDir)
+}
+
+func TestInvalidPathOverridesAreRejected(t *testing.T) {
+	for _, content := range []string{
+		`minimum_severity: extreme`,
+		`path_overrides: [{exclude: [AWS001]}]`,
+		`path_overrides: [{paths: ["**"], minimum_severity: extreme}]`,
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yml")
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		_, err := config.LoadConfig(path)
+		assert.Error(t, err, content)
+	}
+}
+
 func TestWarningIsRewrittenAsMedium(t *testing.T) {
 	content := `{
   "severity_overrides": {
@@ -86,3 +148,51 @@ func load(t *testing.T, filename, content string) *config.Config {
 
 	return c
 }
+
+func TestFindDefaultConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tfsec-config")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	assert.Empty(t, config.FindDefaultConfig(dir))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".tfsec"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".tfsec", "config.yml"), []byte("exclude: []"), 0600))
+	assert.Equal(t, filepath.Join(dir, ".tfsec", "config.yml"), config.FindDefaultConfig(dir))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".tfsec", "config.json"), []byte("{}"), 0600))
+	assert.Equal(t, filepath.Join(dir, ".tfsec", "config.json"), config.FindDefaultConfig(dir))
+}
+
+func TestGateFromYAML(t *testing.T) {
+	content := `
+gate:
+  severity:
+    critical: 0
+    ERROR: 5
+  providers:
+    aws: 10
+  rules:
+    aws-s3-*: 0
+`
+	c := load(t, "config.yml", content)
+
+	assert.True(t, c.Gate.IsSet())
+	assert.Equal(t, map[string]int{"CRITICAL": 0, "HIGH": 5}, c.Gate.Severities)
+	assert.Equal(t, map[string]int{"aws": 10}, c.Gate.Providers)
+	assert.Equal(t, map[string]int{"aws-s3-*": 0}, c.Gate.Rules)
+}
+
+func TestInvalidGateIsRejected(t *testing.T) {
+	for _, content := range []string{
+		`gate: {severity: {extreme: 1}}`,
+		`gate: {severity: {HIGH: -1}}`,
+		`gate: {providers: {aws: many}}`,
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yml")
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		_, err := config.LoadConfig(path)
+		assert.Error(t, err, content)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const dateFormat = "2006-01-02"
+
+// IgnoreFile is loaded from .tfsec/ignores.yml, and lists every accepted exception to the checks in one place
+type IgnoreFile struct {
+	Ignores []IgnoreEntry `json:"ignores" yaml:"ignores"`
+}
+
+// IgnoreEntry ignores the results matching all of its fields which are set
+type IgnoreEntry struct {
+	// Rule is the ID, legacy ID or pattern of the rules to ignore, e.g. aws-s3-* or * for every rule
+	Rule string `json:"rule" yaml:"rule"`
+	// Path is a pattern matched against the path of the file containing the result, relative to the directory containing the .tfsec directory, e.g. prod/**/*.tf
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Resource is the address of the resource, or a pattern matching it, e.g. module.network.aws_security_group.bastion
+	Resource string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	// Workspace restricts the ignore to scans of the given workspace
+	Workspace string `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	// Justification explains why the results are accepted, and is required along with Owner
+	Justification string `json:"justification" yaml:"justification"`
+	Owner         string `json:"owner" yaml:"owner"`
+	// Expiry is the date, as YYYY-MM-DD, after which the ignore no longer applies
+	Expiry string `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+
+	// root is the directory Path is relative to
+	root string
+	// filename and line give where the entry was loaded from
+	filename string
+	line     int
+}
+
+// Root returns the directory the path of the entry is relative to, which contains the .tfsec directory the entry was loaded from
+func (e IgnoreEntry) Root() string {
+	return e.root
+}
+
+// Filename returns the file the entry was loaded from
+func (e IgnoreEntry) Filename() string {
+	return e.filename
+}
+
+// Line returns the line the entry starts on, or 0 if it is not known
+func (e IgnoreEntry) Line() int {
+	return e.line
+}
+
+// ExpiryTime returns the parsed expiry of the entry, or nil if it does not expire
+func (e IgnoreEntry) ExpiryTime() *time.Time {
+	if e.Expiry == "" {
+		return nil
+	}
+	expiry, err := time.Parse(dateFormat, e.Expiry)
+	if err != nil {
+		return nil
+	}
+	return &expiry
+}
+
+// FindIgnoreFile returns the path of the ignore file within the .tfsec directory of the given directory, or an empty string if there is none
+func FindIgnoreFile(dir string) string {
+	for _, name := range []string{"ignores.yml", "ignores.yaml"} {
+		path := filepath.Join(dir, ".tfsec", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// DiscoverIgnoreFiles returns the ignore files within the .tfsec directories of the given directory and each of its parents, in the same way as DiscoverConfigFiles
+func DiscoverIgnoreFiles(dir string) []string {
+	return discover(dir, FindIgnoreFile)
+}
+
+// LoadIgnores loads the entries from the given ignore files, rejecting any entry without a rule, justification or owner
+func LoadIgnores(ignoreFilePaths []string) ([]IgnoreEntry, error) {
+	var entries []IgnoreEntry
+	for _, ignoreFilePath := range ignoreFilePaths {
+		loaded, err := loadIgnoreFile(ignoreFilePath)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, loaded...)
+	}
+	return entries, nil
+}
+
+func loadIgnoreFile(ignoreFilePath string) ([]IgnoreEntry, error) {
+	if abs, err := filepath.Abs(ignoreFilePath); err == nil {
+		ignoreFilePath = abs
+	}
+
+	content, err := ioutil.ReadFile(ignoreFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file '%s': %s", ignoreFilePath, err)
+	}
+
+	root, problems := validateContent(ignoreFilePath, content, reflect.TypeOf(IgnoreFile{}), nil)
+	if problems.HasErrors() {
+		return nil, fmt.Errorf("invalid ignore file '%s':\n%s", ignoreFilePath, problems)
+	}
+
+	var file IgnoreFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to load ignore file '%s': %s", ignoreFilePath, err)
+	}
+
+	items := root.lookup("ignores").list()
+	for i := range file.Ignores {
+		file.Ignores[i].root = filepath.Dir(filepath.Dir(ignoreFilePath))
+		file.Ignores[i].filename = ignoreFilePath
+		if i < len(items) {
+			file.Ignores[i].line = items[i].line
+		}
+	}
+	return file.Ignores, nil
+}
+
+// ValidateIgnoreFiles checks the given ignore files in the same way as Validate checks config files
+func ValidateIgnoreFiles(ignoreFilePaths []string, isKnownRule RuleMatcher) Problems {
+	var problems Problems
+	for _, path := range ignoreFilePaths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			problems = append(problems, Problem{Filename: path, Message: fmt.Sprintf("failed to read ignore file: %s", err)})
+			continue
+		}
+		_, fileProblems := validateContent(path, content, reflect.TypeOf(IgnoreFile{}), isKnownRule)
+		problems = append(problems, fileProblems...)
+	}
+	return problems
+}
@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoresAreLoaded(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".git/HEAD": "ref: refs/heads/main",
+		".tfsec/ignores.yml": `
+ignores:
+  - rule: aws-s3-*
+    path: prod/**
+    resource: module.network.aws_s3_bucket.logs
+    workspace: production
+    justification: Accepted by the security team
+    owner: security@example.com
+    expiry: 2030-06-01
+`,
+		"infra/.tfsec/config.yml": `exclude: [AWS001]`,
+	})
+
+	files := config.DiscoverIgnoreFiles(filepath.Join(dir, "infra"))
+	require.Equal(t, []string{filepath.Join(dir, ".tfsec", "ignores.yml")}, files)
+
+	entries, err := config.LoadIgnores(files)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "aws-s3-*", entry.Rule)
+	assert.Equal(t, "module.network.aws_s3_bucket.logs", entry.Resource)
+	assert.Equal(t, "production", entry.Workspace)
+	assert.Equal(t, dir, entry.Root())
+	assert.Equal(t, filepath.Join(dir, ".tfsec", "ignores.yml"), entry.Filename())
+	assert.Equal(t, 3, entry.Line())
+	require.NotNil(t, entry.ExpiryTime())
+	assert.Equal(t, "2030-06-01", entry.ExpiryTime().Format("2006-01-02"))
+}
+
+func TestIgnoresRequireJustificationAndOwner(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".tfsec/ignores.yml": `
+ignores:
+  - rule: AWS001
+    owner: security
+  - rule: AWS002
+    justification: Accepted
+    owner: security
+    expiry: next week
+`,
+	})
+
+	_, err := config.LoadIgnores([]string{filepath.Join(dir, ".tfsec", "ignores.yml")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ignores.yml:3:5: error: 'justification' must be set and not empty")
+	assert.Contains(t, err.Error(), "ignores.yml:8:13: error: invalid date 'next week'")
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+)
+
+// listField is a config list which is merged by appending the values of later configs
+type listField struct {
+	key    string
+	values *[]string
+}
+
+func (c *Config) lists() []listField {
+	return []listField{
+		{key: "exclude", values: &c.ExcludedChecks},
+		{key: "include", values: &c.IncludedChecks},
+		{key: "include_providers", values: &c.IncludedProviders},
+		{key: "exclude_providers", values: &c.ExcludedProviders},
+		{key: "include_services", values: &c.IncludedServices},
+		{key: "exclude_services", values: &c.ExcludedServices},
+	}
+}
+
+// thresholdField is a map of gate thresholds, which is merged key by key
+type thresholdField struct {
+	key    string
+	values *map[string]int
+}
+
+func (g *Gate) thresholds() []thresholdField {
+	return []thresholdField{
+		{key: "gate.severity", values: &g.Severities},
+		{key: "gate.providers", values: &g.Providers},
+		{key: "gate.rules", values: &g.Rules},
+	}
+}
+
+// merge combines another config into this one, where the other config takes precedence:
+//   - lists, such as exclude and include, are combined, keeping the first occurrence of any duplicates
+//   - maps, such as severity_overrides, are combined key by key, taking the value from the other config where both have a key
+//   - single values, such as minimum_severity and require_ignore_reason, are replaced if set in the other config
+//   - gate thresholds are combined key by key, in the same way as maps
+//   - custom_check_params are combined param by param, taking the value from the other config where both set a param of a check
+//   - path_overrides are appended after those already present, so they are applied afterwards and take precedence
+func (c *Config) merge(other *Config) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+
+	otherLists := other.lists()
+	for i, field := range c.lists() {
+		for _, value := range *otherLists[i].values {
+			if containsString(*field.values, value) {
+				continue
+			}
+			*field.values = append(*field.values, value)
+			key := listKey(field.key, value)
+			c.origins[key] = other.Origin(key)
+		}
+	}
+
+	for id, sev := range other.SeverityOverrides {
+		if c.SeverityOverrides == nil {
+			c.SeverityOverrides = make(map[string]string)
+		}
+		c.SeverityOverrides[id] = sev
+		key := mapKey("severity_overrides", id)
+		c.origins[key] = other.Origin(key)
+	}
+
+	for i, thresholds := range other.Gate.thresholds() {
+		field := c.Gate.thresholds()[i]
+		for key, max := range *thresholds.values {
+			if *field.values == nil {
+				*field.values = make(map[string]int)
+			}
+			(*field.values)[key] = max
+			originKey := mapKey(field.key, key)
+			c.origins[originKey] = other.Origin(originKey)
+		}
+	}
+
+	for code, params := range other.CustomCheckParams {
+		if c.CustomCheckParams == nil {
+			c.CustomCheckParams = make(map[string]map[string]interface{})
+		}
+		if c.CustomCheckParams[code] == nil {
+			c.CustomCheckParams[code] = make(map[string]interface{})
+		}
+		for name, value := range params {
+			c.CustomCheckParams[code][name] = value
+			key := mapKey(mapKey("custom_check_params", code), name)
+			c.origins[key] = other.Origin(key)
+		}
+	}
+
+	if other.MinimumSeverity != "" {
+		c.MinimumSeverity = other.MinimumSeverity
+		c.origins["minimum_severity"] = other.Origin("minimum_severity")
+	}
+
+	if other.RequireIgnoreReason != nil {
+		c.RequireIgnoreReason = other.RequireIgnoreReason
+		c.origins["require_ignore_reason"] = other.Origin("require_ignore_reason")
+	}
+
+	for i, override := range other.PathOverrides {
+		c.origins[indexKey("path_overrides", len(c.PathOverrides))] = other.Origin(indexKey("path_overrides", i))
+		c.PathOverrides = append(c.PathOverrides, override)
+	}
+
+	for _, file := range other.files {
+		if !containsString(c.files, file) {
+			c.files = append(c.files, file)
+		}
+	}
+}
+
+// setOrigin records that every value in the config was loaded from the given file
+func (c *Config) setOrigin(file string) {
+	c.origins = make(map[string]string)
+	for _, field := range c.lists() {
+		for _, value := range *field.values {
+			c.origins[listKey(field.key, value)] = file
+		}
+	}
+	for id := range c.SeverityOverrides {
+		c.origins[mapKey("severity_overrides", id)] = file
+	}
+	for _, field := range c.Gate.thresholds() {
+		for key := range *field.values {
+			c.origins[mapKey(field.key, key)] = file
+		}
+	}
+	for code, params := range c.CustomCheckParams {
+		for name := range params {
+			c.origins[mapKey(mapKey("custom_check_params", code), name)] = file
+		}
+	}
+	if c.MinimumSeverity != "" {
+		c.origins["minimum_severity"] = file
+	}
+	if c.RequireIgnoreReason != nil {
+		c.origins["require_ignore_reason"] = file
+	}
+	for i := range c.PathOverrides {
+		c.origins[indexKey("path_overrides", i)] = file
+	}
+	c.files = []string{file}
+}
+
+// Origin returns the file a value was loaded from, or an empty string if it was not loaded from a file.
+// Values are keyed by their name in the config file, e.g. minimum_severity, with list values as exclude[aws-s3-enable-versioning],
+// map values as severity_overrides.AWS001 or gate.severity.HIGH and path overrides by index as path_overrides[0].
+func (c *Config) Origin(key string) string {
+	return c.origins[key]
+}
+
+// Files returns every file the config was loaded from, including those it extends
+func (c *Config) Files() []string {
+	return c.files
+}
+
+func listKey(field string, value string) string {
+	return fmt.Sprintf("%s[%s]", field, value)
+}
+
+func mapKey(field string, key string) string {
+	return fmt.Sprintf("%s.%s", field, key)
+}
+
+func indexKey(field string, index int) string {
+	return fmt.Sprintf("%s[%d]", field, index)
+}
+
+func containsString(list []string, value string) bool {
+	for _, candidate := range list {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0700))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestDiscoverConfigFilesUpToRepositoryRoot(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".tfsec/config.yml":                 "exclude: [outside-repo]",
+		"repo/.git/HEAD":                    "ref: refs/heads/main",
+		"repo/.tfsec/config.yml":            "exclude: [root]",
+		"repo/infra/.tfsec/config.json":     `{"exclude": ["infra"]}`,
+		"repo/infra/prod/.tfsec/config.yml": "exclude: [prod]",
+		"repo/infra/prod/main.tf":           "",
+	})
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "repo", ".tfsec", "config.yml"),
+		filepath.Join(dir, "repo", "infra", ".tfsec", "config.json"),
+		filepath.Join(dir, "repo", "infra", "prod", ".tfsec", "config.yml"),
+	}, config.DiscoverConfigFiles(filepath.Join(dir, "repo", "infra", "prod")))
+}
+
+func TestDiscoverConfigFilesOutsideRepository(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".tfsec/config.yml":       "exclude: [parent]",
+		"infra/.tfsec/config.yml": "exclude: [infra]",
+	})
+
+	assert.Equal(t, []string{filepath.Join(dir, "infra", ".tfsec", "config.yml")}, config.DiscoverConfigFiles(filepath.Join(dir, "infra")))
+	assert.Empty(t, config.DiscoverConfigFiles(filepath.Join(dir, "missing")))
+}
+
+func TestConfigsAreMergedWithOrigins(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"baseline/org.yml": `
+exclude:
+  - aws-s3-enable-versioning
+severity_overrides:
+  AWS001: LOW
+  AWS002: LOW
+minimum_severity: LOW
+path_overrides:
+  - paths: [sandbox/**]
+    minimum_severity: HIGH
+`,
+		"repo/.git/HEAD": "",
+		"repo/.tfsec/config.yml": `
+extends:
+  - ../../baseline/org.yml
+exclude:
+  - aws-s3-enable-versioning
+  - "*-enable-logging"
+severity_overrides:
+  AWS002: HIGH
+`,
+		"repo/infra/.tfsec/config.yml": `
+minimum_severity: MEDIUM
+path_overrides:
+  - paths: [prod/**]
+    exclude: [aws-s3-*]
+`,
+	})
+
+	baseline := filepath.Join(dir, "baseline", "org.yml")
+	root := filepath.Join(dir, "repo", ".tfsec", "config.yml")
+	infra := filepath.Join(dir, "repo", "infra", ".tfsec", "config.yml")
+
+	c, err := config.LoadConfigs(config.DiscoverConfigFiles(filepath.Join(dir, "repo", "infra")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{baseline, root, infra}, c.Files())
+
+	assert.Equal(t, []string{"aws-s3-enable-versioning", "*-enable-logging"}, c.ExcludedChecks)
+	assert.Equal(t, baseline, c.Origin("exclude[aws-s3-enable-versioning]"))
+	assert.Equal(t, root, c.Origin("exclude[*-enable-logging]"))
+
+	assert.Equal(t, map[string]string{"AWS001": "LOW", "AWS002": "HIGH"}, c.SeverityOverrides)
+	assert.Equal(t, baseline, c.Origin("severity_overrides.AWS001"))
+	assert.Equal(t, root, c.Origin("severity_overrides.AWS002"))
+
+	assert.Equal(t, "MEDIUM", c.MinimumSeverity)
+	assert.Equal(t, infra, c.Origin("minimum_severity"))
+
+	require.Len(t, c.PathOverrides, 2)
+	assert.Equal(t, []string{"sandbox/**"}, c.PathOverrides[0].Paths)
+	assert.Equal(t, baseline, c.Origin("path_overrides[0]"))
+	assert.Equal(t, []string{"prod/**"}, c.PathOverrides[1].Paths)
+	assert.Equal(t, infra, c.Origin("path_overrides[1]"))
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, config.WriteWithOrigins(buffer, c))
+	output := buffer.String()
+	assert.Contains(t, output, "  AWS002: HIGH  # "+root+"\n")
+	assert.Contains(t, output, "  - '*-enable-logging'  # "+root+"\n")
+	assert.Contains(t, output, "minimum_severity: MEDIUM  # "+infra+"\n")
+	assert.Contains(t, output, "  - paths:  # "+infra+"\n    - prod/**\n")
+}
+
+func TestExtendsCycleIsRejected(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": "extends: [b.yml]",
+		"b.yml": "extends: [./a.yml]",
+	})
+
+	_, err := config.LoadConfig(filepath.Join(dir, "a.yml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "extends itself")
+}
+
+func TestRequireIgnoreReasonIsReplacedWhenSet(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"org.yml":     "require_ignore_reason: true\n",
+		"repo.yml":    "extends: [org.yml]\n",
+		"sandbox.yml": "extends: [org.yml]\nrequire_ignore_reason: false\n",
+		"invalid.yml": "require_ignore_reason: sometimes\n",
+	})
+
+	c, err := config.LoadConfig(filepath.Join(dir, "repo.yml"))
+	require.NoError(t, err)
+	assert.True(t, c.RequiresIgnoreReason())
+	assert.Equal(t, filepath.Join(dir, "org.yml"), c.Origin("require_ignore_reason"))
+
+	c, err = config.LoadConfig(filepath.Join(dir, "sandbox.yml"))
+	require.NoError(t, err)
+	assert.False(t, c.RequiresIgnoreReason())
+
+	_, err = config.LoadConfig(filepath.Join(dir, "invalid.yml"))
+	assert.Error(t, err)
+}
+
+func TestCustomCheckParamsAreMergedByParam(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"org.yml": `
+custom_check_params:
+  CUS001:
+    allowed_regions: [eu-west-1]
+    owner_tag: Owner
+`,
+		"repo.yml": `
+extends: [org.yml]
+custom_check_params:
+  CUS001:
+    allowed_regions: [eu-west-1, us-east-1]
+`,
+	})
+	org := filepath.Join(dir, "org.yml")
+	repo := filepath.Join(dir, "repo.yml")
+
+	c, err := config.LoadConfig(repo)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"CUS001": {
+			"allowed_regions": []interface{}{"eu-west-1", "us-east-1"},
+			"owner_tag":       "Owner",
+		},
+	}, c.CustomCheckParams)
+	assert.Equal(t, repo, c.Origin("custom_check_params.CUS001.allowed_regions"))
+	assert.Equal(t, org, c.Origin("custom_check_params.CUS001.owner_tag"))
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, config.WriteWithOrigins(buffer, c))
+	output := buffer.String()
+	assert.Contains(t, output, "    allowed_regions:  # "+repo+"\n      - eu-west-1\n      - us-east-1\n")
+	assert.Contains(t, output, "    owner_tag: Owner  # "+org+"\n")
+}
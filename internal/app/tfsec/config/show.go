@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// WriteWithOrigins writes the config as YAML, with a comment after each value giving the file it was loaded from
+func WriteWithOrigins(w io.Writer, c *Config) error {
+
+	if len(c.files) == 0 {
+		_, _ = fmt.Fprintln(w, "# No config files found")
+	} else {
+		_, _ = fmt.Fprintln(w, "# Merged from:")
+		for _, file := range c.files {
+			_, _ = fmt.Fprintf(w, "#   %s\n", file)
+		}
+	}
+
+	if len(c.SeverityOverrides) > 0 {
+		_, _ = fmt.Fprintln(w, "severity_overrides:")
+		var ids []string
+		for id := range c.SeverityOverrides {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			_, _ = fmt.Fprintf(w, "  %s: %s%s\n", scalar(id), scalar(c.SeverityOverrides[id]), c.originComment(mapKey("severity_overrides", id)))
+		}
+	}
+
+	for _, field := range c.lists() {
+		if len(*field.values) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s:\n", field.key)
+		for _, value := range *field.values {
+			_, _ = fmt.Fprintf(w, "  - %s%s\n", scalar(value), c.originComment(listKey(field.key, value)))
+		}
+	}
+
+	if c.MinimumSeverity != "" {
+		_, _ = fmt.Fprintf(w, "minimum_severity: %s%s\n", scalar(c.MinimumSeverity), c.originComment("minimum_severity"))
+	}
+
+	if c.RequireIgnoreReason != nil {
+		_, _ = fmt.Fprintf(w, "require_ignore_reason: %t%s\n", *c.RequireIgnoreReason, c.originComment("require_ignore_reason"))
+	}
+
+	if c.Gate.IsSet() {
+		_, _ = fmt.Fprintln(w, "gate:")
+		for _, field := range c.Gate.thresholds() {
+			if len(*field.values) == 0 {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "  %s:\n", strings.TrimPrefix(field.key, "gate."))
+			var keys []string
+			for key := range *field.values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				_, _ = fmt.Fprintf(w, "    %s: %d%s\n", scalar(key), (*field.values)[key], c.originComment(mapKey(field.key, key)))
+			}
+		}
+	}
+
+	if len(c.CustomCheckParams) > 0 {
+		_, _ = fmt.Fprintln(w, "custom_check_params:")
+		var codes []string
+		for code := range c.CustomCheckParams {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			_, _ = fmt.Fprintf(w, "  %s:\n", scalar(code))
+			var names []string
+			for name := range c.CustomCheckParams[code] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				data, err := yaml.Marshal(c.CustomCheckParams[code][name])
+				if err != nil {
+					return err
+				}
+				origin := c.originComment(mapKey(mapKey("custom_check_params", code), name))
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				if kind := reflect.ValueOf(c.CustomCheckParams[code][name]).Kind(); kind != reflect.Slice && kind != reflect.Map {
+					_, _ = fmt.Fprintf(w, "    %s: %s%s\n", scalar(name), lines[0], origin)
+					continue
+				}
+				_, _ = fmt.Fprintf(w, "    %s:%s\n", scalar(name), origin)
+				for _, line := range lines {
+					_, _ = fmt.Fprintf(w, "      %s\n", line)
+				}
+			}
+		}
+	}
+
+	if len(c.PathOverrides) > 0 {
+		_, _ = fmt.Fprintln(w, "path_overrides:")
+		for i, override := range c.PathOverrides {
+			data, err := yaml.Marshal(override)
+			if err != nil {
+				return err
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			_, _ = fmt.Fprintf(w, "  - %s%s\n", lines[0], c.originComment(indexKey("path_overrides", i)))
+			for _, line := range lines[1:] {
+				_, _ = fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) originComment(key string) string {
+	if origin := c.Origin(key); origin != "" {
+		return fmt.Sprintf("  # %s", origin)
+	}
+	return ""
+}
+
+// scalar formats a value as YAML, quoting it if required
+func scalar(value string) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,513 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/defsec/severity"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Problem is an issue found in a config file
+type Problem struct {
+	Filename string
+	// Line and Column give the position of the problem, and are zero if it is not known
+	Line    int
+	Column  int
+	Message string
+	// Warning is true for problems which do not prevent the config from being used, such as excluding a rule which does not exist
+	Warning bool
+}
+
+func (p Problem) Error() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.Filename, level, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.Filename, p.Line, p.Column, level, p.Message)
+}
+
+// Problems is a list of problems found in config files, which is returned as an error by LoadConfig if any of them are errors
+type Problems []Problem
+
+func (p Problems) Error() string {
+	var lines []string
+	for _, problem := range p {
+		lines = append(lines, problem.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors returns true if any of the problems are errors rather than warnings
+func (p Problems) HasErrors() bool {
+	for _, problem := range p {
+		if !problem.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleMatcher returns true if the given rule ID, legacy ID or pattern matches at least one rule
+type RuleMatcher func(id string) bool
+
+// Validate checks the given config files, and any files they extend, for errors such as unknown keys and invalid severities.
+// If a RuleMatcher is provided, rule IDs which do not match any rule are reported as warnings.
+func Validate(configFilePaths []string, isKnownRule RuleMatcher) Problems {
+	var problems Problems
+	visited := make(map[string]bool)
+	for _, path := range configFilePaths {
+		problems = append(problems, validateWithExtends(path, isKnownRule, visited)...)
+	}
+	return problems
+}
+
+func validateWithExtends(configFilePath string, isKnownRule RuleMatcher, visited map[string]bool) Problems {
+	if abs, err := filepath.Abs(configFilePath); err == nil {
+		configFilePath = abs
+	}
+	if visited[configFilePath] {
+		return nil
+	}
+	visited[configFilePath] = true
+
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return Problems{{Filename: configFilePath, Message: fmt.Sprintf("failed to read config file: %s", err)}}
+	}
+	root, problems := validateContent(configFilePath, content, reflect.TypeOf(Config{}), isKnownRule)
+	if root == nil {
+		return problems
+	}
+
+	for _, base := range root.lookup("extends").list() {
+		path := base.value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configFilePath), path)
+		}
+		problems = append(problems, validateWithExtends(path, isKnownRule, visited)...)
+	}
+	return problems
+}
+
+// validateContent parses and validates the content of a file against the struct it is loaded into, returning the parsed content if it could be parsed
+func validateContent(filename string, content []byte, schema reflect.Type, isKnownRule RuleMatcher) (*node, Problems) {
+	root, err := parseNodes(filename, content)
+	if err != nil {
+		var problem Problem
+		if errors.As(err, &problem) {
+			return nil, Problems{problem}
+		}
+		return nil, Problems{{Filename: filename, Message: err.Error()}}
+	}
+
+	v := &validator{
+		filename:    filename,
+		isKnownRule: isKnownRule,
+	}
+	v.validate(root, schema, "")
+	return root, v.problems
+}
+
+const invalidSeverity = "invalid severity '%s', must be one of CRITICAL, HIGH, MEDIUM or LOW (or the legacy ERROR, WARNING or INFO)"
+
+// severityPaths are the paths of values which must be severities, where [] is any list item and * is any map key
+var severityPaths = map[string]bool{
+	"severity_overrides.*":                  true,
+	"minimum_severity":                      true,
+	"path_overrides[].severity_overrides.*": true,
+	"path_overrides[].minimum_severity":     true,
+}
+
+// severityKeyPaths are the paths of maps whose keys must be severities
+var severityKeyPaths = map[string]bool{
+	"gate.severity": true,
+}
+
+// ruleIDPaths are the paths of values which refer to rules, or of maps whose keys refer to rules
+var ruleIDPaths = map[string]bool{
+	"exclude[]":                           true,
+	"include[]":                           true,
+	"severity_overrides":                  true,
+	"path_overrides[].exclude[]":          true,
+	"path_overrides[].severity_overrides": true,
+	"gate.rules":                          true,
+	"custom_check_params":                 true,
+	"ignores[].rule":                      true,
+}
+
+// requiredPaths are the paths of values which must be set and not empty
+var requiredPaths = map[string]bool{
+	"path_overrides[].paths":  true,
+	"ignores[].rule":          true,
+	"ignores[].justification": true,
+	"ignores[].owner":         true,
+}
+
+// datePaths are the paths of values which must be dates in the form YYYY-MM-DD
+var datePaths = map[string]bool{
+	"ignores[].expiry": true,
+}
+
+type validator struct {
+	filename    string
+	isKnownRule RuleMatcher
+	problems    Problems
+}
+
+func (v *validator) report(n *node, warning bool, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		Filename: v.filename,
+		Line:     n.line,
+		Column:   n.column,
+		Message:  fmt.Sprintf(format, args...),
+		Warning:  warning,
+	})
+}
+
+func (v *validator) checkRuleID(n *node, path string) {
+	if v.isKnownRule == nil || !ruleIDPaths[path] {
+		return
+	}
+	if !v.isKnownRule(n.value) {
+		v.report(n, true, "'%s' does not match any rule", n.value)
+	}
+}
+
+func (v *validator) validate(n *node, t reflect.Type, path string) {
+	if n.kind == nullNode {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.kind != mappingNode {
+			v.report(n, false, "%s must be a map", describe(path))
+			return
+		}
+		fields := yamlFields(t)
+		for _, entry := range n.entries {
+			field, ok := fields[entry.key.value]
+			if !ok {
+				v.report(entry.key, false, "unknown key '%s'%s", entry.key.value, suggestKey(entry.key.value, fields))
+				continue
+			}
+			v.validate(entry.value, field.Type, joinPath(path, entry.key.value))
+		}
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if requiredPaths[joinPath(path, name)] && n.lookup(name).isEmpty() {
+				v.report(n, false, "'%s' must be set and not empty", name)
+			}
+		}
+	case reflect.Slice:
+		if n.kind != sequenceNode {
+			v.report(n, false, "%s must be a list", describe(path))
+			return
+		}
+		for _, item := range n.items {
+			v.validate(item, t.Elem(), path+"[]")
+		}
+	case reflect.Map:
+		if n.kind != mappingNode {
+			v.report(n, false, "%s must be a map", describe(path))
+			return
+		}
+		for _, entry := range n.entries {
+			if severityKeyPaths[path] && severity.StringToSeverity(entry.key.value) == severity.None {
+				v.report(entry.key, false, invalidSeverity, entry.key.value)
+			}
+			v.checkRuleID(entry.key, path)
+			v.validate(entry.value, t.Elem(), path+".*")
+		}
+	case reflect.String:
+		if n.kind != scalarNode {
+			v.report(n, false, "%s must be a string", describe(path))
+			return
+		}
+		if severityPaths[path] && severity.StringToSeverity(n.value) == severity.None {
+			v.report(n, false, invalidSeverity, n.value)
+		}
+		if datePaths[path] {
+			if _, err := time.Parse(dateFormat, n.value); err != nil {
+				v.report(n, false, "invalid date '%s', must be in the form YYYY-MM-DD", n.value)
+			}
+		}
+		v.checkRuleID(n, path)
+	case reflect.Int:
+		if n.kind != scalarNode {
+			v.report(n, false, "%s must be a number", describe(path))
+			return
+		}
+		if number, err := strconv.Atoi(n.value); err != nil || number < 0 {
+			v.report(n, false, "%s must be a whole number of zero or more, not '%s'", describe(path), n.value)
+		}
+	case reflect.Ptr:
+		v.validate(n, t.Elem(), path)
+	case reflect.Bool:
+		if n.kind != scalarNode || (n.value != "true" && n.value != "false") {
+			v.report(n, false, "%s must be true or false", describe(path))
+		}
+	}
+}
+
+// yamlFields returns the fields of a struct by the name used for them in config files
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggestKey suggests a known key which the given unknown key may be a misspelling of
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.EqualFold(name, key) || strings.EqualFold(strings.ReplaceAll(name, "_", ""), strings.ReplaceAll(strings.ReplaceAll(key, "-", ""), "_", "")) {
+			return fmt.Sprintf(", did you mean '%s'?", name)
+		}
+	}
+	return ""
+}
+
+func joinPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func describe(path string) string {
+	if path == "" {
+		return "the config"
+	}
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(path, ".*", ""))
+}
+
+type nodeKind int
+
+const (
+	nullNode nodeKind = iota
+	scalarNode
+	sequenceNode
+	mappingNode
+)
+
+// node is a value within a config file, along with its position, so that problems can be reported for both JSON and YAML files
+type node struct {
+	kind    nodeKind
+	value   string
+	line    int
+	column  int
+	entries []nodeEntry
+	items   []*node
+}
+
+type nodeEntry struct {
+	key   *node
+	value *node
+}
+
+// lookup returns the value of the given key of a map, or a null node if it is not set
+func (n *node) lookup(key string) *node {
+	if n != nil {
+		for _, entry := range n.entries {
+			if entry.key.value == key {
+				return entry.value
+			}
+		}
+	}
+	return &node{}
+}
+
+// isEmpty returns true if the node is null, an empty string, or an empty list or map
+func (n *node) isEmpty() bool {
+	return n == nil || n.kind == nullNode || (n.kind == scalarNode && n.value == "") || (n.kind == sequenceNode && len(n.items) == 0) || (n.kind == mappingNode && len(n.entries) == 0)
+}
+
+// list returns the items of a list, or nothing if the node is not a list
+func (n *node) list() []*node {
+	if n == nil || n.kind != sequenceNode {
+		return nil
+	}
+	return n.items
+}
+
+func parseNodes(filename string, content []byte) (*node, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return parseJSONNodes(filename, content)
+	case ".yaml", ".yml":
+		return parseYAMLNodes(filename, content)
+	default:
+		return nil, fmt.Errorf("couldn't process the file %s", filename)
+	}
+}
+
+func parseYAMLNodes(filename string, content []byte) (*node, error) {
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &document); err != nil {
+		return nil, Problem{Filename: filename, Message: err.Error()}
+	}
+	if len(document.Content) == 0 {
+		return &node{line: 1, column: 1}, nil
+	}
+	return convertYAMLNode(document.Content[0]), nil
+}
+
+func convertYAMLNode(yamlNode *yamlv3.Node) *node {
+	for yamlNode.Kind == yamlv3.AliasNode && yamlNode.Alias != nil {
+		yamlNode = yamlNode.Alias
+	}
+	n := &node{
+		line:   yamlNode.Line,
+		column: yamlNode.Column,
+	}
+	switch yamlNode.Kind {
+	case yamlv3.ScalarNode:
+		if yamlNode.Tag != "!!null" {
+			n.kind = scalarNode
+			n.value = yamlNode.Value
+		}
+	case yamlv3.SequenceNode:
+		n.kind = sequenceNode
+		for _, item := range yamlNode.Content {
+			n.items = append(n.items, convertYAMLNode(item))
+		}
+	case yamlv3.MappingNode:
+		n.kind = mappingNode
+		for i := 0; i+1 < len(yamlNode.Content); i += 2 {
+			n.entries = append(n.entries, nodeEntry{
+				key:   convertYAMLNode(yamlNode.Content[i]),
+				value: convertYAMLNode(yamlNode.Content[i+1]),
+			})
+		}
+	}
+	return n
+}
+
+// jsonParser builds nodes from JSON tokens, using the offset of each token to find its line and column
+type jsonParser struct {
+	filename string
+	content  []byte
+	decoder  *json.Decoder
+}
+
+func parseJSONNodes(filename string, content []byte) (*node, error) {
+	p := &jsonParser{
+		filename: filename,
+		content:  content,
+		decoder:  json.NewDecoder(bytes.NewReader(content)),
+	}
+	p.decoder.UseNumber()
+	root, err := p.parse()
+	if err == io.EOF {
+		return &node{line: 1, column: 1}, nil
+	}
+	return root, err
+}
+
+func (p *jsonParser) parse() (*node, error) {
+	n := p.position(p.decoder.InputOffset())
+	token, err := p.decoder.Token()
+	if err != nil {
+		return nil, p.convertError(err)
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		switch value {
+		case '{':
+			n.kind = mappingNode
+			for p.decoder.More() {
+				key, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				entryValue, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				n.entries = append(n.entries, nodeEntry{key: key, value: entryValue})
+			}
+		case '[':
+			n.kind = sequenceNode
+			for p.decoder.More() {
+				item, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		}
+		// consume the closing delimiter
+		if _, err := p.decoder.Token(); err != nil {
+			return nil, p.convertError(err)
+		}
+	case nil:
+	default:
+		n.kind = scalarNode
+		n.value = fmt.Sprintf("%v", value)
+	}
+	return n, nil
+}
+
+// position returns a node positioned at the start of the next token after the given offset
+func (p *jsonParser) position(offset int64) *node {
+	for offset < int64(len(p.content)) && strings.ContainsRune(" \t\r\n,:", rune(p.content[offset])) {
+		offset++
+	}
+	line, column := lineAndColumn(p.content, offset)
+	return &node{line: line, column: column}
+}
+
+func (p *jsonParser) convertError(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	problem := Problem{Filename: p.filename, Message: err.Error()}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		problem.Line, problem.Column = lineAndColumn(p.content, syntaxErr.Offset)
+	} else {
+		problem.Line, problem.Column = lineAndColumn(p.content, p.decoder.InputOffset())
+	}
+	return problem
+}
+
+func lineAndColumn(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
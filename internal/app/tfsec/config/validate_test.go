@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownKeysAreRejectedWithPosition(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.yml": `
+exclude:
+  - AWS001
+exlude:
+  - AWS002
+`,
+		"config.json": `{
+  "exclude": ["AWS001"],
+  "Severity_Overrides": {}
+}`,
+	})
+
+	_, err := config.LoadConfig(filepath.Join(dir, "config.yml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config.yml:4:1: error: unknown key 'exlude'")
+
+	_, err = config.LoadConfig(filepath.Join(dir, "config.json"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config.json:3:3: error: unknown key 'Severity_Overrides', did you mean 'severity_overrides'?")
+}
+
+func TestInvalidValuesAreRejected(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.yml": `
+severity_overrides:
+  AWS001: extreme
+exclude: AWS002
+`,
+	})
+
+	problems := config.Validate([]string{filepath.Join(dir, "config.yml")}, nil)
+	require.Len(t, problems, 2)
+	assert.True(t, problems.HasErrors())
+	assert.Equal(t, 3, problems[0].Line)
+	assert.Equal(t, 11, problems[0].Column)
+	assert.Contains(t, problems[0].Message, "invalid severity 'extreme'")
+	assert.Equal(t, 4, problems[1].Line)
+	assert.Equal(t, "'exclude' must be a list", problems[1].Message)
+}
+
+func TestUnknownRuleIDsAreWarnings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yml": `
+exclude:
+  - AWS999
+`,
+		".tfsec/config.yml": `
+extends:
+  - ../base.yml
+exclude:
+  - aws-s3-*
+severity_overrides:
+  AWS001: LOW
+path_overrides:
+  - paths: ["prod/**"]
+    exclude:
+      - gcp-*
+`,
+	})
+
+	known := []string{"AWS001", "aws-s3-enable-versioning"}
+	isKnown := func(id string) bool {
+		for _, k := range known {
+			if matched, _ := filepath.Match(id, k); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	problems := config.Validate([]string{filepath.Join(dir, ".tfsec", "config.yml")}, isKnown)
+	require.Len(t, problems, 2)
+	assert.False(t, problems.HasErrors())
+	assert.Equal(t, "'gcp-*' does not match any rule", problems[0].Message)
+	assert.Equal(t, 11, problems[0].Line)
+	assert.Equal(t, "'AWS999' does not match any rule", problems[1].Message)
+	assert.Equal(t, filepath.Join(dir, "base.yml"), problems[1].Filename)
+
+	_, err := config.LoadConfig(filepath.Join(dir, ".tfsec", "config.yml"))
+	assert.NoError(t, err)
+}
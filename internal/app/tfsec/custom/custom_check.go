@@ -2,6 +2,7 @@ package custom
 
 import (
 	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
 )
 
 type MatchType string
@@ -28,6 +29,13 @@ var ValidCheckActions = []CheckAction{
 	IsNone,
 	HasTag,
 	OfType,
+	Expression,
+	IsReferencedBy,
+	References,
+	Any,
+	All,
+	None,
+	Count,
 	And,
 	Or,
 	Not,
@@ -102,6 +110,27 @@ const HasTag CheckAction = "hasTag"
 // OfType checks that each resource block is of a defined type
 const OfType CheckAction = "ofType"
 
+// Expression checks that the HCL expression in the check value evaluates to true, with the block's attributes available as self
+const Expression CheckAction = "expression"
+
+// IsReferencedBy checks that a resource of the type named before the dot refers to the block in the attribute named after it
+const IsReferencedBy CheckAction = "isReferencedBy"
+
+// References checks that the named attribute refers to another block, of the type given as the check value if there is one
+const References CheckAction = "references"
+
+// Any checks that at least one of the named nested blocks or list elements satisfies the subMatch
+const Any CheckAction = "any"
+
+// All checks that every one of the named nested blocks or list elements satisfies the subMatch
+const All CheckAction = "all"
+
+// None checks that none of the named nested blocks or list elements satisfy the subMatch
+const None CheckAction = "none"
+
+// Count checks that the number of the named nested blocks or list elements which satisfy the subMatch is between min and max
+const Count CheckAction = "count"
+
 // MatchSpec specifies the checks that should be performed
 type MatchSpec struct {
 	Name               string      `json:"name,omitempty" yaml:"name,omitempty"`
@@ -112,6 +141,13 @@ type MatchSpec struct {
 	SubMatch           *MatchSpec  `json:"subMatch,omitempty" yaml:"subMatch,omitempty"`
 	IgnoreUndefined    bool        `json:"ignoreUndefined,omitempty" yaml:"ignoreUndefined,omitempty"`
 	IgnoreUnmatched    bool        `json:"ignoreUnmatched,omitempty" yaml:"ignoreUnmatched,omitempty"`
+	Min                *int        `json:"min,omitempty" yaml:"min,omitempty"`
+	Max                *int        `json:"max,omitempty" yaml:"max,omitempty"`
+	// Ref names a fragment of the check file which the spec is replaced by
+	Ref string `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+
+	// source is the location the spec was defined at, if known
+	source string
 }
 
 //Check specifies the check definition represented in json/yaml
@@ -127,6 +163,24 @@ type Check struct {
 	RelatedLinks    []string          `json:"relatedLinks,omitempty" yaml:"relatedLinks,omitempty"`
 	Impact          string            `json:"impact,omitempty" yaml:"impact,omitempty"`
 	Resolution      string            `json:"resolution,omitempty" yaml:"resolution,omitempty"`
+	Provider        string            `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Service         string            `json:"service,omitempty" yaml:"service,omitempty"`
+	Explanation     string            `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	GoodExamples    []string          `json:"goodExamples,omitempty" yaml:"goodExamples,omitempty"`
+	BadExamples     []string          `json:"badExamples,omitempty" yaml:"badExamples,omitempty"`
+	ComplianceTags  []string          `json:"complianceTags,omitempty" yaml:"complianceTags,omitempty"`
+	// Target is a path within the adapted defsec state, such as aws.s3.buckets, which the check runs against instead of blocks
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	// Params are the default values of parameters which match spec values can refer to with {"$param": name}, and which
+	// can be overridden by the tfsec config
+	Params map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+
+	// source is the location the check was defined at, if known
+	source string
+	// fragments are the match specs which the check can refer to with $ref
+	fragments map[string]*MatchSpec
+	// debug is the logger the check was loaded with
+	debug debug.Logger
 }
 
 func (action *CheckAction) isValid() bool {
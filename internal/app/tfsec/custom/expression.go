@@ -0,0 +1,57 @@
+package custom
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// parseExpression parses the value of an expression match spec
+func parseExpression(spec *MatchSpec) (hclsyntax.Expression, error) {
+	source, ok := spec.MatchValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("matchSpec.Value for the `%s` action must be a string", Expression)
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(source), "expression", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("matchSpec.Value is not a valid expression: %s", diagnosticsError(diags))
+	}
+	return expr, nil
+}
+
+// evalExpression evaluates the expression within the context the block was evaluated in, so the functions, variables, locals and
+// other resources of its module are available alongside the block's own attributes as self. The expression must return a bool,
+// otherwise the spec evaluates to the value of IgnoreUndefined.
+func (m matcher) evalExpression(b block.Block, spec *MatchSpec) bool {
+	expr, err := parseExpression(spec)
+	if err != nil {
+		m.debug.Log("Failed to parse expression for %s: %s", b.FullName(), err)
+		return false
+	}
+
+	var ctx *hcl.EvalContext
+	if b.Context() != nil {
+		ctx = b.Context().Inner().NewChild()
+	} else {
+		ctx = &hcl.EvalContext{}
+	}
+	ctx.Variables = map[string]cty.Value{
+		"self": b.Values(),
+	}
+
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		m.debug.Log("Failed to evaluate expression for %s: %s", b.FullName(), diagnosticsError(diags))
+		return spec.IgnoreUndefined
+	}
+	val, err = convert.Convert(val, cty.Bool)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		m.debug.Log("Expression for %s did not evaluate to a bool", b.FullName())
+		return spec.IgnoreUndefined
+	}
+	return val.True()
+}
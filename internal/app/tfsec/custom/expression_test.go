@@ -0,0 +1,116 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressionMatchFunction(t *testing.T) {
+	var tests = []struct {
+		name      string
+		source    string
+		matchSpec MatchSpec
+		expected  bool
+	}{
+		{
+			name: "check that an expression using the block's attributes and functions passes",
+			source: `
+resource "aws_autoscaling_group" "example" {
+	min_size = 1
+	max_size = 3
+	tags     = { Owner = "team" }
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: "self.min_size <= self.max_size && length(self.tags) > 0"},
+			expected:  true,
+		},
+		{
+			name: "check that an expression using the block's attributes and functions fails",
+			source: `
+resource "aws_autoscaling_group" "example" {
+	min_size = 4
+	max_size = 3
+	tags     = { Owner = "team" }
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: "self.min_size <= self.max_size && length(self.tags) > 0"},
+			expected:  false,
+		},
+		{
+			name: "check that an expression can use variables and other resources in the module",
+			source: `
+variable "max" {
+	default = 5
+}
+
+resource "aws_launch_template" "example" {
+	instance_type = "t3.micro"
+}
+
+resource "aws_autoscaling_group" "example" {
+	max_size = 3
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: `self.max_size <= var.max && substr(aws_launch_template.example.instance_type, 0, 3) == "t3."`},
+			expected:  true,
+		},
+		{
+			name: "check that an expression referencing a missing attribute fails",
+			source: `
+resource "aws_autoscaling_group" "example" {
+	max_size = 3
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: "self.min_size <= self.max_size"},
+			expected:  false,
+		},
+		{
+			name: "check that an expression referencing a missing attribute passes when ignoring undefined values",
+			source: `
+resource "aws_autoscaling_group" "example" {
+	max_size = 3
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: "self.min_size <= self.max_size", IgnoreUndefined: true},
+			expected:  true,
+		},
+		{
+			name: "check that an expression which does not return a bool fails",
+			source: `
+resource "aws_autoscaling_group" "example" {
+	max_size = 3
+}
+`,
+			matchSpec: MatchSpec{Action: Expression, MatchValue: "self.max_size"},
+			expected:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block := ParseFromSource(test.source)[0].GetResourcesByType("aws_autoscaling_group")[0]
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, nil)
+			assert.Equal(t, test.expected, result, "expression evaluating incorrectly.")
+		})
+	}
+}
+
+func TestExpressionIsValidated(t *testing.T) {
+	check := &Check{
+		Code:           "CUS001",
+		Description:    "Autoscaling groups must have a sensible size",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_autoscaling_group"},
+		Severity:       "HIGH",
+		MatchSpec:      &MatchSpec{Action: Expression, MatchValue: "self.min_size <="},
+	}
+	errs := validate(check)
+	assert.Len(t, errs, 1)
+
+	check.MatchSpec.MatchValue = "self.min_size <= self.max_size"
+	assert.Empty(t, validate(check))
+
+	check.MatchSpec.MatchValue = 3
+	assert.Len(t, validate(check), 1)
+}
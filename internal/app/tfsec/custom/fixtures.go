@@ -0,0 +1,195 @@
+package custom
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing/fstest"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+)
+
+// TestResult is the outcome of running a check against one of its fixtures
+type TestResult struct {
+	Code string
+	// Fixture is the path of the fixture, or the inline example it came from, e.g. badExamples[0]
+	Fixture string
+	// ExpectFailure is true for fixtures which should fail the check
+	ExpectFailure bool
+	// Failures describes each failure of the check reported for the fixture
+	Failures []string
+	// Err is set if the fixture could not be scanned
+	Err error
+}
+
+// Passed reports whether the check behaved as the fixture expected
+func (r TestResult) Passed() bool {
+	return r.Err == nil && (len(r.Failures) > 0) == r.ExpectFailure
+}
+
+type fixture struct {
+	name          string
+	expectFailure bool
+	parse         func() ([]block.Module, error)
+}
+
+// RunTests runs each check in the check file against its fixtures, which are its inline good and bad examples and the contents of
+// testdata/<code>/pass and testdata/<code>/fail beside the check file. Each .tf file in those directories is a fixture by itself,
+// while each subdirectory is a fixture made up of all of its files. Only the check itself is run against each fixture.
+func RunTests(checkFilePath string) ([]TestResult, error) {
+	if err := Validate(checkFilePath); err != nil {
+		return nil, err
+	}
+	checks, err := loadCheckFile(checkFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TestResult
+	for _, check := range checks.Checks {
+		fixtures, err := findFixtures(check, filepath.Join(filepath.Dir(checkFilePath), "testdata", check.Code))
+		if err != nil {
+			return nil, err
+		}
+		checkRules, err := processFoundChecks(ChecksFile{Checks: []*Check{check}}, debug.Logger{})
+		if err != nil {
+			return nil, err
+		}
+		checkRule := checkRules[0]
+		for _, f := range fixtures {
+			results = append(results, runFixture(check.Code, checkRule, f))
+		}
+	}
+	return results, nil
+}
+
+func findFixtures(check *Check, dir string) ([]fixture, error) {
+	var fixtures []fixture
+	for i, example := range check.GoodExamples {
+		fixtures = append(fixtures, inlineFixture(fmt.Sprintf("goodExamples[%d]", i), example, false))
+	}
+	for i, example := range check.BadExamples {
+		fixtures = append(fixtures, inlineFixture(fmt.Sprintf("badExamples[%d]", i), example, true))
+	}
+	for _, outcome := range []string{"pass", "fail"} {
+		outcomeDir := filepath.Join(dir, outcome)
+		entries, err := os.ReadDir(outcomeDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(outcomeDir, entry.Name())
+			switch {
+			case entry.IsDir():
+				fixtures = append(fixtures, fixture{
+					name:          path,
+					expectFailure: outcome == "fail",
+					parse: func() ([]block.Module, error) {
+						return parseDirectory(path)
+					},
+				})
+			case isTerraformFile(entry.Name()):
+				fixtures = append(fixtures, fileFixture(path, outcome == "fail"))
+			}
+		}
+	}
+	return fixtures, nil
+}
+
+func inlineFixture(name string, content string, expectFailure bool) fixture {
+	return fixture{
+		name:          name,
+		expectFailure: expectFailure,
+		parse: func() ([]block.Module, error) {
+			fsys := fstest.MapFS{"main.tf": &fstest.MapFile{Data: []byte(content)}}
+			return parser.New(".", parser.OptionWithFS(fsys), parser.OptionWithWarningWriter(nil)).ParseDirectory()
+		},
+	}
+}
+
+// fileFixture parses a single file, so that fixtures beside each other are separate
+func fileFixture(path string, expectFailure bool) fixture {
+	return fixture{
+		name:          path,
+		expectFailure: expectFailure,
+		parse: func() ([]block.Module, error) {
+			return parseFile(path)
+		},
+	}
+}
+
+func parseDirectory(path string) ([]block.Module, error) {
+	return parser.New(path, parser.OptionWithWarningWriter(nil)).ParseDirectory()
+}
+
+// parseFile parses a single terraform file, hiding the other terraform files in its directory
+func parseFile(path string) ([]block.Module, error) {
+	fsys := singleFileFS{FS: os.DirFS(filepath.Dir(path)), name: filepath.Base(path)}
+	return parser.New(".", parser.OptionWithFS(fsys), parser.OptionWithWarningWriter(nil)).ParseDirectory()
+}
+
+func runFixture(code string, checkRule rule.Rule, f fixture) TestResult {
+	result := TestResult{
+		Code:          code,
+		Fixture:       f.name,
+		ExpectFailure: f.expectFailure,
+	}
+	modules, err := f.parse()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	scanned, err := scanner.New(scanner.OptionWithRules([]rule.Rule{checkRule})).Scan(modules)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	seen := make(map[string]bool)
+	for _, r := range scanned {
+		if r.Status() == rules.StatusPassed {
+			continue
+		}
+		// checks with a target run once per module, so the same failure can be reported more than once
+		failure := fmt.Sprintf("%s: %s", r.NarrowestRange(), r.Description())
+		if !seen[failure] {
+			seen[failure] = true
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+	sort.Strings(result.Failures)
+	return result
+}
+
+func isTerraformFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+// singleFileFS lists only one of the terraform files in its root directory
+type singleFileFS struct {
+	fs.FS
+	name string
+}
+
+func (s singleFileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(s.FS, name)
+	if err != nil || name != "." {
+		return entries, err
+	}
+	var filtered []fs.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() || !isTerraformFile(entry.Name()) || entry.Name() == s.name {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,99 @@
+package custom
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+	checkFile := writeCheckFile(t, dir, "acme_tfchecks.yaml", `
+checks:
+- code: CUS301
+  description: Instances must have an owner tag
+  requiredTypes: [resource]
+  requiredLabels: [aws_instance]
+  severity: HIGH
+  matchSpec:
+    name: tags
+    action: contains
+    value: Owner
+  goodExamples:
+  - |
+    resource "aws_instance" "good" {
+      tags = { Owner = "team" }
+    }
+  badExamples:
+  - |
+    resource "aws_instance" "bad" {
+    }
+`)
+	writeCheckFile(t, dir, "testdata/CUS301/pass/tagged.tf", `
+resource "aws_instance" "tagged" {
+  tags = { Owner = "team" }
+}
+`)
+	writeCheckFile(t, dir, "testdata/CUS301/fail/untagged.tf", `
+resource "aws_instance" "untagged" {
+}
+`)
+	writeCheckFile(t, dir, "testdata/CUS301/fail/wrongly_tagged.tf", `
+resource "aws_instance" "wrongly_tagged" {
+  tags = { Owner = "team" }
+}
+`)
+	writeCheckFile(t, dir, "testdata/CUS301/pass/module/main.tf", `
+locals {
+  owner = "team"
+}
+`)
+	writeCheckFile(t, dir, "testdata/CUS301/pass/module/instance.tf", `
+resource "aws_instance" "tagged" {
+  tags = { Owner = local.owner }
+}
+`)
+
+	results, err := RunTests(checkFile)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	byFixture := make(map[string]TestResult)
+	for _, result := range results {
+		assert.Equal(t, "CUS301", result.Code)
+		assert.NoError(t, result.Err)
+		byFixture[result.Fixture] = result
+	}
+
+	fixtureDir := filepath.Join(dir, "testdata", "CUS301")
+	for _, fixture := range []string{
+		"goodExamples[0]",
+		"badExamples[0]",
+		filepath.Join(fixtureDir, "pass", "tagged.tf"),
+		filepath.Join(fixtureDir, "pass", "module"),
+		filepath.Join(fixtureDir, "fail", "untagged.tf"),
+	} {
+		assert.True(t, byFixture[fixture].Passed(), fixture)
+	}
+
+	wronglyTagged := byFixture[filepath.Join(fixtureDir, "fail", "wrongly_tagged.tf")]
+	assert.True(t, wronglyTagged.ExpectFailure)
+	assert.Empty(t, wronglyTagged.Failures)
+	assert.False(t, wronglyTagged.Passed())
+
+	untagged := byFixture[filepath.Join(fixtureDir, "fail", "untagged.tf")]
+	require.Len(t, untagged.Failures, 1)
+	assert.Contains(t, untagged.Failures[0], "aws_instance.untagged")
+}
+
+func TestRunTestsRejectsInvalidChecks(t *testing.T) {
+	checkFile := writeCheckFile(t, t.TempDir(), "acme_tfchecks.yaml", `
+checks:
+- code: CUS302
+  severity: HIGH
+`)
+	_, err := RunTests(checkFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package custom
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// paramKey is the only key of a value which is replaced by the named parameter of the check, e.g. {"$param": "allowed_regions"}
+const paramKey = "$param"
+
+// overrideParams replaces the parameters of each check with any given for its code
+func overrideParams(checks ChecksFile, params map[string]map[string]interface{}) {
+	for _, check := range checks.Checks {
+		for name, value := range params[check.Code] {
+			if check.Params == nil {
+				check.Params = make(map[string]interface{})
+			}
+			check.Params[name] = value
+		}
+	}
+}
+
+// linkFragments gives each check the fragments of its file, along with those of the files it imports, where fragments
+// defined in the file itself take precedence
+func linkFragments(checkFilePath string, checks *ChecksFile) error {
+	fragments, err := loadFragments(checkFilePath, *checks, nil)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks.Checks {
+		check.fragments = fragments
+	}
+	return nil
+}
+
+func loadFragments(checkFilePath string, checks ChecksFile, chain []string) (map[string]*MatchSpec, error) {
+	if abs, err := filepath.Abs(checkFilePath); err == nil {
+		checkFilePath = abs
+	}
+	for _, previous := range chain {
+		if previous == checkFilePath {
+			return nil, fmt.Errorf("check file '%s' imports itself: %s", checkFilePath, strings.Join(append(chain, checkFilePath), " -> "))
+		}
+	}
+	chain = append(chain, checkFilePath)
+
+	fragments := make(map[string]*MatchSpec)
+	for _, imported := range checks.Imports {
+		if !filepath.IsAbs(imported) {
+			imported = filepath.Join(filepath.Dir(checkFilePath), imported)
+		}
+		importedChecks, err := parseCheckFile(imported)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import '%s': %w", imported, err)
+		}
+		importedFragments, err := loadFragments(imported, importedChecks, append([]string{}, chain...))
+		if err != nil {
+			return nil, err
+		}
+		for name, fragment := range importedFragments {
+			fragments[name] = fragment
+		}
+	}
+	for name, fragment := range checks.Fragments {
+		fragments[name] = fragment
+	}
+	return fragments, nil
+}
+
+// resolve returns a copy of the spec with each $ref replaced by the fragment it names and each $param value replaced by the
+// parameter of the check, where chain lists the fragments being resolved so that cycles can be reported
+func (check *Check) resolve(spec *MatchSpec, chain []string) (*MatchSpec, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	if spec.Ref != "" {
+		for _, previous := range chain {
+			if previous == spec.Ref {
+				return nil, fmt.Errorf("matchSpec.$ref[%s] is cyclic: %s", spec.Ref, strings.Join(append(chain, spec.Ref), " -> "))
+			}
+		}
+		fragment, ok := check.fragments[spec.Ref]
+		if !ok || fragment == nil {
+			return nil, fmt.Errorf("matchSpec.$ref[%s] does not name a fragment", spec.Ref)
+		}
+		return check.resolve(fragment, append(append([]string{}, chain...), spec.Ref))
+	}
+
+	resolved := *spec
+	var err error
+	if resolved.MatchValue, err = check.resolveValue(spec.MatchValue); err != nil {
+		return nil, err
+	}
+	if resolved.PreConditions, err = check.resolveAll(spec.PreConditions, chain); err != nil {
+		return nil, err
+	}
+	if resolved.PredicateMatchSpec, err = check.resolveAll(spec.PredicateMatchSpec, chain); err != nil {
+		return nil, err
+	}
+	if resolved.SubMatch, err = check.resolve(spec.SubMatch, chain); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+func (check *Check) resolveAll(specs []MatchSpec, chain []string) ([]MatchSpec, error) {
+	if specs == nil {
+		return nil, nil
+	}
+	resolved := make([]MatchSpec, 0, len(specs))
+	for i := range specs {
+		spec, err := check.resolve(&specs[i], chain)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, *spec)
+	}
+	return resolved, nil
+}
+
+// resolveValue replaces a value of the form {"$param": name} with the named parameter of the check
+func (check *Check) resolveValue(value interface{}) (interface{}, error) {
+	name, ok := paramName(value)
+	if !ok {
+		return value, nil
+	}
+	param, ok := check.Params[name]
+	if !ok {
+		return nil, fmt.Errorf("matchSpec.Value refers to the parameter %s, which is not defined by check.Params", name)
+	}
+	return param, nil
+}
+
+// paramName returns the name of the parameter if the value refers to one, as decoded from either JSON or YAML
+func paramName(value interface{}) (string, bool) {
+	var name interface{}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(value) != 1 {
+			return "", false
+		}
+		name = value[paramKey]
+	case map[interface{}]interface{}:
+		if len(value) != 1 {
+			return "", false
+		}
+		name = value[paramKey]
+	}
+	s, ok := name.(string)
+	return s, ok
+}
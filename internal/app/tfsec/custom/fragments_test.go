@@ -0,0 +1,228 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fragmentsCheckFile = `
+imports:
+- shared/library.yaml
+fragments:
+  allowed_region:
+    name: region
+    action: isAny
+    value:
+      $param: allowed_regions
+checks:
+- code: CUS501
+  description: Buckets must be tagged and in an allowed region
+  requiredTypes: [resource]
+  requiredLabels: [aws_s3_bucket]
+  severity: HIGH
+  params:
+    allowed_regions: [eu-west-1]
+  matchSpec:
+    action: and
+    predicateMatchSpec:
+    - $ref: cost_centre
+    - $ref: allowed_region
+`
+
+const fragmentsLibraryFile = `
+fragments:
+  cost_centre:
+    name: tags
+    action: contains
+    value: CostCentre
+`
+
+func TestFragmentsAndParams(t *testing.T) {
+	dir := t.TempDir()
+	checkFile := writeCheckFile(t, dir, "acme_tfchecks.yaml", fragmentsCheckFile)
+	writeCheckFile(t, dir, "shared/library.yaml", fragmentsLibraryFile)
+	require.NoError(t, Validate(checkFile))
+
+	rules, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	assert.Len(t, scanWithRules(t, rules, `
+resource "aws_s3_bucket" "good" {
+  region = "eu-west-1"
+  tags   = { CostCentre = "123" }
+}
+`), 0)
+	assert.Len(t, scanWithRules(t, rules, `
+resource "aws_s3_bucket" "untagged" {
+  region = "eu-west-1"
+}
+`), 1)
+	assert.Len(t, scanWithRules(t, rules, `
+resource "aws_s3_bucket" "elsewhere" {
+  region = "us-east-1"
+  tags   = { CostCentre = "123" }
+}
+`), 1)
+
+	overridden, err := Load(dir, OptionWithParams(map[string]map[string]interface{}{
+		"CUS501": {"allowed_regions": []interface{}{"us-east-1"}},
+	}))
+	require.NoError(t, err)
+	assert.Len(t, scanWithRules(t, overridden, `
+resource "aws_s3_bucket" "elsewhere" {
+  region = "us-east-1"
+  tags   = { CostCentre = "123" }
+}
+`), 0)
+}
+
+func TestFragmentsInHCL(t *testing.T) {
+	dir := t.TempDir()
+	writeCheckFile(t, dir, "acme_tfchecks.hcl", `
+fragment "owner" {
+  name   = "tags"
+  action = "contains"
+  value  = { "$param" = "owner_tag" }
+}
+
+check "CUS502" {
+  description     = "Instances must have an owner"
+  required_types  = ["resource"]
+  required_labels = ["aws_instance"]
+  severity        = "LOW"
+  params          = { owner_tag = "Owner" }
+
+  match {
+    ref = "owner"
+  }
+}
+`)
+	rules, err := Load(dir)
+	require.NoError(t, err)
+
+	assert.Len(t, scanWithRules(t, rules, `
+resource "aws_instance" "owned" {
+  tags = { Owner = "team" }
+}
+`), 0)
+	assert.Len(t, scanWithRules(t, rules, `
+resource "aws_instance" "unowned" {
+}
+`), 1)
+}
+
+func TestFragmentValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name: "cycle",
+			content: `
+fragments:
+  first:
+    action: not
+    predicateMatchSpec:
+    - $ref: second
+  second:
+    action: and
+    predicateMatchSpec:
+    - $ref: first
+checks:
+- code: CUS503
+  description: Cyclic fragments
+  requiredTypes: [resource]
+  requiredLabels: [aws_instance]
+  severity: LOW
+  matchSpec:
+    $ref: first
+`,
+			expected: "matchSpec.$ref[first] is cyclic: first -> second -> first",
+		},
+		{
+			name: "unknown fragment",
+			content: `
+checks:
+- code: CUS503
+  description: Unknown fragment
+  requiredTypes: [resource]
+  requiredLabels: [aws_instance]
+  severity: LOW
+  matchSpec:
+    $ref: missing
+`,
+			expected: "matchSpec.$ref[missing] does not name a fragment",
+		},
+		{
+			name: "undefined parameter",
+			content: `
+checks:
+- code: CUS503
+  description: Undefined parameter
+  requiredTypes: [resource]
+  requiredLabels: [aws_instance]
+  severity: LOW
+  matchSpec:
+    name: instance_type
+    action: isAny
+    value:
+      $param: instance_types
+`,
+			expected: "matchSpec.Value refers to the parameter instance_types, which is not defined by check.Params",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkFile := writeCheckFile(t, t.TempDir(), "acme_tfchecks.yaml", test.content)
+			err := Validate(checkFile)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
+
+func TestUnresolvedChecksAreNotDropped(t *testing.T) {
+	check := &Check{
+		Code:           "CUS504",
+		Description:    "Undefined parameter",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_instance"},
+		Severity:       "LOW",
+		MatchSpec:      &MatchSpec{Name: "instance_type", Action: IsAny, MatchValue: map[string]interface{}{paramKey: "instance_types"}},
+	}
+
+	loaded, err := processFoundChecks(ChecksFile{Checks: []*Check{check}}, debug.Logger{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "check CUS504 failed with the following errors")
+	assert.Contains(t, err.Error(), "which is not defined by check.Params")
+	assert.Empty(t, loaded)
+}
+
+func TestImportCycle(t *testing.T) {
+	dir := t.TempDir()
+	checkFile := writeCheckFile(t, dir, "first_tfchecks.yaml", "imports: [second.yaml]\n")
+	writeCheckFile(t, dir, "second.yaml", "imports: [first_tfchecks.yaml]\n")
+
+	err := Validate(checkFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "imports itself")
+}
+
+func scanWithRules(t *testing.T, rules []rule.Rule, source string) []string {
+	results, err := scanner.New(scanner.OptionWithRules(rules)).Scan(ParseFromSource(source))
+	require.NoError(t, err)
+
+	var failures []string
+	for _, result := range results {
+		failures = append(failures, result.Description())
+	}
+	return failures
+}
@@ -0,0 +1,378 @@
+package custom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+)
+
+// maxGenerateDepth is how deeply nested blocks are compared when generating a check
+const maxGenerateDepth = 2
+
+// ignoredGenerateNames are meta-arguments and blocks which say nothing about how a resource is configured
+var ignoredGenerateNames = map[string]bool{
+	"count":      true,
+	"for_each":   true,
+	"depends_on": true,
+	"provider":   true,
+	"lifecycle":  true,
+	"dynamic":    true,
+}
+
+// candidate ranks, where specs about the structure of a block are preferred over those about particular values
+const (
+	rankStructure = iota
+	rankScalar
+	rankString
+	rankCollection
+)
+
+// generateBlock is a resource or data block along with the module it belongs to, which is needed to evaluate match specs
+type generateBlock struct {
+	block  block.Block
+	module block.Module
+}
+
+// candidate is a match spec which every good block satisfies, along with the bad blocks which fail it
+type candidate struct {
+	spec  MatchSpec
+	rank  int
+	fails map[int]bool
+}
+
+// Generate proposes a check which fails the resource and data blocks in the bad terraform and passes those in the good terraform,
+// by comparing the evaluated attributes and nested blocks of the two. Each path can be a terraform file or a directory. Only blocks
+// of the types found in both are compared, and the match spec is made up of as few predicates as distinguish every bad block.
+func Generate(code string, badPath string, goodPath string) (*Check, error) {
+	bad, err := generateBlocks(badPath)
+	if err != nil {
+		return nil, err
+	}
+	good, err := generateBlocks(goodPath)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredTypes, requiredLabels := commonLabels(bad, good)
+	if len(requiredLabels) == 0 {
+		return nil, fmt.Errorf("none of the resource or data blocks in %s are of the same type as those in %s", badPath, goodPath)
+	}
+	bad = filterGenerateBlocks(bad, requiredTypes, requiredLabels)
+	good = filterGenerateBlocks(good, requiredTypes, requiredLabels)
+
+	spec, err := distinguish(bad, good)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &Check{
+		Code:           code,
+		Description:    fmt.Sprintf("Generated from the differences between %s and %s", badPath, goodPath),
+		RequiredTypes:  requiredTypes,
+		RequiredLabels: requiredLabels,
+		Severity:       severity.Medium,
+		ErrorMessage:   "{{.Resource}} failed {{.Spec.Action}}{{with .Spec.Name}} {{.}}{{end}}{{with .Spec.MatchValue}} {{.}}{{end}}",
+		MatchSpec:      spec,
+	}
+	// single files are kept as examples, so that the check can be tested against them
+	if example, ok := readExample(badPath); ok {
+		check.BadExamples = []string{example}
+	}
+	if example, ok := readExample(goodPath); ok {
+		check.GoodExamples = []string{example}
+	}
+	return check, nil
+}
+
+func readExample(path string) (string, bool) {
+	if !strings.HasSuffix(path, ".tf") {
+		return "", false
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// generateBlocks returns the resource and data blocks of the terraform file or directory at the path
+func generateBlocks(path string) ([]generateBlock, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var modules []block.Module
+	if info.IsDir() {
+		modules, err = parseDirectory(path)
+	} else {
+		modules, err = parseFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []generateBlock
+	for _, module := range modules {
+		for _, b := range module.GetBlocks() {
+			if b.Type() == "resource" || b.Type() == "data" {
+				blocks = append(blocks, generateBlock{block: b, module: module})
+			}
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no resource or data blocks were found in %s", path)
+	}
+	return blocks, nil
+}
+
+// commonLabels returns the block types and type labels of the bad blocks which are also found among the good blocks
+func commonLabels(bad []generateBlock, good []generateBlock) ([]string, []string) {
+	goodKeys := make(map[string]bool)
+	for _, g := range good {
+		goodKeys[g.block.Type()+"."+g.block.TypeLabel()] = true
+	}
+	types := make(map[string]bool)
+	labels := make(map[string]bool)
+	for _, b := range bad {
+		if goodKeys[b.block.Type()+"."+b.block.TypeLabel()] {
+			types[b.block.Type()] = true
+			labels[b.block.TypeLabel()] = true
+		}
+	}
+	return sortedKeys(types), sortedKeys(labels)
+}
+
+func filterGenerateBlocks(blocks []generateBlock, requiredTypes []string, requiredLabels []string) []generateBlock {
+	var filtered []generateBlock
+	for _, b := range blocks {
+		if contains(requiredTypes, b.block.Type()) && contains(requiredLabels, b.block.TypeLabel()) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
+
+// distinguish chooses the candidates which fail the most bad blocks until every bad block fails, combining them with and
+func distinguish(bad []generateBlock, good []generateBlock) (*MatchSpec, error) {
+	candidates := evaluateCandidates(candidateSpecs(blocksOf(good), blocksOf(bad), 0), bad, good)
+
+	uncovered := make(map[int]bool)
+	for i := range bad {
+		uncovered[i] = true
+	}
+	var chosen []MatchSpec
+	for len(uncovered) > 0 {
+		best, bestCount := -1, 0
+		for i, c := range candidates {
+			var count int
+			for index := range c.fails {
+				if uncovered[index] {
+					count++
+				}
+			}
+			if count > bestCount || (count == bestCount && count > 0 && c.rank < candidates[best].rank) {
+				best, bestCount = i, count
+			}
+		}
+		if best < 0 {
+			break
+		}
+		chosen = append(chosen, candidates[best].spec)
+		for index := range candidates[best].fails {
+			delete(uncovered, index)
+		}
+	}
+
+	if len(uncovered) > 0 {
+		var names []string
+		for index := range uncovered {
+			names = append(names, bad[index].block.FullName())
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("no attributes were found which distinguish %s from the good blocks", strings.Join(names, ", "))
+	}
+	if len(chosen) == 1 {
+		return &chosen[0], nil
+	}
+	return &MatchSpec{Action: And, PredicateMatchSpec: chosen}, nil
+}
+
+// evaluateCandidates returns the candidates which every good block satisfies and at least one bad block fails
+func evaluateCandidates(specs []candidate, bad []generateBlock, good []generateBlock) []candidate {
+	var m matcher
+	var candidates []candidate
+	for _, c := range specs {
+		passesGood := true
+		for _, g := range good {
+			if !m.evalMatchSpec(g.block, &c.spec, g.module) {
+				passesGood = false
+				break
+			}
+		}
+		if !passesGood {
+			continue
+		}
+		c.fails = make(map[int]bool)
+		for i, b := range bad {
+			if !m.evalMatchSpec(b.block, &c.spec, b.module) {
+				c.fails[i] = true
+			}
+		}
+		if len(c.fails) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// candidateSpecs returns the specs which might distinguish the blocks, based on the attribute values and nested blocks of the good
+// blocks, and on the names of the attributes and nested blocks of the bad blocks
+func candidateSpecs(good []block.Block, bad []block.Block, depth int) []candidate {
+	var candidates []candidate
+
+	attributeNames, blockNames := childNames(good)
+	for _, name := range attributeNames {
+		candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: IsPresent}, rank: rankStructure})
+		candidates = append(candidates, valueCandidates(name, good)...)
+	}
+	badAttributeNames, badBlockNames := childNames(bad)
+	for _, name := range append(badAttributeNames, badBlockNames...) {
+		candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: NotPresent}, rank: rankStructure})
+	}
+	for _, name := range blockNames {
+		candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: IsPresent}, rank: rankStructure})
+		if depth+1 >= maxGenerateDepth {
+			continue
+		}
+		for _, sub := range candidateSpecs(childBlocks(good, name), childBlocks(bad, name), depth+1) {
+			sub := sub
+			candidates = append(candidates, candidate{
+				spec: MatchSpec{Name: name, Action: IsPresent, SubMatch: &sub.spec},
+				rank: sub.rank,
+			})
+		}
+	}
+	return candidates
+}
+
+// valueCandidates returns the specs which compare the named attribute with its values in the good blocks
+func valueCandidates(name string, good []block.Block) []candidate {
+	var values []interface{}
+	for _, b := range good {
+		blockValues, err := ctyToGo(b.Values())
+		if err != nil {
+			continue
+		}
+		if blockValues, ok := blockValues.(map[string]interface{}); ok && blockValues[name] != nil {
+			values = append(values, blockValues[name])
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	var candidates []candidate
+	switch value := values[0].(type) {
+	case bool, float64:
+		candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: Equals, MatchValue: value}, rank: rankScalar})
+	case string:
+		candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: Equals, MatchValue: value}, rank: rankString})
+		// a value unique to each good block is more likely to be a name than a setting, so is not worth listing
+		if distinct := distinctStrings(values); len(distinct) > 1 && len(distinct) < len(good) {
+			candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: IsAny, MatchValue: distinct}, rank: rankString})
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: Contains, MatchValue: key}, rank: rankCollection})
+		}
+	case []interface{}:
+		for _, element := range value {
+			switch element.(type) {
+			case string, float64:
+				candidates = append(candidates, candidate{spec: MatchSpec{Name: name, Action: Contains, MatchValue: element}, rank: rankCollection})
+			}
+		}
+	}
+	return candidates
+}
+
+// childNames returns the sorted names of the attributes and of the nested blocks of the blocks
+func childNames(blocks []block.Block) ([]string, []string) {
+	attributes := make(map[string]bool)
+	nested := make(map[string]bool)
+	for _, b := range blocks {
+		for _, attribute := range b.GetAttributes() {
+			if !ignoredGenerateNames[attribute.Name()] {
+				attributes[attribute.Name()] = true
+			}
+		}
+		for _, child := range b.AllBlocks() {
+			if !ignoredGenerateNames[child.Type()] {
+				nested[child.Type()] = true
+			}
+		}
+	}
+	return sortedKeys(attributes), sortedKeys(nested)
+}
+
+func childBlocks(blocks []block.Block, name string) []block.Block {
+	var children []block.Block
+	for _, b := range blocks {
+		children = append(children, b.GetBlocks(name)...)
+	}
+	return children
+}
+
+func blocksOf(blocks []generateBlock) []block.Block {
+	var result []block.Block
+	for _, b := range blocks {
+		result = append(result, b.block)
+	}
+	return result
+}
+
+func distinctStrings(values []interface{}) []interface{} {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		seen[s] = true
+	}
+	var distinct []interface{}
+	for _, s := range sortedKeys(seen) {
+		distinct = append(distinct, s)
+	}
+	return distinct
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]bool:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]interface{}:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package custom
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeCheckFile(t, dir, "bad.tf", `
+resource "aws_s3_bucket" "bad" {
+  bucket = "bad-bucket"
+  versioning {
+    enabled = false
+  }
+}
+
+resource "aws_instance" "unrelated" {
+  ami = "ami-123"
+}
+`)
+	good := writeCheckFile(t, dir, "good.tf", `
+resource "aws_s3_bucket" "good" {
+  bucket = "good-bucket"
+  versioning {
+    enabled = true
+  }
+}
+`)
+
+	check, err := Generate("CUS401", bad, good)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"resource"}, check.RequiredTypes)
+	assert.Equal(t, []string{"aws_s3_bucket"}, check.RequiredLabels)
+	assert.Equal(t, &MatchSpec{
+		Name:     "versioning",
+		Action:   IsPresent,
+		SubMatch: &MatchSpec{Name: "enabled", Action: Equals, MatchValue: true},
+	}, check.MatchSpec)
+	assert.Empty(t, validate(check))
+
+	assert.Len(t, scanWithCheck(t, check, check.BadExamples[0]), 1)
+	assert.Len(t, scanWithCheck(t, check, check.GoodExamples[0]), 0)
+}
+
+func TestGenerateCombinesPredicates(t *testing.T) {
+	dir := t.TempDir()
+	writeCheckFile(t, dir, "bad/public.tf", `
+resource "aws_instance" "public" {
+  associate_public_ip_address = true
+  tags = {
+    Owner = "team"
+  }
+}
+`)
+	writeCheckFile(t, dir, "bad/untagged.tf", `
+resource "aws_instance" "untagged" {
+  associate_public_ip_address = false
+}
+`)
+	writeCheckFile(t, dir, "good/main.tf", `
+resource "aws_instance" "first" {
+  associate_public_ip_address = false
+  tags = {
+    Owner = "team"
+  }
+}
+
+resource "aws_instance" "second" {
+  associate_public_ip_address = false
+  tags = {
+    Owner = "platform"
+  }
+}
+`)
+
+	check, err := Generate("CUS402", filepath.Join(dir, "bad"), filepath.Join(dir, "good"))
+	require.NoError(t, err)
+
+	require.Equal(t, And, check.MatchSpec.Action)
+	assert.Equal(t, []MatchSpec{
+		{Name: "tags", Action: IsPresent},
+		{Name: "associate_public_ip_address", Action: Equals, MatchValue: false},
+	}, check.MatchSpec.PredicateMatchSpec)
+	assert.Empty(t, check.BadExamples)
+	assert.Empty(t, validate(check))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := t.TempDir()
+	bucket := writeCheckFile(t, dir, "bucket.tf", `
+resource "aws_s3_bucket" "bucket" {
+  acl = "private"
+}
+`)
+	sameBucket := writeCheckFile(t, dir, "same/bucket.tf", `
+resource "aws_s3_bucket" "same" {
+  acl = "private"
+}
+`)
+	instance := writeCheckFile(t, dir, "instance.tf", `
+resource "aws_instance" "instance" {
+}
+`)
+
+	_, err := Generate("CUS403", bucket, sameBucket)
+	assert.EqualError(t, err, "no attributes were found which distinguish aws_s3_bucket.bucket from the good blocks")
+
+	_, err = Generate("CUS403", bucket, instance)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "none of the resource or data blocks")
+
+	_, err = Generate("CUS403", filepath.Join(dir, "missing.tf"), instance)
+	assert.Error(t, err)
+}
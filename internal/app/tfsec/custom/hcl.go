@@ -0,0 +1,341 @@
+package custom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var hclFileSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "imports"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "check", LabelNames: []string{"code"}},
+		{Type: "fragment", LabelNames: []string{"name"}},
+	},
+}
+
+var hclCheckSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "description"},
+		{Name: "impact"},
+		{Name: "resolution"},
+		{Name: "required_types"},
+		{Name: "required_labels"},
+		{Name: "required_sources"},
+		{Name: "severity"},
+		{Name: "error_message"},
+		{Name: "related_links"},
+		{Name: "target"},
+		{Name: "provider"},
+		{Name: "service"},
+		{Name: "explanation"},
+		{Name: "good_examples"},
+		{Name: "bad_examples"},
+		{Name: "compliance_tags"},
+		{Name: "params"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "match"},
+	},
+}
+
+var hclMatchSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "name"},
+		{Name: "action"},
+		{Name: "value"},
+		{Name: "ignore_undefined"},
+		{Name: "ignore_unmatched"},
+		{Name: "min"},
+		{Name: "max"},
+		{Name: "ref"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "match"},
+		{Type: "sub_match"},
+		{Type: "precondition"},
+	},
+}
+
+// loadHCLChecks reads checks from HCL, where each check block maps onto a Check and each match block onto a MatchSpec:
+//
+//	check "CUS001" {
+//	  description     = "Custom check to ensure the CostCentre tag is applied to EC2 instances"
+//	  required_types  = ["resource"]
+//	  required_labels = ["aws_instance"]
+//	  severity        = "ERROR"
+//
+//	  match {
+//	    name   = "tags"
+//	    action = "contains"
+//	    value  = "CostCentre"
+//	  }
+//	}
+//
+// Within a match block, nested match blocks are its predicates, and sub_match and precondition blocks are its sub match and preconditions.
+// Fragment blocks have the same content as match blocks, and are referred to by name with the ref attribute of a match block.
+func loadHCLChecks(filename string, content []byte) (ChecksFile, error) {
+	var checks ChecksFile
+
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return checks, diagnosticsError(diags)
+	}
+
+	fileContent, diags := file.Body.Content(hclFileSchema)
+	if diags.HasErrors() {
+		return checks, diagnosticsError(diags)
+	}
+
+	d := hclDecoder{attributes: fileContent.Attributes}
+	d.strings("imports", &checks.Imports)
+	diags = append(diags, d.diags...)
+
+	for _, block := range fileContent.Blocks {
+		if block.Type == "fragment" {
+			spec, specDiags := decodeHCLMatch(block)
+			diags = append(diags, specDiags...)
+			if spec != nil {
+				if checks.Fragments == nil {
+					checks.Fragments = make(map[string]*MatchSpec)
+				}
+				checks.Fragments[block.Labels[0]] = spec
+			}
+			continue
+		}
+		check, checkDiags := decodeHCLCheck(block)
+		diags = append(diags, checkDiags...)
+		if check != nil {
+			checks.Checks = append(checks.Checks, check)
+		}
+	}
+	if diags.HasErrors() {
+		return checks, diagnosticsError(diags)
+	}
+	return checks, nil
+}
+
+func decodeHCLCheck(block *hcl.Block) (*Check, hcl.Diagnostics) {
+	content, diags := block.Body.Content(hclCheckSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	check := &Check{
+		Code:   block.Labels[0],
+		source: block.DefRange.String(),
+	}
+	d := hclDecoder{attributes: content.Attributes}
+	d.string("description", &check.Description)
+	d.string("impact", &check.Impact)
+	d.string("resolution", &check.Resolution)
+	d.string("error_message", &check.ErrorMessage)
+	d.string("target", &check.Target)
+	d.string("provider", &check.Provider)
+	d.string("service", &check.Service)
+	d.string("explanation", &check.Explanation)
+	d.strings("good_examples", &check.GoodExamples)
+	d.strings("bad_examples", &check.BadExamples)
+	d.strings("compliance_tags", &check.ComplianceTags)
+	d.strings("required_types", &check.RequiredTypes)
+	d.strings("required_labels", &check.RequiredLabels)
+	d.strings("required_sources", &check.RequiredSources)
+	d.strings("related_links", &check.RelatedLinks)
+	d.object("params", &check.Params)
+	var sev string
+	d.string("severity", &sev)
+	check.Severity = severity.Severity(sev)
+	diags = append(diags, d.diags...)
+
+	switch len(content.Blocks) {
+	case 0:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing match block",
+			Detail:   fmt.Sprintf("Check %s must have a match block.", check.Code),
+			Subject:  &block.DefRange,
+		})
+	case 1:
+		spec, specDiags := decodeHCLMatch(content.Blocks[0])
+		diags = append(diags, specDiags...)
+		check.MatchSpec = spec
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Duplicate match block",
+			Detail:   fmt.Sprintf("Check %s must have a single match block, combining conditions with the and, or and not actions.", check.Code),
+			Subject:  &content.Blocks[1].DefRange,
+		})
+	}
+
+	return check, diags
+}
+
+func decodeHCLMatch(block *hcl.Block) (*MatchSpec, hcl.Diagnostics) {
+	content, diags := block.Body.Content(hclMatchSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	spec := &MatchSpec{
+		source: block.DefRange.String(),
+	}
+	d := hclDecoder{attributes: content.Attributes}
+	d.string("name", &spec.Name)
+	d.string("ref", &spec.Ref)
+	var action string
+	d.string("action", &action)
+	spec.Action = CheckAction(action)
+	d.bool("ignore_undefined", &spec.IgnoreUndefined)
+	d.bool("ignore_unmatched", &spec.IgnoreUnmatched)
+	d.int("min", &spec.Min)
+	d.int("max", &spec.Max)
+	d.value("value", &spec.MatchValue)
+	diags = append(diags, d.diags...)
+
+	for _, child := range content.Blocks {
+		childSpec, childDiags := decodeHCLMatch(child)
+		diags = append(diags, childDiags...)
+		if childSpec == nil {
+			continue
+		}
+		switch child.Type {
+		case "match":
+			spec.PredicateMatchSpec = append(spec.PredicateMatchSpec, *childSpec)
+		case "precondition":
+			spec.PreConditions = append(spec.PreConditions, *childSpec)
+		case "sub_match":
+			if spec.SubMatch != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate sub_match block",
+					Detail:   "A match block can only have a single sub_match block.",
+					Subject:  &child.DefRange,
+				})
+				continue
+			}
+			spec.SubMatch = childSpec
+		}
+	}
+
+	return spec, diags
+}
+
+// diagnosticsError lists every diagnostic, where hcl.Diagnostics only describes the first
+func diagnosticsError(diags hcl.Diagnostics) error {
+	var messages []string
+	for _, diag := range diags {
+		messages = append(messages, diag.Error())
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
+
+// hclDecoder converts literal attribute values, collecting any problems as diagnostics
+type hclDecoder struct {
+	attributes hcl.Attributes
+	diags      hcl.Diagnostics
+}
+
+func (d *hclDecoder) evaluate(name string, ty cty.Type) (cty.Value, bool) {
+	attr, ok := d.attributes[name]
+	if !ok {
+		return cty.NilVal, false
+	}
+	val, diags := attr.Expr.Value(nil)
+	d.diags = append(d.diags, diags...)
+	if diags.HasErrors() {
+		return cty.NilVal, false
+	}
+	if ty == cty.DynamicPseudoType {
+		return val, true
+	}
+	converted, err := convert.Convert(val, ty)
+	if err != nil || converted.IsNull() {
+		d.diags = append(d.diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value",
+			Detail:   fmt.Sprintf("%s must be a %s.", name, ty.FriendlyName()),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+		return cty.NilVal, false
+	}
+	return converted, true
+}
+
+func (d *hclDecoder) string(name string, target *string) {
+	if val, ok := d.evaluate(name, cty.String); ok {
+		*target = val.AsString()
+	}
+}
+
+func (d *hclDecoder) strings(name string, target *[]string) {
+	if val, ok := d.evaluate(name, cty.List(cty.String)); ok {
+		for _, item := range val.AsValueSlice() {
+			*target = append(*target, item.AsString())
+		}
+	}
+}
+
+func (d *hclDecoder) bool(name string, target *bool) {
+	if val, ok := d.evaluate(name, cty.Bool); ok {
+		*target = val.True()
+	}
+}
+
+func (d *hclDecoder) int(name string, target **int) {
+	if val, ok := d.evaluate(name, cty.Number); ok {
+		i, _ := val.AsBigFloat().Int64()
+		n := int(i)
+		*target = &n
+	}
+}
+
+// object converts an object, with each of its values converted as by value
+func (d *hclDecoder) object(name string, target *map[string]interface{}) {
+	var value interface{}
+	d.value(name, &value)
+	if value == nil {
+		return
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		d.diags = append(d.diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value",
+			Detail:   fmt.Sprintf("%s must be an object.", name),
+			Subject:  d.attributes[name].Expr.Range().Ptr(),
+		})
+		return
+	}
+	*target = object
+}
+
+// value converts any value as it would be decoded from a JSON check file, so that checks behave the same in every format
+func (d *hclDecoder) value(name string, target *interface{}) {
+	val, ok := d.evaluate(name, cty.DynamicPseudoType)
+	if !ok {
+		return
+	}
+	data, err := ctyjson.SimpleJSONValue{Value: val}.MarshalJSON()
+	if err == nil {
+		err = json.Unmarshal(data, target)
+	}
+	if err != nil {
+		d.diags = append(d.diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value",
+			Detail:   fmt.Sprintf("%s could not be converted: %s", name, err),
+			Subject:  d.attributes[name].Expr.Range().Ptr(),
+		})
+	}
+}
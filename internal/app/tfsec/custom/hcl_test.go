@@ -0,0 +1,183 @@
+package custom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hclChecks = `
+check "CUS001" {
+  description     = "Custom check to ensure the CostCentre tag is applied to EC2 instances"
+  impact          = "By not having CostCentre we can't keep track of billing"
+  resolution      = "Add the CostCentre tag"
+  required_types  = ["resource"]
+  required_labels = ["aws_instance"]
+  severity        = "ERROR"
+  error_message   = "The required CostCentre tag was missing"
+  related_links   = ["http://internal.acmecorp.com/standards/aws/tagging.html"]
+
+  match {
+    action = "and"
+
+    match {
+      name   = "tags"
+      action = "contains"
+      value  = "CostCentre"
+    }
+    match {
+      name   = "cpu_core_count"
+      action = "lessThan"
+      value  = 8
+    }
+
+    precondition {
+      name   = "instance_type"
+      action = "isAny"
+      value  = ["t2.micro", "t2.small"]
+    }
+  }
+}
+`
+
+const jsonChecks = `{
+  "checks": [
+    {
+      "code": "CUS001",
+      "description": "Custom check to ensure the CostCentre tag is applied to EC2 instances",
+      "impact": "By not having CostCentre we can't keep track of billing",
+      "resolution": "Add the CostCentre tag",
+      "requiredTypes": ["resource"],
+      "requiredLabels": ["aws_instance"],
+      "severity": "ERROR",
+      "errorMessage": "The required CostCentre tag was missing",
+      "relatedLinks": ["http://internal.acmecorp.com/standards/aws/tagging.html"],
+      "matchSpec": {
+        "action": "and",
+        "predicateMatchSpec": [
+          {"name": "tags", "action": "contains", "value": "CostCentre"},
+          {"name": "cpu_core_count", "action": "lessThan", "value": 8}
+        ],
+        "preconditions": [
+          {"name": "instance_type", "action": "isAny", "value": ["t2.micro", "t2.small"]}
+        ]
+      }
+    }
+  ]
+}`
+
+func TestHCLChecksMatchJSONChecks(t *testing.T) {
+	dir := t.TempDir()
+	hclPath := writeCheckFile(t, dir, "custom_tfchecks.hcl", hclChecks)
+	jsonPath := writeCheckFile(t, dir, "custom_tfchecks.json", jsonChecks)
+
+	require.NoError(t, Validate(hclPath))
+
+	fromHCL, err := loadCheckFile(hclPath)
+	require.NoError(t, err)
+	fromJSON, err := loadCheckFile(jsonPath)
+	require.NoError(t, err)
+
+	hclContent, err := json.Marshal(fromHCL)
+	require.NoError(t, err)
+	jsonContent, err := json.Marshal(fromJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(jsonContent), string(hclContent))
+}
+
+func TestHCLChecksAreRun(t *testing.T) {
+	checkDir := t.TempDir()
+	writeCheckFile(t, checkDir, "custom_tfchecks.hcl", hclChecks)
+	loaded, err := Load(checkDir)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+
+	dir := t.TempDir()
+	writeCheckFile(t, dir, "main.tf", `
+resource "aws_instance" "tagged" {
+  instance_type  = "t2.micro"
+  cpu_core_count = 2
+  tags = {
+    CostCentre = "CC1234"
+  }
+}
+
+resource "aws_instance" "untagged" {
+  instance_type  = "t2.small"
+  cpu_core_count = 2
+}
+
+resource "aws_instance" "large" {
+  instance_type  = "m5.large"
+  cpu_core_count = 2
+}
+`)
+	modules, err := parser.New(dir, parser.OptionStopOnHCLError()).ParseDirectory()
+	require.NoError(t, err)
+	results, err := scanner.New(scanner.OptionWithRules(loaded)).Scan(modules)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].Description(), "The required CostCentre tag was missing")
+	assert.Equal(t, 10, results[0].NarrowestRange().GetStartLine())
+}
+
+func TestHCLCheckErrorsHaveRanges(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeCheckFile(t, dir, "invalid_tfchecks.hcl", `
+check "CUS002" {
+  description     = "Buckets must be private"
+  required_types  = ["resource"]
+  required_labels = ["aws_s3_bucket"]
+  severity        = "HIGH"
+
+  match {
+    name   = "acl"
+    action = "isPrivate"
+  }
+}
+`)
+	err := Validate(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "check CUS002 at "+path+":2,1-15")
+	assert.Contains(t, err.Error(), path+":8,3-8: matchSpec.Action[isPrivate] is not a recognised option")
+
+	path = writeCheckFile(t, dir, "unknown_tfchecks.hcl", `
+check "CUS003" {
+  description = "Buckets must be private"
+  requiredTypes = ["resource"]
+}
+`)
+	err = Validate(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":4,3-16: Unsupported argument")
+
+	path = writeCheckFile(t, dir, "types_tfchecks.hcl", `
+check "CUS004" {
+  required_labels = "aws_s3_bucket"
+  match {
+    name   = "acl"
+    action = "isPresent"
+    ignore_undefined = "sometimes"
+  }
+}
+`)
+	err = Validate(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":3,21-36: Invalid value; required_labels must be a list of string.")
+	assert.Contains(t, err.Error(), path+":7,24-35: Invalid value; ignore_undefined must be a bool.")
+}
+
+func writeCheckFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+	return path
+}
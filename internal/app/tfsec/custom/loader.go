@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -11,29 +12,63 @@ import (
 	"strings"
 
 	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/pkg/rule"
 	"gopkg.in/yaml.v2"
 )
 
 type ChecksFile struct {
 	Checks []*Check `json:"checks" yaml:"checks"`
+	// Fragments are named match specs which any check in the file can refer to with $ref
+	Fragments map[string]*MatchSpec `json:"fragments,omitempty" yaml:"fragments,omitempty"`
+	// Imports lists other check files whose fragments can be referred to, relative to this file if not absolute
+	Imports []string `json:"imports,omitempty" yaml:"imports,omitempty"`
 }
 
-func Load(customCheckDir string) error {
+// Option configures how custom checks are loaded
+type Option func(o *loadOptions)
+
+type loadOptions struct {
+	params map[string]map[string]interface{}
+	debug  debug.Logger
+}
+
+// OptionWithParams overrides the parameters of checks, keyed by check code and then by parameter name
+func OptionWithParams(params map[string]map[string]interface{}) Option {
+	return func(o *loadOptions) {
+		o.params = params
+	}
+}
+
+// OptionWithDebugWriter enables debug logging for the loaded checks only, both while loading and while they are run by a scanner
+func OptionWithDebugWriter(writer io.Writer) Option {
+	return func(o *loadOptions) {
+		o.debug = debug.New(writer)
+	}
+}
+
+// Load reads all custom checks from the given directory and returns them as rules, which can be passed to a scanner
+func Load(customCheckDir string, options ...Option) ([]rule.Rule, error) {
 	_, err := os.Stat(customCheckDir)
 	if os.IsNotExist(err) {
-		return nil
+		return nil, nil
 	} else if err != nil {
-		return err
+		return nil, err
 	}
 
-	return loadCustomChecks(customCheckDir)
+	var o loadOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return loadCustomChecks(customCheckDir, o)
 }
 
-func loadCustomChecks(customCheckDir string) error {
+func loadCustomChecks(customCheckDir string, o loadOptions) ([]rule.Rule, error) {
 	checkFiles, err := listFiles(customCheckDir, ".*_tfchecks.*")
 	if err != nil {
-		return err
+		return nil, err
 	}
+	var loaded []rule.Rule
 	var errorList []string
 	for _, checkFilePath := range checkFiles {
 		err = Validate(checkFilePath)
@@ -46,17 +81,35 @@ func loadCustomChecks(customCheckDir string) error {
 			errorList = append(errorList, err.Error())
 			continue
 		}
+		overrideParams(checks, o.params)
 
-		processFoundChecks(checks)
+		checkRules, err := processFoundChecks(checks, o.debug)
+		if err != nil {
+			errorList = append(errorList, err.Error())
+			continue
+		}
+		loaded = append(loaded, checkRules...)
 	}
 
 	if len(errorList) > 0 {
-		return errors.New(strings.Join(errorList, "\n"))
+		return nil, errors.New(strings.Join(errorList, "\n"))
 	}
-	return nil
+	return loaded, nil
 }
 
+// loadCheckFile reads the checks in the file, giving each of them the fragments it can refer to
 func loadCheckFile(checkFilePath string) (ChecksFile, error) {
+	checks, err := parseCheckFile(checkFilePath)
+	if err != nil {
+		return checks, err
+	}
+	if err := linkFragments(checkFilePath, &checks); err != nil {
+		return checks, err
+	}
+	return checks, nil
+}
+
+func parseCheckFile(checkFilePath string) (ChecksFile, error) {
 	var checks ChecksFile
 	checkFileContent, err := ioutil.ReadFile(checkFilePath)
 	if err != nil {
@@ -69,6 +122,11 @@ func loadCheckFile(checkFilePath string) (ChecksFile, error) {
 		if err != nil {
 			return checks, err
 		}
+	case ".hcl":
+		checks, err = loadHCLChecks(checkFilePath, checkFileContent)
+		if err != nil {
+			return checks, err
+		}
 	case ".yml", ".yaml":
 		err = yaml.Unmarshal(checkFileContent, &checks)
 		if err != nil {
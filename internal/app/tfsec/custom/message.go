@@ -0,0 +1,152 @@
+package custom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/aquasecurity/defsec/provider"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// messageData is available to error messages written as Go templates
+type messageData struct {
+	// Resource is the name of the failing resource, such as aws_s3_bucket.example
+	Resource string
+	// Values holds the attribute values of the failing block, which are not available to checks with a target
+	Values map[string]interface{}
+	// Spec is the match spec which failed
+	Spec *MatchSpec
+}
+
+// baseRule describes the check as a defsec rule, which is in the custom provider and service unless the check names others
+func (check *Check) baseRule() rules.Rule {
+	base := rules.Rule{
+		Service:     "custom",
+		ShortCode:   check.Code,
+		Summary:     check.Description,
+		Explanation: check.Explanation,
+		Impact:      check.Impact,
+		Resolution:  check.Resolution,
+		Provider:    provider.CustomProvider,
+		Links:       check.RelatedLinks,
+		Severity:    check.Severity,
+	}
+	if check.Provider != "" {
+		base.Provider = provider.Provider(check.Provider)
+	}
+	if check.Service != "" {
+		base.Service = check.Service
+	}
+	if len(check.GoodExamples) > 0 || len(check.BadExamples) > 0 {
+		base.Terraform = &rules.EngineMetadata{
+			GoodExamples: check.GoodExamples,
+			BadExamples:  check.BadExamples,
+			Links:        check.RelatedLinks,
+		}
+	}
+	return base
+}
+
+func (check *Check) isTemplated() bool {
+	return strings.Contains(check.ErrorMessage, "{{")
+}
+
+func (check *Check) parseMessage() (*template.Template, error) {
+	return template.New(check.Code).Option("missingkey=zero").Parse(check.ErrorMessage)
+}
+
+// message describes a failure of the check. If the error message is a template, the rendered template is the whole message.
+func (check *Check) message(data messageData) string {
+	if !check.isTemplated() {
+		return fmt.Sprintf("Custom check failed for resource %s. %s", data.Resource, check.ErrorMessage)
+	}
+	tmpl, err := check.parseMessage()
+	if err == nil {
+		buffer := bytes.NewBuffer(nil)
+		if err = tmpl.Execute(buffer, data); err == nil {
+			return buffer.String()
+		}
+	}
+	check.debug.Log("Failed to render the error message of %s: %s", check.Code, err)
+	return fmt.Sprintf("Custom check failed for resource %s. %s", data.Resource, check.ErrorMessage)
+}
+
+// blockMessageData returns the data for the message of a block which failed the spec
+func (m matcher) blockMessageData(b block.Block, spec *MatchSpec, module block.Module) messageData {
+	data := messageData{
+		Resource: b.FullName(),
+		Spec:     m.failingSpec(b, spec, module),
+	}
+	if values, err := ctyToGo(b.Values()); err == nil {
+		data.Values, _ = values.(map[string]interface{})
+	} else {
+		m.debug.Log("Failed to convert the values of %s: %s", b.FullName(), err)
+	}
+	return data
+}
+
+// failingSpec returns the spec which caused the block to fail, descending into `and` predicates and the sub matches of nested blocks
+func (m matcher) failingSpec(b block.Block, spec *MatchSpec, module block.Module) *MatchSpec {
+	switch spec.Action {
+	case And:
+		for i := range spec.PredicateMatchSpec {
+			if predicate := &spec.PredicateMatchSpec[i]; !m.evalMatchSpec(b, predicate, module) {
+				return m.failingSpec(b, predicate, module)
+			}
+		}
+		return spec
+	case InModule, HasTag, OfType, RequiresPresence, Not, Or, IsReferencedBy, References, Any, All, None, Count:
+		return spec
+	}
+	if spec.SubMatch != nil {
+		for _, child := range b.GetBlocks(spec.Name) {
+			if !m.evalMatchSpec(child, spec.SubMatch, module) {
+				return m.failingSpec(child, spec.SubMatch, module)
+			}
+		}
+	}
+	return spec
+}
+
+// failingStateSpec returns the spec which caused the item of the state to fail, as failingSpec does for blocks
+func (m matcher) failingStateSpec(item reflect.Value, spec *MatchSpec) *MatchSpec {
+	switch spec.Action {
+	case And:
+		for i := range spec.PredicateMatchSpec {
+			if predicate := &spec.PredicateMatchSpec[i]; !m.evalStateMatchSpec(item, predicate) {
+				return m.failingStateSpec(item, predicate)
+			}
+		}
+		return spec
+	case Not, Or, Any, All, None, Count:
+		return spec
+	}
+	if field, found := itemField(item, spec.Name); found && spec.SubMatch != nil {
+		for _, element := range flatten(field) {
+			if !m.evalStateMatchSpec(element, spec.SubMatch) {
+				return m.failingStateSpec(element, spec.SubMatch)
+			}
+		}
+	}
+	return spec
+}
+
+// ctyToGo converts a value as it would be decoded from JSON, treating unknown values as null
+func ctyToGo(val cty.Value) (interface{}, error) {
+	data, err := ctyjson.SimpleJSONValue{Value: cty.UnknownAsNull(val)}.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var converted interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
@@ -0,0 +1,203 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatedMessages(t *testing.T) {
+	var tests = []struct {
+		name     string
+		check    Check
+		source   string
+		expected string
+	}{
+		{
+			name: "check that the message can use the values of the block and the failing predicate",
+			check: Check{
+				Code:           "CUS201",
+				RequiredTypes:  []string{"resource"},
+				RequiredLabels: []string{"aws_autoscaling_group"},
+				ErrorMessage:   "{{.Resource}} has {{.Values.max_size}} instances at most, so {{.Spec.Name}} must be {{.Spec.Action}} {{.Spec.MatchValue}}",
+				MatchSpec: &MatchSpec{
+					Action: And,
+					PredicateMatchSpec: []MatchSpec{
+						{Name: "min_size", Action: IsPresent},
+						{Name: "max_size", Action: GreaterThanOrEqualTo, MatchValue: 2},
+					},
+				},
+			},
+			source: `
+resource "aws_autoscaling_group" "example" {
+	min_size = 1
+	max_size = 1
+}
+`,
+			expected: "aws_autoscaling_group.example has 1 instances at most, so max_size must be greaterThanOrEqualTo 2",
+		},
+		{
+			name: "check that the failing spec of a nested block is found",
+			check: Check{
+				Code:           "CUS202",
+				RequiredTypes:  []string{"resource"},
+				RequiredLabels: []string{"aws_instance"},
+				ErrorMessage:   "{{.Spec.Name}} must be {{.Spec.MatchValue}}",
+				MatchSpec: &MatchSpec{
+					Name:     "ebs_block_device",
+					Action:   IsPresent,
+					SubMatch: &MatchSpec{Name: "encrypted", Action: Equals, MatchValue: true},
+				},
+			},
+			source: `
+resource "aws_instance" "example" {
+	ebs_block_device {
+		encrypted = false
+	}
+}
+`,
+			expected: "encrypted must be true",
+		},
+		{
+			name: "check that messages which are not templates are unchanged",
+			check: Check{
+				Code:           "CUS203",
+				RequiredTypes:  []string{"resource"},
+				RequiredLabels: []string{"aws_instance"},
+				ErrorMessage:   "The instance must be encrypted",
+				MatchSpec:      &MatchSpec{Name: "ebs_block_device", Action: IsPresent},
+			},
+			source: `
+resource "aws_instance" "example" {
+}
+`,
+			expected: "Custom check failed for resource aws_instance.example. The instance must be encrypted",
+		},
+		{
+			name: "check that checks with a target can use templated messages",
+			check: Check{
+				Code:         "CUS204",
+				Target:       "aws.s3.buckets",
+				ErrorMessage: "{{.Resource}} must have {{.Spec.Name}} set to {{.Spec.MatchValue}}",
+				MatchSpec:    &MatchSpec{Name: "versioning.enabled", Action: Equals, MatchValue: true},
+			},
+			source: `
+resource "aws_s3_bucket" "example" {
+}
+`,
+			expected: "aws_s3_bucket.example must have versioning.enabled set to true",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := scanWithCheck(t, &test.check, test.source)
+			require.Len(t, results, 1)
+			assert.Equal(t, test.expected, results[0].Description())
+		})
+	}
+}
+
+func TestCheckMetadata(t *testing.T) {
+	check := &Check{
+		Code:           "CUS205",
+		Description:    "Buckets must be versioned",
+		Provider:       "acme",
+		Service:        "storage",
+		Explanation:    "Versioning lets us recover deleted objects.",
+		GoodExamples:   []string{`resource "aws_s3_bucket" "good" {}`},
+		BadExamples:    []string{`resource "aws_s3_bucket" "bad" {}`},
+		ComplianceTags: []string{"acme-1.2"},
+		RelatedLinks:   []string{"https://acme.example/standards/storage"},
+		Severity:       "HIGH",
+		Target:         "aws.s3.buckets",
+		MatchSpec:      &MatchSpec{Name: "versioning.enabled", Action: Equals, MatchValue: true},
+	}
+
+	loaded, err := processFoundChecks(ChecksFile{Checks: []*Check{check}}, debug.Logger{})
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "acme-storage-cus205", loaded[0].ID())
+	assert.Equal(t, "CUS205", loaded[0].LegacyID)
+	assert.Equal(t, check.GoodExamples, loaded[0].GoodExample)
+	assert.Equal(t, check.BadExamples, loaded[0].BadExample)
+	assert.Equal(t, check.ComplianceTags, loaded[0].ComplianceTags)
+
+	base := loaded[0].BaseRule()
+	assert.Equal(t, "Versioning lets us recover deleted objects.", base.Explanation)
+	require.NotNil(t, base.Terraform)
+	assert.Equal(t, check.GoodExamples, base.Terraform.GoodExamples)
+	assert.Equal(t, check.BadExamples, base.Terraform.BadExamples)
+}
+
+func TestChecksAreNotRegisteredWithDefsec(t *testing.T) {
+	registered := len(rules.GetRegistered())
+	check := &Check{
+		Code:           "CUS206",
+		Description:    "Buckets must be versioned",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_s3_bucket"},
+		Severity:       "HIGH",
+		MatchSpec:      &MatchSpec{Name: "versioning", Action: IsPresent},
+	}
+	stateCheck := &Check{
+		Code:        "CUS207",
+		Description: "Buckets must be versioned",
+		Severity:    "HIGH",
+		Target:      "aws.s3.buckets",
+		MatchSpec:   &MatchSpec{Name: "versioning.enabled", Action: Equals, MatchValue: true},
+	}
+
+	loaded, err := processFoundChecks(ChecksFile{Checks: []*Check{check, stateCheck}}, debug.Logger{})
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, registered, len(rules.GetRegistered()))
+
+	results, err := scanner.New(scanner.OptionWithCustomRules(loaded)).Scan(ParseFromSource(`
+resource "aws_s3_bucket" "unversioned" {
+}
+`))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, rules.StatusFailed, result.Status())
+		assert.Equal(t, "custom", result.Rule().Service)
+	}
+}
+
+func TestTemplatedMessagesAreValidated(t *testing.T) {
+	check := &Check{
+		Code:           "CUS206",
+		Description:    "Instances must be encrypted",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_instance"},
+		Severity:       "HIGH",
+		ErrorMessage:   "{{.Resource must be encrypted",
+		MatchSpec:      &MatchSpec{Name: "ebs_block_device", Action: IsPresent},
+	}
+	errs := validate(check)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "check.ErrorMessage is not a valid template")
+}
+
+func scanWithCheck(t *testing.T, check *Check, source string) rules.Results {
+	loaded, err := processFoundChecks(ChecksFile{Checks: []*Check{check}}, debug.Logger{})
+	require.NoError(t, err)
+	results, err := scanner.New(
+		scanner.OptionStopOnErrors(),
+		scanner.OptionWithCustomRules(loaded),
+	).Scan(ParseFromSource(source))
+	require.NoError(t, err)
+
+	var custom rules.Results
+	for _, result := range results {
+		if result.Rule().ShortCode == check.Code {
+			custom = append(custom, result)
+		}
+	}
+	return custom
+}
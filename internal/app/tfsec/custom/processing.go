@@ -1,13 +1,14 @@
 package custom
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/aquasecurity/defsec/provider"
 	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/state"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
-	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
 	"github.com/aquasecurity/tfsec/pkg/rule"
 )
 
@@ -28,123 +29,112 @@ var matchFunctions = map[CheckAction]func(block.Block, *MatchSpec) bool{
 		childBlock := block.GetBlock(spec.Name)
 		return childBlock.IsEmpty()
 	},
-	StartsWith: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.StartsWith(spec.MatchValue)
-	},
-	EndsWith: func(block block.Block, spec *MatchSpec) bool {
+	StartsWith:           matchAttribute(StartsWith),
+	EndsWith:             matchAttribute(EndsWith),
+	Contains:             matchAttribute(Contains),
+	NotContains:          matchAttribute(NotContains),
+	Equals:               matchAttribute(Equals),
+	NotEqual:             matchAttribute(NotEqual),
+	LessThan:             matchAttribute(LessThan),
+	LessThanOrEqualTo:    matchAttribute(LessThanOrEqualTo),
+	GreaterThan:          matchAttribute(GreaterThan),
+	GreaterThanOrEqualTo: matchAttribute(GreaterThanOrEqualTo),
+	RegexMatches:         matchAttribute(RegexMatches),
+	IsAny: func(block block.Block, spec *MatchSpec) bool {
 		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.EndsWith(spec.MatchValue)
+		return attribute != nil && attributeMatchFunctions[IsAny](attribute, spec)
 	},
-	Contains: func(b block.Block, spec *MatchSpec) bool {
-		attribute := b.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
+	IsNone: matchAttribute(IsNone),
+}
+
+// attributeMatchFunctions compare the value of an attribute with the value of a spec. They are shared by checks with a target,
+// which compare the fields of the adapted state as attributes.
+var attributeMatchFunctions = map[CheckAction]func(block.Attribute, *MatchSpec) bool{
+	StartsWith: func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.StartsWith(spec.MatchValue) },
+	EndsWith:   func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.EndsWith(spec.MatchValue) },
+	Contains: func(attribute block.Attribute, spec *MatchSpec) bool {
 		return attribute.Contains(spec.MatchValue, block.IgnoreCase)
 	},
-	NotContains: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return !attribute.Contains(spec.MatchValue)
-	},
-	Equals: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.Equals(spec.MatchValue)
-	},
-	NotEqual: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.NotEqual(spec.MatchValue)
-	},
-	LessThan: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.LessThan(spec.MatchValue)
-	},
-	LessThanOrEqualTo: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
+	NotContains: func(attribute block.Attribute, spec *MatchSpec) bool { return !attribute.Contains(spec.MatchValue) },
+	Equals:      func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.Equals(spec.MatchValue) },
+	NotEqual:    func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.NotEqual(spec.MatchValue) },
+	LessThan:    func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.LessThan(spec.MatchValue) },
+	LessThanOrEqualTo: func(attribute block.Attribute, spec *MatchSpec) bool {
 		return attribute.LessThanOrEqualTo(spec.MatchValue)
 	},
-	GreaterThan: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.GreaterThan(spec.MatchValue)
-	},
-	GreaterThanOrEqualTo: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
+	GreaterThan: func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.GreaterThan(spec.MatchValue) },
+	GreaterThanOrEqualTo: func(attribute block.Attribute, spec *MatchSpec) bool {
 		return attribute.GreaterThanOrEqualTo(spec.MatchValue)
 	},
-	RegexMatches: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		if attribute.IsNil() {
-			return spec.IgnoreUndefined
-		}
-		return attribute.RegexMatches(spec.MatchValue)
+	RegexMatches: func(attribute block.Attribute, spec *MatchSpec) bool { return attribute.RegexMatches(spec.MatchValue) },
+	IsAny: func(attribute block.Attribute, spec *MatchSpec) bool {
+		return attribute.IsAny(unpackInterfaceToInterfaceSlice(spec.MatchValue)...)
 	},
-	IsAny: func(block block.Block, spec *MatchSpec) bool {
-		attribute := block.GetAttribute(spec.Name)
-		return attribute != nil && attribute.IsAny(unpackInterfaceToInterfaceSlice(spec.MatchValue)...)
+	IsNone: func(attribute block.Attribute, spec *MatchSpec) bool {
+		return attribute.IsNone(unpackInterfaceToInterfaceSlice(spec.MatchValue)...)
 	},
-	IsNone: func(block block.Block, spec *MatchSpec) bool {
+}
+
+// matchAttribute compares the attribute of the block named by the spec, which passes if undefined and the spec ignores undefined attributes
+func matchAttribute(action CheckAction) func(block.Block, *MatchSpec) bool {
+	return func(block block.Block, spec *MatchSpec) bool {
 		attribute := block.GetAttribute(spec.Name)
 		if attribute.IsNil() {
 			return spec.IgnoreUndefined
 		}
-		return attribute.IsNone(unpackInterfaceToInterfaceSlice(spec.MatchValue)...)
-	},
+		return attributeMatchFunctions[action](attribute, spec)
+	}
 }
 
-func processFoundChecks(checks ChecksFile) {
+// matcher evaluates match specs, logging through the debug logger the checks were loaded with
+type matcher struct {
+	debug debug.Logger
+}
+
+// processFoundChecks converts the checks into rules. The rules are not registered with defsec, so they are only run by scanners
+// they are given to. Checks whose match spec cannot be resolved with their parameters are reported as errors.
+func processFoundChecks(checks ChecksFile, logger debug.Logger) ([]rule.Rule, error) {
+	var loaded []rule.Rule
+	var errorList []string
 	for _, customCheck := range checks.Checks {
 		func(customCheck Check) {
-			debug.Log("Loading check: %s\n", customCheck.Code)
-			scanner.RegisterCheckRule(rule.Rule{
-				Base: rules.Register(
-					rules.Rule{
-						Service:    "custom",
-						ShortCode:  customCheck.Code,
-						Summary:    customCheck.Description,
-						Impact:     customCheck.Impact,
-						Resolution: customCheck.Resolution,
-						Provider:   provider.CustomProvider,
-						Links:      customCheck.RelatedLinks,
-						Severity:   customCheck.Severity,
+			logger.Log("Loading check: %s", customCheck.Code)
+			matchSpec, err := customCheck.resolve(customCheck.MatchSpec, nil)
+			if err != nil {
+				errorList = append(errorList, resolveError(customCheck, err).Error())
+				return
+			}
+			customCheck.MatchSpec = matchSpec
+			customCheck.debug = logger
+			m := matcher{debug: logger}
+			definition := customCheck.baseRule()
+			if customCheck.Target != "" {
+				loaded = append(loaded, rule.Rule{
+					Definition: &definition,
+					CheckState: func(s *state.State) rules.Results {
+						return m.checkState(customCheck, s)
 					},
-					nil,
-				),
+					LegacyID:       customCheck.Code,
+					BadExample:     customCheck.BadExamples,
+					GoodExample:    customCheck.GoodExamples,
+					ComplianceTags: customCheck.ComplianceTags,
+				})
+				return
+			}
+			loaded = append(loaded, rule.Rule{
+				Definition:      &definition,
 				LegacyID:        customCheck.Code,
+				BadExample:      customCheck.BadExamples,
+				GoodExample:     customCheck.GoodExamples,
+				ComplianceTags:  customCheck.ComplianceTags,
 				RequiredTypes:   customCheck.RequiredTypes,
 				RequiredLabels:  customCheck.RequiredLabels,
 				RequiredSources: customCheck.RequiredSources,
 				CheckTerraform: func(rootBlock block.Block, module block.Module) (results rules.Results) {
 					matchSpec := customCheck.MatchSpec
-					if !evalMatchSpec(rootBlock, matchSpec, module) {
+					if !m.evalMatchSpec(rootBlock, matchSpec, module) {
 						results.Add(
-							fmt.Sprintf("Custom check failed for resource %s. %s", rootBlock.FullName(), customCheck.ErrorMessage),
+							customCheck.message(m.blockMessageData(rootBlock, matchSpec, module)),
 							rootBlock,
 						)
 					}
@@ -153,9 +143,21 @@ func processFoundChecks(checks ChecksFile) {
 			})
 		}(*customCheck)
 	}
+	if len(errorList) > 0 {
+		return nil, errors.New(strings.Join(errorList, "\n"))
+	}
+	return loaded, nil
+}
+
+// resolveError reports a check which could not be resolved in the same form as the errors found by Validate
+func resolveError(check Check, err error) error {
+	if check.source != "" {
+		return fmt.Errorf("check %s at %s failed with the following errors;\n\n - %s\n", check.Code, check.source, err)
+	}
+	return fmt.Errorf("check %s failed with the following errors;\n\n - %s\n", check.Code, err)
 }
 
-func evalMatchSpec(b block.Block, spec *MatchSpec, module block.Module) bool {
+func (m matcher) evalMatchSpec(b block.Block, spec *MatchSpec, module block.Module) bool {
 	if b.IsNil() {
 		return false
 	}
@@ -163,7 +165,7 @@ func evalMatchSpec(b block.Block, spec *MatchSpec, module block.Module) bool {
 
 	if spec.PreConditions != nil {
 		for _, preCondition := range spec.PreConditions {
-			if !evalMatchSpec(b, &preCondition, module) {
+			if !m.evalMatchSpec(b, &preCondition, module) {
 				// precondition not met
 				return true
 			}
@@ -181,43 +183,51 @@ func evalMatchSpec(b block.Block, spec *MatchSpec, module block.Module) bool {
 		return checkTags(b, spec, module)
 	case OfType:
 		return ofType(b, spec)
+	case Expression:
+		evalResult = m.evalExpression(b, spec)
+	case IsReferencedBy:
+		return m.isReferencedBy(b, spec, module)
+	case References:
+		return m.references(b, spec, module)
+	case Any, All, None, Count:
+		return m.evalQuantifier(b, spec, module)
 	case RequiresPresence:
 		return resourceFound(spec, module)
 	case Not:
-		return notifyPredicate(b, spec, module)
+		return m.notifyPredicate(b, spec, module)
 	case And:
-		return processAndPredicate(spec, b, module)
+		return m.processAndPredicate(spec, b, module)
 	case Or:
-		return processOrPredicate(spec, b, module)
+		return m.processOrPredicate(spec, b, module)
 	default:
 		evalResult = matchFunctions[spec.Action](b, spec)
 	}
 
 	if spec.SubMatch != nil {
-		evalResult = processSubMatches(spec, b, evalResult)
+		evalResult = m.processSubMatches(spec, b, evalResult, module)
 	}
 
 	return evalResult
 }
 
-func notifyPredicate(b block.Block, spec *MatchSpec, module block.Module) bool {
-	return !evalMatchSpec(b, &spec.PredicateMatchSpec[0], module)
+func (m matcher) notifyPredicate(b block.Block, spec *MatchSpec, module block.Module) bool {
+	return !m.evalMatchSpec(b, &spec.PredicateMatchSpec[0], module)
 }
 
-func processOrPredicate(spec *MatchSpec, b block.Block, module block.Module) bool {
+func (m matcher) processOrPredicate(spec *MatchSpec, b block.Block, module block.Module) bool {
 	for _, childSpec := range spec.PredicateMatchSpec {
-		if evalMatchSpec(b, &childSpec, module) {
+		if m.evalMatchSpec(b, &childSpec, module) {
 			return true
 		}
 	}
 	return false
 }
 
-func processAndPredicate(spec *MatchSpec, b block.Block, module block.Module) bool {
+func (m matcher) processAndPredicate(spec *MatchSpec, b block.Block, module block.Module) bool {
 	set := make(map[bool]bool)
 
 	for _, childSpec := range spec.PredicateMatchSpec {
-		result := evalMatchSpec(b, &childSpec, module)
+		result := m.evalMatchSpec(b, &childSpec, module)
 		set[result] = true
 
 	}
@@ -225,9 +235,9 @@ func processAndPredicate(spec *MatchSpec, b block.Block, module block.Module) bo
 	return len(set) == 1 && set[true]
 }
 
-func processSubMatches(spec *MatchSpec, b block.Block, evalResult bool) bool {
+func (m matcher) processSubMatches(spec *MatchSpec, b block.Block, evalResult bool, module block.Module) bool {
 	for _, b := range b.GetBlocks(spec.Name) {
-		evalResult = evalMatchSpec(b, spec.SubMatch, nil)
+		evalResult = m.evalMatchSpec(b, spec.SubMatch, module)
 		if !evalResult {
 			break
 		}
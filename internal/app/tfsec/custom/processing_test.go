@@ -12,8 +12,10 @@ import (
 	"github.com/aquasecurity/defsec/rules"
 
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -192,7 +194,7 @@ resource "aws_ami" "example" {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			block := ParseFromSource(test.source)[0].GetBlocks()[0]
-			result := evalMatchSpec(block, &test.predicateMatchSpec, nil)
+			result := matcher{}.evalMatchSpec(block, &test.predicateMatchSpec, nil)
 			assert.Equal(t, result, test.expected, "`Or` match function evaluating incorrectly.")
 		})
 	}
@@ -239,7 +241,7 @@ resource "aws_ami" "example" {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			block := ParseFromSource(test.source)[0].GetBlocks()[0]
-			result := evalMatchSpec(block, &test.predicateMatchSpec, nil)
+			result := matcher{}.evalMatchSpec(block, &test.predicateMatchSpec, nil)
 			assert.Equal(t, result, test.expected, "`And` match function evaluating incorrectly.")
 		})
 	}
@@ -289,7 +291,7 @@ resource "aws_ami" "example" {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			block := ParseFromSource(test.source)[0].GetBlocks()[0]
-			result := evalMatchSpec(block, &test.predicateMatchSpec, nil)
+			result := matcher{}.evalMatchSpec(block, &test.predicateMatchSpec, nil)
 			assert.Equal(t, result, test.expected, "Nested match functions evaluating incorrectly.")
 		})
 	}
@@ -316,7 +318,7 @@ resource "aws_ami" "example" {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			block := ParseFromSource(test.source)[0].GetBlocks()[0]
-			result := evalMatchSpec(block, &test.matchSpec, nil)
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, nil)
 			assert.Equal(t, result, test.expected, "Not match functions evaluating incorrectly.")
 		})
 	}
@@ -366,19 +368,25 @@ resource "aws_ami" "testing" {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			block := ParseFromSource(test.source)[0].GetBlocks()[0]
-			result := evalMatchSpec(block, &test.matchSpec, nil)
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, nil)
 			assert.Equal(t, result, test.expected, "precondition functions evaluating incorrectly.")
 		})
 	}
 }
 
+var customRules []rule.Rule
+
 func givenCheck(jsonContent string) {
 	var checksfile ChecksFile
 	err := json.NewDecoder(strings.NewReader(jsonContent)).Decode(&checksfile)
 	if err != nil {
 		panic(err)
 	}
-	processFoundChecks(checksfile)
+	loaded, err := processFoundChecks(checksfile, debug.Logger{})
+	if err != nil {
+		panic(err)
+	}
+	customRules = append(customRules, loaded...)
 }
 
 func scanTerraform(t *testing.T, mainTf string) []rules.Result {
@@ -391,7 +399,7 @@ func scanTerraform(t *testing.T, mainTf string) []rules.Result {
 	blocks, err := parser.New(dirName, parser.OptionStopOnHCLError()).ParseDirectory()
 	assert.NoError(t, err)
 
-	res, _ := scanner.New(scanner.OptionStopOnErrors()).Scan(blocks)
+	res, _ := scanner.New(scanner.OptionStopOnErrors(), scanner.OptionWithCustomRules(customRules)).Scan(blocks)
 	return res
 }
 
@@ -0,0 +1,105 @@
+package custom
+
+import (
+	"reflect"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// isQuantifier reports whether the action applies its sub match to each element of the named blocks or list
+func (action CheckAction) isQuantifier() bool {
+	return action == Any || action == All || action == None || action == Count
+}
+
+// quantify decides whether a quantifier passes, given the number of elements and how many of them satisfied the sub match
+func quantify(spec *MatchSpec, total int, matched int) bool {
+	switch spec.Action {
+	case Any:
+		return matched > 0
+	case All:
+		return matched == total
+	case None:
+		return matched == 0
+	case Count:
+		if spec.Min != nil && matched < *spec.Min {
+			return false
+		}
+		if spec.Max != nil && matched > *spec.Max {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// evalQuantifier applies the sub match to each of the named nested blocks, including those generated by dynamic blocks, or to
+// each element of the named list attribute if there are no such blocks
+func (m matcher) evalQuantifier(b block.Block, spec *MatchSpec, module block.Module) bool {
+	if children := b.GetBlocks(spec.Name); len(children) > 0 {
+		var matched int
+		for _, child := range children {
+			if m.evalMatchSpec(child, spec.SubMatch, module) {
+				matched++
+			}
+		}
+		return quantify(spec, len(children), matched)
+	}
+
+	attribute := b.GetAttribute(spec.Name)
+	if attribute.IsNil() {
+		if spec.IgnoreUndefined {
+			return true
+		}
+		return quantify(spec, 0, 0)
+	}
+	elements, ok := m.valueElements(attribute.Value())
+	if !ok {
+		m.debug.Log("The %s action cannot be applied to %s of %s, which is not a list", spec.Action, spec.Name, b.FullName())
+		return false
+	}
+	var matched int
+	for _, element := range elements {
+		if m.evalStateMatchSpec(element, spec.SubMatch) {
+			matched++
+		}
+	}
+	return quantify(spec, len(elements), matched)
+}
+
+// evalStateQuantifier applies the sub match to each element of a list field of the adapted state
+func (m matcher) evalStateQuantifier(field reflect.Value, found bool, spec *MatchSpec) bool {
+	if !found {
+		if spec.IgnoreUndefined {
+			return true
+		}
+		return quantify(spec, 0, 0)
+	}
+	elements := flatten(indirect(field))
+	var matched int
+	for _, element := range elements {
+		if m.evalStateMatchSpec(element, spec.SubMatch) {
+			matched++
+		}
+	}
+	return quantify(spec, len(elements), matched)
+}
+
+// valueElements converts the elements of a list attribute into values which can be matched in the same way as the adapted state,
+// where the name of a sub match refers to a key of each element, or to the element itself when empty
+func (m matcher) valueElements(val cty.Value) ([]reflect.Value, bool) {
+	if val.IsNull() || !val.IsKnown() || !(val.Type().IsListType() || val.Type().IsSetType() || val.Type().IsTupleType()) {
+		return nil, false
+	}
+	converted, err := ctyToGo(val)
+	if err != nil {
+		m.debug.Log("Failed to convert list value: %s", err)
+		return nil, false
+	}
+	elements, _ := converted.([]interface{})
+	var values []reflect.Value
+	for i := range elements {
+		values = append(values, reflect.ValueOf(&elements[i]).Elem())
+	}
+	return values, true
+}
@@ -0,0 +1,206 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRestrictedIngress = MatchSpec{Name: "cidr_blocks", Action: NotContains, MatchValue: "0.0.0.0/0"}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestQuantifiers(t *testing.T) {
+	nestedSource := `
+resource "aws_security_group" "example" {
+	ingress {
+		cidr_blocks = ["10.0.0.0/16"]
+	}
+	ingress {
+		cidr_blocks = ["0.0.0.0/0"]
+	}
+	ingress {
+		cidr_blocks = ["10.1.0.0/16"]
+	}
+}
+`
+	var tests = []struct {
+		name      string
+		source    string
+		matchSpec MatchSpec
+		expected  bool
+	}{
+		{
+			name:      "check that any passes when one nested block matches",
+			source:    nestedSource,
+			matchSpec: MatchSpec{Name: "ingress", Action: Any, SubMatch: &testRestrictedIngress},
+			expected:  true,
+		},
+		{
+			name:      "check that all fails when one nested block does not match",
+			source:    nestedSource,
+			matchSpec: MatchSpec{Name: "ingress", Action: All, SubMatch: &testRestrictedIngress},
+			expected:  false,
+		},
+		{
+			name:      "check that none fails when a nested block matches",
+			source:    nestedSource,
+			matchSpec: MatchSpec{Name: "ingress", Action: None, SubMatch: &testRestrictedIngress},
+			expected:  false,
+		},
+		{
+			name:      "check that count passes when the number of matching nested blocks is within the bounds",
+			source:    nestedSource,
+			matchSpec: MatchSpec{Name: "ingress", Action: Count, Min: intPtr(2), Max: intPtr(2), SubMatch: &testRestrictedIngress},
+			expected:  true,
+		},
+		{
+			name:      "check that count fails when the number of matching nested blocks is outside the bounds",
+			source:    nestedSource,
+			matchSpec: MatchSpec{Name: "ingress", Action: Count, Max: intPtr(1), SubMatch: &testRestrictedIngress},
+			expected:  false,
+		},
+		{
+			name: "check that quantifiers include dynamic blocks",
+			source: `
+locals {
+	cidrs = ["0.0.0.0/0", "10.0.0.0/16"]
+}
+
+resource "aws_security_group" "example" {
+	dynamic "ingress" {
+		for_each = local.cidrs
+		content {
+			cidr_blocks = [ingress.value]
+		}
+	}
+}
+`,
+			matchSpec: MatchSpec{Name: "ingress", Action: Count, Min: intPtr(1), Max: intPtr(1), SubMatch: &testRestrictedIngress},
+			expected:  true,
+		},
+		{
+			name: "check that quantifiers apply to the objects of a list attribute",
+			source: `
+resource "aws_security_group" "example" {
+	ingress = [
+		{ cidr_blocks = ["0.0.0.0/0"] },
+		{ cidr_blocks = ["10.0.0.0/16"] },
+	]
+}
+`,
+			matchSpec: MatchSpec{Name: "ingress", Action: Any, SubMatch: &MatchSpec{Name: "cidr_blocks", Action: Contains, MatchValue: "0.0.0.0/0"}},
+			expected:  true,
+		},
+		{
+			name: "check that quantifiers apply to the values of a list attribute",
+			source: `
+resource "aws_security_group" "example" {
+	ingress {
+		cidr_blocks = ["10.0.0.0/16", "192.168.0.0/16"]
+	}
+}
+`,
+			matchSpec: MatchSpec{
+				Name:     "ingress",
+				Action:   All,
+				SubMatch: &MatchSpec{Name: "cidr_blocks", Action: All, SubMatch: &MatchSpec{Action: RegexMatches, MatchValue: `^(10|192\.168)\.`}},
+			},
+			expected: true,
+		},
+		{
+			name: "check that all passes when there are no nested blocks",
+			source: `
+resource "aws_security_group" "example" {
+}
+`,
+			matchSpec: MatchSpec{Name: "ingress", Action: All, SubMatch: &testRestrictedIngress},
+			expected:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := ParseFromSource(test.source)[0]
+			block := module.GetResourcesByType("aws_security_group")[0]
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, module)
+			assert.Equal(t, test.expected, result, "quantifier evaluating incorrectly.")
+		})
+	}
+}
+
+func TestSubMatchesHaveTheModule(t *testing.T) {
+	module := ParseFromSource(`
+resource "aws_kms_key" "example" {
+}
+
+resource "aws_instance" "example" {
+	ebs_block_device {
+		encrypted = true
+	}
+}
+`)[0]
+	block := module.GetResourcesByType("aws_instance")[0]
+
+	spec := MatchSpec{
+		Name:     "ebs_block_device",
+		Action:   IsPresent,
+		SubMatch: &MatchSpec{Name: "aws_kms_key", Action: RequiresPresence},
+	}
+	assert.True(t, matcher{}.evalMatchSpec(block, &spec, module))
+
+	spec.Action = Any
+	assert.True(t, matcher{}.evalMatchSpec(block, &spec, module))
+}
+
+func TestQuantifiersAreValidated(t *testing.T) {
+	check := &Check{
+		Code:           "CUS001",
+		Description:    "At least one ingress block must be restricted",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_security_group"},
+		Severity:       "HIGH",
+		MatchSpec:      &MatchSpec{Name: "ingress", Action: Count},
+	}
+	assert.Len(t, validate(check), 2)
+
+	check.MatchSpec.Min = intPtr(2)
+	check.MatchSpec.Max = intPtr(1)
+	check.MatchSpec.SubMatch = &MatchSpec{Action: Equals, MatchValue: "0.0.0.0/0"}
+	assert.Len(t, validate(check), 1)
+
+	check.MatchSpec.Min = intPtr(1)
+	assert.Empty(t, validate(check))
+}
+
+func TestQuantifierBoundsInHCL(t *testing.T) {
+	checks, err := loadHCLChecks("test_tfchecks.hcl", []byte(`
+check "CUS001" {
+  description     = "At least one ingress block must be restricted"
+  required_types  = ["resource"]
+  required_labels = ["aws_security_group"]
+  severity        = "HIGH"
+
+  match {
+    name   = "ingress"
+    action = "count"
+    min    = 1
+
+    sub_match {
+      name   = "cidr_blocks"
+      action = "notContains"
+      value  = "0.0.0.0/0"
+    }
+  }
+}
+`))
+	require.NoError(t, err)
+	require.Len(t, checks.Checks, 1)
+	spec := checks.Checks[0].MatchSpec
+	require.NotNil(t, spec.Min)
+	assert.Equal(t, 1, *spec.Min)
+	assert.Nil(t, spec.Max)
+}
@@ -0,0 +1,60 @@
+package custom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+)
+
+// splitReferencingName splits the name of an isReferencedBy spec, such as aws_s3_bucket_public_access_block.bucket, into the
+// type of the referencing resources and the attribute which refers to the block
+func splitReferencingName(name string) (string, string, error) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("matchSpec.Name[%s] for the `%s` action must be a resource type and attribute, e.g. aws_s3_bucket_public_access_block.bucket", name, IsReferencedBy)
+	}
+	return parts[0], parts[1], nil
+}
+
+// isReferencedBy checks that a resource of the named type refers to the block in the named attribute. If there is a sub match,
+// at least one of the referencing resources must also satisfy it.
+func (m matcher) isReferencedBy(b block.Block, spec *MatchSpec, module block.Module) bool {
+	if module == nil {
+		m.debug.Log("The %s action cannot be evaluated for %s without its module", IsReferencedBy, b.FullName())
+		return false
+	}
+	referencingType, attributeName, err := splitReferencingName(spec.Name)
+	if err != nil {
+		m.debug.Log("%s", err)
+		return false
+	}
+	for _, referencing := range module.GetReferencingResources(b, referencingType, attributeName) {
+		if spec.SubMatch == nil || m.evalMatchSpec(referencing, spec.SubMatch, module) {
+			return true
+		}
+	}
+	return false
+}
+
+// references checks that the named attribute of the block refers to another block in the module, which must be of the type
+// given as the check value if there is one. If there is a sub match, the referenced block must also satisfy it.
+func (m matcher) references(b block.Block, spec *MatchSpec, module block.Module) bool {
+	attribute := b.GetAttribute(spec.Name)
+	if attribute.IsNil() {
+		return spec.IgnoreUndefined
+	}
+	if module == nil {
+		m.debug.Log("The %s action cannot be evaluated for %s without its module", References, b.FullName())
+		return false
+	}
+	referenced, err := module.GetReferencedBlock(attribute, b)
+	if err != nil {
+		m.debug.Log("%s", err)
+		return false
+	}
+	if spec.MatchValue != nil && referenced.TypeLabel() != fmt.Sprintf("%v", spec.MatchValue) {
+		return false
+	}
+	return spec.SubMatch == nil || m.evalMatchSpec(referenced, spec.SubMatch, module)
+}
@@ -0,0 +1,168 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReferencedBy(t *testing.T) {
+	var tests = []struct {
+		name      string
+		source    string
+		matchSpec MatchSpec
+		expected  bool
+	}{
+		{
+			name: "check that a bucket referenced by a public access block passes",
+			source: `
+resource "aws_s3_bucket" "example" {
+}
+
+resource "aws_s3_bucket_public_access_block" "example" {
+	bucket = aws_s3_bucket.example.id
+}
+`,
+			matchSpec: MatchSpec{Name: "aws_s3_bucket_public_access_block.bucket", Action: IsReferencedBy},
+			expected:  true,
+		},
+		{
+			name: "check that a bucket which is not referenced by a public access block fails",
+			source: `
+resource "aws_s3_bucket" "example" {
+}
+
+resource "aws_s3_bucket" "other" {
+}
+
+resource "aws_s3_bucket_public_access_block" "other" {
+	bucket = aws_s3_bucket.other.id
+}
+`,
+			matchSpec: MatchSpec{Name: "aws_s3_bucket_public_access_block.bucket", Action: IsReferencedBy},
+			expected:  false,
+		},
+		{
+			name: "check that the sub match is applied to the referencing resources",
+			source: `
+resource "aws_s3_bucket" "example" {
+}
+
+resource "aws_s3_bucket_public_access_block" "example" {
+	bucket            = aws_s3_bucket.example.id
+	block_public_acls = false
+}
+`,
+			matchSpec: MatchSpec{
+				Name:     "aws_s3_bucket_public_access_block.bucket",
+				Action:   IsReferencedBy,
+				SubMatch: &MatchSpec{Name: "block_public_acls", Action: Equals, MatchValue: true},
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := ParseFromSource(test.source)[0]
+			block := module.GetResourcesByType("aws_s3_bucket")[0]
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, module)
+			assert.Equal(t, test.expected, result, "isReferencedBy evaluating incorrectly.")
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	var tests = []struct {
+		name      string
+		source    string
+		matchSpec MatchSpec
+		expected  bool
+	}{
+		{
+			name: "check that an instance in a private subnet passes",
+			source: `
+resource "aws_subnet" "private" {
+	map_public_ip_on_launch = false
+}
+
+resource "aws_instance" "example" {
+	subnet_id = aws_subnet.private.id
+}
+`,
+			matchSpec: MatchSpec{
+				Name:       "subnet_id",
+				Action:     References,
+				MatchValue: "aws_subnet",
+				SubMatch:   &MatchSpec{Name: "map_public_ip_on_launch", Action: Equals, MatchValue: false},
+			},
+			expected: true,
+		},
+		{
+			name: "check that an instance in a public subnet fails",
+			source: `
+resource "aws_subnet" "public" {
+	map_public_ip_on_launch = true
+}
+
+resource "aws_instance" "example" {
+	subnet_id = aws_subnet.public.id
+}
+`,
+			matchSpec: MatchSpec{
+				Name:       "subnet_id",
+				Action:     References,
+				MatchValue: "aws_subnet",
+				SubMatch:   &MatchSpec{Name: "map_public_ip_on_launch", Action: Equals, MatchValue: false},
+			},
+			expected: false,
+		},
+		{
+			name: "check that a reference to a block of another type fails",
+			source: `
+resource "aws_default_subnet" "default" {
+}
+
+resource "aws_instance" "example" {
+	subnet_id = aws_default_subnet.default.id
+}
+`,
+			matchSpec: MatchSpec{Name: "subnet_id", Action: References, MatchValue: "aws_subnet"},
+			expected:  false,
+		},
+		{
+			name: "check that an attribute which is not a reference fails",
+			source: `
+resource "aws_instance" "example" {
+	subnet_id = "subnet-12345"
+}
+`,
+			matchSpec: MatchSpec{Name: "subnet_id", Action: References},
+			expected:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := ParseFromSource(test.source)[0]
+			block := module.GetResourcesByType("aws_instance")[0]
+			result := matcher{}.evalMatchSpec(block, &test.matchSpec, module)
+			assert.Equal(t, test.expected, result, "references evaluating incorrectly.")
+		})
+	}
+}
+
+func TestIsReferencedByNameIsValidated(t *testing.T) {
+	check := &Check{
+		Code:           "CUS001",
+		Description:    "Buckets must have a public access block",
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"aws_s3_bucket"},
+		Severity:       "HIGH",
+		MatchSpec:      &MatchSpec{Name: "aws_s3_bucket_public_access_block", Action: IsReferencedBy},
+	}
+	assert.Len(t, validate(check), 1)
+
+	check.MatchSpec.Name = "aws_s3_bucket_public_access_block.bucket"
+	assert.Empty(t, validate(check))
+}
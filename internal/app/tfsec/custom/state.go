@@ -0,0 +1,416 @@
+package custom
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/state"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// stateActions are the actions which can be used by checks with a target, as the others inspect HCL blocks
+var stateActions = []CheckAction{
+	IsPresent,
+	NotPresent,
+	IsEmpty,
+	StartsWith,
+	EndsWith,
+	Contains,
+	NotContains,
+	Equals,
+	NotEqual,
+	LessThan,
+	LessThanOrEqualTo,
+	GreaterThan,
+	GreaterThanOrEqualTo,
+	RegexMatches,
+	IsAny,
+	IsNone,
+	Any,
+	All,
+	None,
+	Count,
+	And,
+	Or,
+	Not,
+}
+
+var metadataType = reflect.TypeOf(types.Metadata{})
+
+// stateItem reports a result against an item of the adapted state
+type stateItem struct {
+	metadata *types.Metadata
+}
+
+func (i stateItem) GetMetadata() *types.Metadata {
+	return i.metadata
+}
+
+func (i stateItem) GetRawValue() interface{} {
+	return nil
+}
+
+// checkState runs the check against each item at its target, such as each bucket for aws.s3.buckets
+func (m matcher) checkState(check Check, s *state.State) (results rules.Results) {
+	items, err := stateItems(s, check.Target)
+	if err != nil {
+		m.debug.Log("Failed to find the target of %s: %s", check.Code, err)
+		return nil
+	}
+	for _, item := range items {
+		metadata := itemMetadata(item)
+		if metadata == nil || metadata.Range() == nil || !metadata.IsManaged() {
+			continue
+		}
+		if !m.evalStateMatchSpec(item, check.MatchSpec) {
+			results.Add(
+				check.message(messageData{Resource: metadata.String(), Spec: m.failingStateSpec(item, check.MatchSpec)}),
+				stateItem{metadata: metadata},
+			)
+		}
+	}
+	return results
+}
+
+// stateItems returns the items at the path within the state, where each part of the path names a field, ignoring case and
+// underscores. Lists are flattened, so that each of their elements is an item.
+func stateItems(s *state.State, path string) ([]reflect.Value, error) {
+	values := []reflect.Value{reflect.ValueOf(s).Elem()}
+	for _, part := range strings.Split(path, ".") {
+		var next []reflect.Value
+		for _, value := range values {
+			field, ok := stateField(value, part)
+			if !ok {
+				return nil, fmt.Errorf("%s has no field named %s", value.Type(), part)
+			}
+			next = append(next, flatten(field)...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// validateTarget checks that the path names fields of the state, by walking the types of the state rather than any adapted values
+func validateTarget(path string) error {
+	if path == "" {
+		return fmt.Errorf("check.Target requires a value")
+	}
+	typ := reflect.TypeOf(state.State{})
+	for _, part := range strings.Split(path, ".") {
+		fieldType, ok := stateFieldType(typ, part)
+		if !ok {
+			return fmt.Errorf("%s has no field named %s", typ, part)
+		}
+		typ = flattenType(fieldType)
+		if typ.Kind() == reflect.Interface {
+			// the fields of an interface depend on the value it holds, so cannot be checked
+			return nil
+		}
+	}
+	return nil
+}
+
+// stateFieldType returns the type of the field of a struct type with the given name, ignoring case and underscores, or the
+// type of the values of a map with string keys
+func stateFieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	typ = indirectType(typ)
+	if typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String {
+		return typ.Elem(), true
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+	normalised := normaliseFieldName(name)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Anonymous {
+			continue
+		}
+		if normaliseFieldName(field.Name) == normalised {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// flattenType returns the type of the elements of a list type, or the type itself if it is not a list, following pointers
+func flattenType(typ reflect.Type) reflect.Type {
+	typ = indirectType(typ)
+	if typ.Kind() == reflect.Slice {
+		return indirectType(typ.Elem())
+	}
+	return typ
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// stateField returns the field of a struct with the given name, ignoring case and underscores, or the value of a map with the given key
+func stateField(value reflect.Value, name string) (reflect.Value, bool) {
+	value = indirect(value)
+	if value.IsValid() && value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String {
+		field := value.MapIndex(reflect.ValueOf(name))
+		return field, field.IsValid()
+	}
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	normalised := normaliseFieldName(name)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" || field.Anonymous {
+			continue
+		}
+		if normaliseFieldName(field.Name) == normalised {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func normaliseFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// flatten returns the elements of a list, or the value itself if it is not a list
+func flatten(value reflect.Value) []reflect.Value {
+	if value.Kind() != reflect.Slice {
+		return []reflect.Value{value}
+	}
+	var elements []reflect.Value
+	for i := 0; i < value.Len(); i++ {
+		elements = append(elements, value.Index(i))
+	}
+	return elements
+}
+
+// indirect follows pointers and interfaces, returning an invalid value if any are nil
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// itemMetadata returns the metadata of an adapted item, which either provides it or embeds it
+func itemMetadata(item reflect.Value) *types.Metadata {
+	if provider, ok := metadataProvider(item); ok {
+		return provider.GetMetadata()
+	}
+	item = indirect(item)
+	if !item.IsValid() || item.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < item.NumField(); i++ {
+		field := item.Type().Field(i)
+		if field.Anonymous && field.Type == metadataType && item.Field(i).CanAddr() {
+			return item.Field(i).Addr().Interface().(*types.Metadata)
+		}
+	}
+	return nil
+}
+
+func metadataProvider(value reflect.Value) (rules.MetadataProvider, bool) {
+	if !value.IsValid() {
+		return nil, false
+	}
+	if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+		return nil, false
+	}
+	if provider, ok := value.Interface().(rules.MetadataProvider); ok {
+		return provider, true
+	}
+	if value.CanAddr() {
+		if provider, ok := value.Addr().Interface().(rules.MetadataProvider); ok {
+			return provider, true
+		}
+	}
+	return nil, false
+}
+
+// itemField returns the field of the item at the dot separated path given as the name of a match spec, such as encryption.enabled,
+// or the item itself if the name is empty
+func itemField(item reflect.Value, name string) (reflect.Value, bool) {
+	value := item
+	if name == "" {
+		return value, indirect(value).IsValid()
+	}
+	for _, part := range strings.Split(name, ".") {
+		field, ok := stateField(value, part)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		value = field
+	}
+	if indirect(value).IsValid() {
+		return value, true
+	}
+	return reflect.Value{}, false
+}
+
+// rawValue returns the value held by a field, unwrapping defsec values such as types.StringValue
+func rawValue(value reflect.Value) interface{} {
+	if provider, ok := metadataProvider(value); ok {
+		if raw := provider.GetRawValue(); raw != nil {
+			return raw
+		}
+	}
+	value = indirect(value)
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
+
+// isSet reports whether a field was set in the terraform code, rather than defaulted by the adapter
+func isSet(value reflect.Value) bool {
+	if !indirect(value).IsValid() {
+		return false
+	}
+	if provider, ok := metadataProvider(value); ok && provider.GetMetadata() != nil {
+		return !provider.GetMetadata().IsDefault()
+	}
+	return true
+}
+
+func (m matcher) evalStateMatchSpec(item reflect.Value, spec *MatchSpec) bool {
+	for _, preCondition := range spec.PreConditions {
+		if !m.evalStateMatchSpec(item, &preCondition) {
+			// precondition not met
+			return true
+		}
+	}
+
+	switch spec.Action {
+	case Not:
+		return !m.evalStateMatchSpec(item, &spec.PredicateMatchSpec[0])
+	case And:
+		for _, childSpec := range spec.PredicateMatchSpec {
+			if !m.evalStateMatchSpec(item, &childSpec) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, childSpec := range spec.PredicateMatchSpec {
+			if m.evalStateMatchSpec(item, &childSpec) {
+				return true
+			}
+		}
+		return false
+	}
+
+	field, found := itemField(item, spec.Name)
+	var evalResult bool
+	switch spec.Action {
+	case Any, All, None, Count:
+		return m.evalStateQuantifier(field, found, spec)
+	case IsPresent:
+		evalResult = (found && isSet(field)) || spec.IgnoreUndefined
+	case NotPresent:
+		evalResult = !found || !isSet(field)
+	case IsEmpty:
+		evalResult = !found || isEmptyValue(rawValue(field))
+	default:
+		if !found {
+			return spec.IgnoreUndefined
+		}
+		evalResult = m.compareStateValue(field, spec)
+	}
+
+	if spec.SubMatch != nil && found {
+		for _, element := range flatten(field) {
+			evalResult = m.evalStateMatchSpec(element, spec.SubMatch)
+			if !evalResult {
+				break
+			}
+		}
+	}
+	return evalResult
+}
+
+// compareStateValue compares the field using the same logic as block checks use for attributes, by converting it to a value
+func (m matcher) compareStateValue(field reflect.Value, spec *MatchSpec) bool {
+	compare, ok := attributeMatchFunctions[spec.Action]
+	if !ok {
+		m.debug.Log("The %s action cannot be used with a target", spec.Action)
+		return false
+	}
+	value, err := stateValue(field)
+	if err != nil {
+		m.debug.Log("The value of %s cannot be compared: %s", spec.Name, err)
+		return false
+	}
+	attribute := block.NewHCLAttribute(
+		&hcl.Attribute{Name: spec.Name, Expr: hcl.StaticExpr(value, hcl.Range{})},
+		block.NewContext(&hcl.EvalContext{}, nil),
+		"",
+		&block.Reference{},
+	)
+	result := compare(attribute, spec)
+	if spec.Action == RegexMatches && !result {
+		return spec.IgnoreUnmatched
+	}
+	return result
+}
+
+// stateValue converts a field of the adapted state to a cty value, where lists become tuples
+func stateValue(field reflect.Value) (cty.Value, error) {
+	if value := indirect(field); value.IsValid() && value.Kind() == reflect.Slice {
+		var elements []cty.Value
+		for _, element := range flatten(value) {
+			converted, err := stateValue(element)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elements = append(elements, converted)
+		}
+		if len(elements) == 0 {
+			return cty.EmptyTupleVal, nil
+		}
+		return cty.TupleVal(elements), nil
+	}
+	raw := rawValue(field)
+	if raw == nil {
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	}
+	typ, err := gocty.ImpliedType(raw)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return gocty.ToCtyValue(raw, typ)
+}
+
+func isEmptyValue(raw interface{}) bool {
+	if raw == nil {
+		return true
+	}
+	value := reflect.ValueOf(raw)
+	switch value.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice:
+		return value.Len() == 0
+	}
+	return false
+}
+
+func (action *CheckAction) isValidForState() bool {
+	for _, checkAction := range stateActions {
+		if checkAction == *action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateCheckUsesAdaptedValues(t *testing.T) {
+	check := &Check{
+		Code:         "CUS101",
+		Description:  "Buckets must be versioned",
+		Severity:     "HIGH",
+		Target:       "aws.s3.buckets",
+		ErrorMessage: "Versioning is not enabled",
+		MatchSpec: &MatchSpec{
+			Name:       "versioning.enabled",
+			Action:     Equals,
+			MatchValue: true,
+		},
+	}
+
+	results := scanWithCheck(t, check, `
+resource "aws_s3_bucket" "versioned" {
+	versioning {
+		enabled = true
+	}
+}
+
+resource "aws_s3_bucket" "unversioned" {
+}
+`)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Custom check failed for resource aws_s3_bucket.unversioned. Versioning is not enabled", results[0].Description())
+	assert.Equal(t, 8, results[0].NarrowestRange().GetStartLine())
+	assert.Equal(t, 9, results[0].NarrowestRange().GetEndLine())
+}
+
+func TestStateCheckCoversItemsDefinedByOtherResources(t *testing.T) {
+	check := &Check{
+		Code:        "CUS102",
+		Description: "Storage account network rules must allow Azure services",
+		Severity:    "HIGH",
+		Target:      "azure.storage.accounts.network_rules",
+		MatchSpec: &MatchSpec{
+			Name:       "bypass",
+			Action:     Contains,
+			MatchValue: "AzureServices",
+		},
+	}
+
+	results := scanWithCheck(t, check, `
+resource "azurerm_storage_account" "inline" {
+	network_rules {
+		bypass = ["AzureServices"]
+	}
+}
+
+resource "azurerm_storage_account" "separate" {
+	name = "separate"
+}
+
+resource "azurerm_storage_account_network_rules" "separate" {
+	storage_account_name = azurerm_storage_account.separate.name
+	bypass               = ["Logging"]
+}
+`)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Custom check failed for resource azurerm_storage_account_network_rules.separate. ", results[0].Description())
+	assert.Equal(t, 12, results[0].NarrowestRange().GetStartLine())
+}
+
+func TestStateCheckWithSubMatch(t *testing.T) {
+	check := &Check{
+		Code:        "CUS103",
+		Description: "Buckets must have logging enabled",
+		Severity:    "HIGH",
+		Target:      "aws.s3.buckets",
+		MatchSpec: &MatchSpec{
+			Name:   "logging",
+			Action: IsPresent,
+			SubMatch: &MatchSpec{
+				Name:       "enabled",
+				Action:     Equals,
+				MatchValue: true,
+			},
+		},
+	}
+
+	results := scanWithCheck(t, check, `
+resource "aws_s3_bucket" "logged" {
+	logging {
+		target_bucket = "logs"
+	}
+}
+
+resource "aws_s3_bucket" "unlogged" {
+}
+`)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].Description(), "aws_s3_bucket.unlogged")
+}
+
+func TestStateCheckIsValidated(t *testing.T) {
+	check := &Check{
+		Code:        "CUS104",
+		Description: "Buckets must be versioned",
+		Severity:    "HIGH",
+		Target:      "aws.s3.bucketz",
+		MatchSpec: &MatchSpec{
+			Action: And,
+			PredicateMatchSpec: []MatchSpec{
+				{Name: "versioning.enabled", Action: Equals, MatchValue: true},
+				{Name: "aws_s3_bucket_policy", Action: RequiresPresence},
+			},
+		},
+	}
+
+	errs := validate(check)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "check.Target[aws.s3.bucketz] is not valid")
+	assert.Contains(t, errs[1].Error(), "matchSpec.Action[requiresPresence] cannot be used by a check with a target")
+
+	check.Target = "aws.s3.buckets"
+	check.MatchSpec.PredicateMatchSpec = check.MatchSpec.PredicateMatchSpec[:1]
+	assert.Empty(t, validate(check))
+
+	// fields after a list are validated against the type of its elements
+	assert.NoError(t, validateTarget("azure.storage.accounts.network_rules"))
+	err := validateTarget("azure.storage.accounts.network_rulez")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no field named network_rulez")
+}
+
+func TestStateCheckActionsMatchBlockChecks(t *testing.T) {
+	source := `
+resource "azurerm_storage_account" "inline" {
+	network_rules {
+		bypass = ["azureservices"]
+	}
+}
+
+resource "aws_s3_bucket" "named" {
+	bucket = "Logs-Bucket"
+}
+`
+	for _, test := range []struct {
+		name     string
+		target   string
+		spec     MatchSpec
+		failures int
+	}{
+		{
+			name:   "contains ignores case",
+			target: "azure.storage.accounts.network_rules",
+			spec:   MatchSpec{Name: "bypass", Action: Contains, MatchValue: "AzureServices"},
+		},
+		{
+			name:   "equals ignores case",
+			target: "aws.s3.buckets",
+			spec:   MatchSpec{Name: "name", Action: Equals, MatchValue: "logs-bucket"},
+		},
+		{
+			name:     "is any does not ignore case",
+			target:   "aws.s3.buckets",
+			spec:     MatchSpec{Name: "name", Action: IsAny, MatchValue: []interface{}{"logs-bucket"}},
+			failures: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			spec := test.spec
+			check := &Check{
+				Code:        "CUS105",
+				Description: test.name,
+				Severity:    "LOW",
+				Target:      test.target,
+				MatchSpec:   &spec,
+			}
+			assert.Len(t, scanWithCheck(t, check, source), test.failures)
+		})
+	}
+}
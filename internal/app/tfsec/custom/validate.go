@@ -24,6 +24,9 @@ func Validate(checkFilePath string) error {
 	for _, check := range checkFile.Checks {
 		if err = func(check *Check) error {
 			errs := validate(check)
+			if len(errs) > 0 && check.source != "" {
+				return fmt.Errorf("check %s at %s failed with the following errors;\n\n - %s\n", check.Code, check.source, getErrorStrings(errs))
+			}
 			if len(errs) > 0 {
 				jsonContent, err := json.MarshalIndent(check, "", "  ")
 				if err != nil {
@@ -62,6 +65,18 @@ func validate(check *Check) []error {
 	if !check.Severity.IsValid() {
 		checkErrors = append(checkErrors, fmt.Errorf("check.Severity[%s] is not a recognised option. Should be %s", check.Severity, severity.ValidSeverity))
 	}
+	if check.isTemplated() {
+		if _, err := check.parseMessage(); err != nil {
+			checkErrors = append(checkErrors, fmt.Errorf("check.ErrorMessage is not a valid template: %w", err))
+		}
+	}
+	if check.Target != "" {
+		checkErrors = validateMatchSpec(check.MatchSpec, check, checkErrors)
+		if err := validateTarget(check.Target); err != nil {
+			checkErrors = append(checkErrors, fmt.Errorf("check.Target[%s] is not valid: %w", check.Target, err))
+		}
+		return validateStateMatchSpec(check.MatchSpec, check, checkErrors)
+	}
 	if len(check.RequiredTypes) == 0 {
 		checkErrors = append(checkErrors, errors.New("check.RequiredTypes requires a value"))
 	}
@@ -71,13 +86,76 @@ func validate(check *Check) []error {
 	return validateMatchSpec(check.MatchSpec, check, checkErrors)
 }
 
+// validateStateMatchSpec checks that a spec of a check with a target, and all of its children, only use actions which can be applied to the state
+func validateStateMatchSpec(spec *MatchSpec, check *Check, checkErrors []error) []error {
+	if spec == nil {
+		return checkErrors
+	}
+	if spec.Ref != "" {
+		// problems resolving the fragment are reported by validateMatchSpec
+		resolved, err := check.resolve(spec, nil)
+		if err != nil {
+			return checkErrors
+		}
+		return validateStateMatchSpec(resolved, check, checkErrors)
+	}
+	if spec.Action.isValid() && !spec.Action.isValidForState() {
+		checkErrors = append(checkErrors, specError(spec, fmt.Errorf("matchSpec.Action[%s] cannot be used by a check with a target. Should be %s", spec.Action, stateActions)))
+	}
+	for i := range spec.PreConditions {
+		checkErrors = validateStateMatchSpec(&spec.PreConditions[i], check, checkErrors)
+	}
+	for i := range spec.PredicateMatchSpec {
+		checkErrors = validateStateMatchSpec(&spec.PredicateMatchSpec[i], check, checkErrors)
+	}
+	if spec.SubMatch != nil {
+		checkErrors = validateStateMatchSpec(spec.SubMatch, check, checkErrors)
+	}
+	return checkErrors
+}
+
 func validateMatchSpec(spec *MatchSpec, check *Check, checkErrors []error) []error {
+	if spec == nil {
+		return append(checkErrors, errors.New("check.MatchSpec requires a value"))
+	}
+	return validateSpec(spec, check, checkErrors, false)
+}
+
+// validateSpec validates a match spec, where the name is optional for the sub match of a quantifier, as it can refer to each element of a list
+func validateSpec(spec *MatchSpec, check *Check, checkErrors []error, element bool) []error {
+	// a reference is validated as the fragment it names, once any cycles between fragments have been ruled out
+	if spec.Ref != "" {
+		if spec.Action != "" || spec.Name != "" {
+			checkErrors = append(checkErrors, specError(spec, fmt.Errorf("matchSpec.$ref[%s] cannot be combined with a name or action", spec.Ref)))
+		}
+		resolved, err := check.resolve(spec, nil)
+		if err != nil {
+			return append(checkErrors, specError(spec, err))
+		}
+		return validateSpec(resolved, check, checkErrors, element)
+	}
+	if _, err := check.resolveValue(spec.MatchValue); err != nil {
+		checkErrors = append(checkErrors, specError(spec, err))
+	}
+
 	if !spec.Action.isValid() {
-		checkErrors = append(checkErrors, fmt.Errorf("matchSpec.Action[%s] is not a recognised option. Should be %s", spec.Action, ValidCheckActions))
+		checkErrors = append(checkErrors, specError(spec, fmt.Errorf("matchSpec.Action[%s] is not a recognised option. Should be %s", spec.Action, ValidCheckActions)))
 	}
-	// if the check is one of `inModule`,`or`,`and`, `not`, no name is required
-	if len(spec.Name) == 0 && spec.Action != "inModule" && spec.Action != "or" && spec.Action != "and" && spec.Action != "not" {
-		checkErrors = append(checkErrors, errors.New("matchSpec.Name requires a value"))
+	// if the check is one of `inModule`,`or`,`and`, `not`, `expression`, no name is required
+	if len(spec.Name) == 0 && !element && spec.Action != "inModule" && spec.Action != "or" && spec.Action != "and" && spec.Action != "not" && spec.Action != Expression {
+		checkErrors = append(checkErrors, specError(spec, errors.New("matchSpec.Name requires a value")))
+	}
+
+	// quantifiers apply their subMatch to each element, and `count` must be bounded
+	if spec.Action.isQuantifier() && spec.SubMatch == nil {
+		checkErrors = append(checkErrors, specError(spec, fmt.Errorf("`%s` action must have a subMatch", spec.Action)))
+	}
+	if spec.Action == Count {
+		if spec.Min == nil && spec.Max == nil {
+			checkErrors = append(checkErrors, specError(spec, errors.New("`count` action must have a min or max")))
+		} else if spec.Min != nil && spec.Max != nil && *spec.Min > *spec.Max {
+			checkErrors = append(checkErrors, specError(spec, fmt.Errorf("matchSpec.Min[%d] cannot be greater than matchSpec.Max[%d]", *spec.Min, *spec.Max)))
+		}
 	}
 
 	// if the check is one of `or`, `and`, then all PredicateMatchSpec's must also be valid
@@ -92,12 +170,32 @@ func validateMatchSpec(spec *MatchSpec, check *Check, checkErrors []error) []err
 		if len(spec.PredicateMatchSpec) == 1 {
 			checkErrors = append(checkErrors, validateMatchSpec(&spec.PredicateMatchSpec[0], check, checkErrors)...)
 		} else {
-			checkErrors = append(checkErrors, errors.New("`not` action must have a single predicate attached"))
+			checkErrors = append(checkErrors, specError(spec, errors.New("`not` action must have a single predicate attached")))
+		}
+	}
+
+	if spec.Action == IsReferencedBy {
+		if _, _, err := splitReferencingName(spec.Name); err != nil && len(spec.Name) > 0 {
+			checkErrors = append(checkErrors, specError(spec, err))
+		}
+	}
+
+	if spec.Action == Expression {
+		if _, err := parseExpression(spec); err != nil {
+			checkErrors = append(checkErrors, specError(spec, err))
 		}
 	}
 
 	if spec.SubMatch != nil {
-		return validateMatchSpec(spec.SubMatch, check, checkErrors)
+		return validateSpec(spec.SubMatch, check, checkErrors, spec.Action.isQuantifier())
 	}
 	return checkErrors
 }
+
+// specError adds the location of the match spec to the error, if known
+func specError(spec *MatchSpec, err error) error {
+	if spec.source == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", spec.source, err)
+}
@@ -2,15 +2,51 @@ package debug
 
 import (
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 )
 
-var Enabled bool
+// Logger writes debug output. The zero value discards everything, so loggers can be embedded without initialisation.
+type Logger struct {
+	writer io.Writer
+}
 
-func Log(format string, args ...interface{}) {
-	if !Enabled {
+// New creates a Logger which writes to the given writer. A nil writer disables logging.
+func New(writer io.Writer) Logger {
+	return Logger{
+		writer: writer,
+	}
+}
+
+// Enabled returns true if the logger will write anything
+func (l Logger) Enabled() bool {
+	return l.writer != nil
+}
+
+func (l Logger) Log(format string, args ...interface{}) {
+	if l.writer == nil {
 		return
 	}
 	line := fmt.Sprintf(format, args...)
-	fmt.Printf("[DEBUG][%s] %s\n", time.Now(), line)
+	_, _ = fmt.Fprintf(l.writer, "[DEBUG][%s] %s\n", time.Now(), line)
+}
+
+var defaultLogger atomic.Value
+
+// Enable sets the process-wide logger used by Log, which is only intended for code that does not run as part of a particular scan
+func Enable(writer io.Writer) {
+	defaultLogger.Store(New(writer))
+}
+
+// Default returns the process-wide logger
+func Default() Logger {
+	if logger, ok := defaultLogger.Load().(Logger); ok {
+		return logger
+	}
+	return Logger{}
+}
+
+func Log(format string, args ...interface{}) {
+	Default().Log(format, args...)
 }
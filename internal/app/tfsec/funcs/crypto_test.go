@@ -3,6 +3,7 @@ package funcs
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/zclconf/go-cty/cty"
@@ -145,7 +146,7 @@ func TestFileBase64Sha256(t *testing.T) {
 		},
 	}
 
-	fileSHA256 := MakeFileBase64Sha256Func(".")
+	fileSHA256 := MakeFileBase64Sha256Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filebase64sha256(%#v)", test.Path), func(t *testing.T) {
@@ -226,7 +227,7 @@ func TestFileBase64Sha512(t *testing.T) {
 		},
 	}
 
-	fileSHA512 := MakeFileBase64Sha512Func(".")
+	fileSHA512 := MakeFileBase64Sha512Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filebase64sha512(%#v)", test.Path), func(t *testing.T) {
@@ -344,7 +345,7 @@ func TestFileMD5(t *testing.T) {
 		},
 	}
 
-	fileMD5 := MakeFileMd5Func(".")
+	fileMD5 := MakeFileMd5Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filemd5(%#v)", test.Path), func(t *testing.T) {
@@ -501,7 +502,7 @@ func TestFileSHA1(t *testing.T) {
 		},
 	}
 
-	fileSHA1 := MakeFileSha1Func(".")
+	fileSHA1 := MakeFileSha1Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filesha1(%#v)", test.Path), func(t *testing.T) {
@@ -579,7 +580,7 @@ func TestFileSHA256(t *testing.T) {
 		},
 	}
 
-	fileSHA256 := MakeFileSha256Func(".")
+	fileSHA256 := MakeFileSha256Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filesha256(%#v)", test.Path), func(t *testing.T) {
@@ -657,7 +658,7 @@ func TestFileSHA512(t *testing.T) {
 		},
 	}
 
-	fileSHA512 := MakeFileSha512Func(".")
+	fileSHA512 := MakeFileSha512Func(os.DirFS("."), ".")
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("filesha512(%#v)", test.Path), func(t *testing.T) {
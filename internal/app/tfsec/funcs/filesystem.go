@@ -3,10 +3,13 @@ package funcs
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
-	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/bmatcuk/doublestar"
@@ -20,7 +23,7 @@ import (
 // MakeFileFunc constructs a function that takes a file path and returns the
 // contents of that file, either directly as a string (where valid UTF-8 is
 // required) or as a string containing base64 bytes.
-func MakeFileFunc(baseDir string, encBase64 bool) function.Function {
+func MakeFileFunc(fsys fs.FS, baseDir string, encBase64 bool) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -31,7 +34,7 @@ func MakeFileFunc(baseDir string, encBase64 bool) function.Function {
 		Type: function.StaticReturnType(cty.String),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
 			path := args[0].AsString()
-			src, err := readFileBytes(baseDir, path)
+			src, err := readFileBytes(fsys, baseDir, path)
 			if err != nil {
 				err = function.NewArgError(0, err)
 				return cty.UnknownVal(cty.String), err
@@ -64,7 +67,7 @@ func MakeFileFunc(baseDir string, encBase64 bool) function.Function {
 // As a special exception, a referenced template file may not recursively call
 // the templatefile function, since that would risk the same file being
 // included into itself indefinitely.
-func MakeTemplateFileFunc(baseDir string, funcsCb func() map[string]function.Function) function.Function {
+func MakeTemplateFileFunc(fsys fs.FS, baseDir string, funcsCb func() map[string]function.Function) function.Function {
 
 	params := []function.Parameter{
 		{
@@ -80,7 +83,7 @@ func MakeTemplateFileFunc(baseDir string, funcsCb func() map[string]function.Fun
 	loadTmpl := func(fn string) (hcl.Expression, error) {
 		// We re-use File here to ensure the same filename interpretation
 		// as it does, along with its other safety checks.
-		tmplVal, err := File(baseDir, cty.StringVal(fn))
+		tmplVal, err := File(fsys, baseDir, cty.StringVal(fn))
 		if err != nil {
 			return nil, err
 		}
@@ -183,7 +186,7 @@ func MakeTemplateFileFunc(baseDir string, funcsCb func() map[string]function.Fun
 
 // MakeFileExistsFunc constructs a function that takes a path
 // and determines whether a file exists at that path
-func MakeFileExistsFunc(baseDir string) function.Function {
+func MakeFileExistsFunc(fsys fs.FS, baseDir string) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -193,22 +196,14 @@ func MakeFileExistsFunc(baseDir string) function.Function {
 		},
 		Type: function.StaticReturnType(cty.Bool),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			path := args[0].AsString()
-			path, err := homedir.Expand(path)
+			path, err := resolvePath(baseDir, args[0].AsString())
 			if err != nil {
-				return cty.UnknownVal(cty.Bool), fmt.Errorf("failed to expand ~: %s", err)
-			}
-
-			if !filepath.IsAbs(path) {
-				path = filepath.Join(baseDir, path)
+				return cty.UnknownVal(cty.Bool), err
 			}
 
-			// Ensure that the path is canonical for the host OS
-			path = filepath.Clean(path)
-
-			fi, err := os.Stat(path)
+			fi, err := fs.Stat(fsys, path)
 			if err != nil {
-				if os.IsNotExist(err) {
+				if errors.Is(err, fs.ErrNotExist) {
 					return cty.False, nil
 				}
 				return cty.UnknownVal(cty.Bool), fmt.Errorf("failed to stat %s", path)
@@ -226,7 +221,7 @@ func MakeFileExistsFunc(baseDir string) function.Function {
 
 // MakeFileSetFunc constructs a function that takes a glob pattern
 // and enumerates a file set from that pattern
-func MakeFileSetFunc(baseDir string) function.Function {
+func MakeFileSetFunc(fsys fs.FS, baseDir string) function.Function {
 	return function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -240,46 +235,22 @@ func MakeFileSetFunc(baseDir string) function.Function {
 		},
 		Type: function.StaticReturnType(cty.Set(cty.String)),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			path := args[0].AsString()
-			pattern := args[1].AsString()
-
-			if !filepath.IsAbs(path) {
-				path = filepath.Join(baseDir, path)
+			dir, err := resolvePath(baseDir, args[0].AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.Set(cty.String)), err
 			}
 
-			// Join the path to the glob pattern, while ensuring the full
-			// pattern is canonical for the host OS. The joined path is
-			// automatically cleaned during this operation.
-			pattern = filepath.Join(path, pattern)
+			// Ensure the pattern is canonical, as matches are relative to the path with forward slash (/)
+			// separators for cross-system compatibility.
+			pattern := path.Clean(filepath.ToSlash(args[1].AsString()))
 
-			matches, err := doublestar.Glob(pattern)
+			matches, err := globFS(fsys, dir, pattern)
 			if err != nil {
 				return cty.UnknownVal(cty.Set(cty.String)), fmt.Errorf("failed to glob pattern (%s): %s", pattern, err)
 			}
 
 			var matchVals []cty.Value
 			for _, match := range matches {
-				fi, err := os.Stat(match)
-
-				if err != nil {
-					return cty.UnknownVal(cty.Set(cty.String)), fmt.Errorf("failed to stat (%s): %s", match, err)
-				}
-
-				if !fi.Mode().IsRegular() {
-					continue
-				}
-
-				// Remove the path and file separator from matches.
-				match, err = filepath.Rel(path, match)
-
-				if err != nil {
-					return cty.UnknownVal(cty.Set(cty.String)), fmt.Errorf("failed to trim path of match (%s): %s", match, err)
-				}
-
-				// Replace any remaining file separators with forward slash (/)
-				// separators for cross-system compatibility.
-				match = filepath.ToSlash(match)
-
 				matchVals = append(matchVals, cty.StringVal(match))
 			}
 
@@ -353,31 +324,49 @@ var PathExpandFunc = function.New(&function.Spec{
 	},
 })
 
-func openFile(baseDir, path string) (*os.File, error) {
-	path, err := homedir.Expand(path)
+// resolvePath converts a path given to a function into a path within the filesystem.
+// Absolute paths are relative to the root of the filesystem, and relative paths are relative to baseDir.
+func resolvePath(baseDir, target string) (string, error) {
+	target, err := homedir.Expand(target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand ~: %s", err)
+		return "", fmt.Errorf("failed to expand ~: %s", err)
 	}
 
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(baseDir, path)
+	target = filepath.ToSlash(target)
+	if volume := filepath.VolumeName(target); volume != "" {
+		target = strings.TrimPrefix(target, volume)
 	}
 
-	// Ensure that the path is canonical for the host OS
-	path = filepath.Clean(path)
+	if strings.HasPrefix(target, "/") {
+		target = path.Clean(strings.TrimLeft(target, "/"))
+	} else {
+		target = path.Join(baseDir, target)
+	}
 
-	return os.Open(path)
+	if !fs.ValidPath(target) {
+		return "", fmt.Errorf("%s is outside of the filesystem being scanned", target)
+	}
+	return target, nil
+}
+
+func openFile(fsys fs.FS, baseDir, target string) (fs.File, error) {
+	resolved, err := resolvePath(baseDir, target)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.Open(resolved)
 }
 
-func readFileBytes(baseDir, path string) ([]byte, error) {
-	f, err := openFile(baseDir, path)
+func readFileBytes(fsys fs.FS, baseDir, path string) ([]byte, error) {
+	f, err := openFile(fsys, baseDir, path)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// An extra Terraform-specific hint for this situation
 			return nil, fmt.Errorf("no file exists at %s; this function works only with files that are distributed as part of the configuration source code, so if this file will be created by a resource in this configuration you must instead obtain this result from an attribute of that resource", path)
 		}
 		return nil, err
 	}
+	defer func() { _ = f.Close() }()
 
 	src, err := ioutil.ReadAll(f)
 	if err != nil {
@@ -387,6 +376,59 @@ func readFileBytes(baseDir, path string) ([]byte, error) {
 	return src, nil
 }
 
+// globFS returns the paths of all regular files within dir which match the given pattern, relative to dir
+func globFS(fsys fs.FS, dir string, pattern string) ([]string, error) {
+
+	// walk from the deepest directory which the pattern refers to literally, rather than the whole of dir
+	components := strings.Split(pattern, "/")
+	root := dir
+	for len(components) > 1 && !strings.ContainsAny(components[0], "*?[{\\") {
+		root = path.Join(root, components[0])
+		components = components[1:]
+	}
+
+	// without a ** the pattern can only match files at a fixed depth below the root
+	maxDepth := -1
+	if !strings.Contains(pattern, "**") {
+		maxDepth = len(components)
+	}
+
+	var matches []string
+	err := fs.WalkDir(fsys, root, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && current == root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if maxDepth >= 0 && current != root && strings.Count(strings.TrimPrefix(current, root+"/"), "/")+1 >= maxDepth {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel := strings.TrimPrefix(current, dir+"/")
+		if dir == "." {
+			rel = current
+		}
+		matched, err := doublestar.Match(pattern, rel)
+		if err != nil {
+			return err
+		}
+		if matched {
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
 // File reads the contents of the file at the given path.
 //
 // The file must contain valid UTF-8 bytes, or this function will return an error.
@@ -394,8 +436,8 @@ func readFileBytes(baseDir, path string) ([]byte, error) {
 // The underlying function implementation works relative to a particular base
 // directory, so this wrapper takes a base directory string and uses it to
 // construct the underlying function before calling it.
-func File(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileFunc(baseDir, false)
+func File(fsys fs.FS, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileFunc(fsys, baseDir, false)
 	return fn.Call([]cty.Value{path})
 }
 
@@ -404,8 +446,8 @@ func File(baseDir string, path cty.Value) (cty.Value, error) {
 // The underlying function implementation works relative to a particular base
 // directory, so this wrapper takes a base directory string and uses it to
 // construct the underlying function before calling it.
-func FileExists(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileExistsFunc(baseDir)
+func FileExists(fsys fs.FS, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileExistsFunc(fsys, baseDir)
 	return fn.Call([]cty.Value{path})
 }
 
@@ -414,8 +456,8 @@ func FileExists(baseDir string, path cty.Value) (cty.Value, error) {
 // The underlying function implementation works relative to a particular base
 // directory, so this wrapper takes a base directory string and uses it to
 // construct the underlying function before calling it.
-func FileSet(baseDir string, path, pattern cty.Value) (cty.Value, error) {
-	fn := MakeFileSetFunc(baseDir)
+func FileSet(fsys fs.FS, baseDir string, path, pattern cty.Value) (cty.Value, error) {
+	fn := MakeFileSetFunc(fsys, baseDir)
 	return fn.Call([]cty.Value{path, pattern})
 }
 
@@ -426,8 +468,8 @@ func FileSet(baseDir string, path, pattern cty.Value) (cty.Value, error) {
 // The underlying function implementation works relative to a particular base
 // directory, so this wrapper takes a base directory string and uses it to
 // construct the underlying function before calling it.
-func FileBase64(baseDir string, path cty.Value) (cty.Value, error) {
-	fn := MakeFileFunc(baseDir, true)
+func FileBase64(fsys fs.FS, baseDir string, path cty.Value) (cty.Value, error) {
+	fn := MakeFileFunc(fsys, baseDir, true)
 	return fn.Call([]cty.Value{path})
 }
 
@@ -3,6 +3,7 @@ package funcs
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -37,7 +38,7 @@ func TestFile(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("File(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := File(".", test.Path)
+			got, err := File(os.DirFS("."), ".", test.Path)
 
 			if test.Err {
 				if err == nil {
@@ -158,10 +159,10 @@ func TestTemplateFile(t *testing.T) {
 		},
 	}
 
-	templateFileFn := MakeTemplateFileFunc(".", func() map[string]function.Function {
+	templateFileFn := MakeTemplateFileFunc(os.DirFS("."), ".", func() map[string]function.Function {
 		return map[string]function.Function{
 			"join":         stdlib.JoinFunc,
-			"templatefile": MakeFileFunc(".", false), // just a placeholder, since templatefile itself overrides this
+			"templatefile": MakeFileFunc(os.DirFS("."), ".", false), // just a placeholder, since templatefile itself overrides this
 		}
 	})
 
@@ -219,7 +220,7 @@ func TestFileExists(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileExists(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := FileExists(".", test.Path)
+			got, err := FileExists(os.DirFS("."), ".", test.Path)
 
 			if test.Err {
 				if err == nil {
@@ -426,7 +427,7 @@ func TestFileSet(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileSet(\".\", %#v, %#v)", test.Path, test.Pattern), func(t *testing.T) {
-			got, err := FileSet(".", test.Path, test.Pattern)
+			got, err := FileSet(os.DirFS("."), ".", test.Path, test.Pattern)
 
 			if test.Err {
 				if err == nil {
@@ -469,7 +470,7 @@ func TestFileBase64(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("FileBase64(\".\", %#v)", test.Path), func(t *testing.T) {
-			got, err := FileBase64(".", test.Path)
+			got, err := FileBase64(os.DirFS("."), ".", test.Path)
 
 			if test.Err {
 				if err == nil {
@@ -0,0 +1,223 @@
+package gate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+)
+
+// Kind is what a threshold counts results by
+type Kind string
+
+const (
+	KindSeverity Kind = "severity"
+	KindProvider Kind = "provider"
+	KindRule     Kind = "rule"
+)
+
+// Threshold is the maximum number of failed results allowed for a severity, provider or rule
+type Threshold struct {
+	Kind Kind
+	// Key is the severity, provider or rule, where rules may be given by ID, legacy ID or pattern
+	Key string
+	Max int
+}
+
+func (t Threshold) String() string {
+	if t.Kind == KindSeverity {
+		return t.Key
+	}
+	return fmt.Sprintf("%s %s", t.Kind, t.Key)
+}
+
+// Policy decides whether a scan passes, from the number of failed results for each threshold
+type Policy struct {
+	thresholds []Threshold
+	legacyIDs  map[string]string
+}
+
+// New creates a policy from the gate in a config. Custom rules are given so that they can be matched by legacy ID.
+func New(gate config.Gate, customRules []rule.Rule) Policy {
+	policy := Policy{
+		legacyIDs: make(map[string]string),
+	}
+	for _, r := range append(scanner.GetRegisteredRules(), customRules...) {
+		policy.legacyIDs[r.ID()] = r.LegacyID
+	}
+	for _, sev := range []severity.Severity{severity.Critical, severity.High, severity.Medium, severity.Low} {
+		if max, ok := gate.Severities[string(sev)]; ok {
+			policy.thresholds = append(policy.thresholds, Threshold{Kind: KindSeverity, Key: string(sev), Max: max})
+		}
+	}
+	policy.thresholds = append(policy.thresholds, sortedThresholds(KindProvider, gate.Providers)...)
+	policy.thresholds = append(policy.thresholds, sortedThresholds(KindRule, gate.Rules)...)
+	return policy
+}
+
+func sortedThresholds(kind Kind, maxima map[string]int) []Threshold {
+	var thresholds []Threshold
+	for key, max := range maxima {
+		thresholds = append(thresholds, Threshold{Kind: kind, Key: key, Max: max})
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Key < thresholds[j].Key
+	})
+	return thresholds
+}
+
+// Override parses thresholds given on the command line and sets them on the gate, replacing any the gate already has for the same key.
+// Thresholds are given as SEVERITY=max, provider:NAME=max or rule:ID=max, e.g. CRITICAL=0, HIGH=5, provider:aws=10 or rule:aws-s3-*=0.
+func Override(gate config.Gate, overrides []string) (config.Gate, error) {
+	merged := config.Gate{
+		Severities: copyThresholds(gate.Severities),
+		Providers:  copyThresholds(gate.Providers),
+		Rules:      copyThresholds(gate.Rules),
+	}
+	for _, override := range overrides {
+		threshold, err := ParseThreshold(override)
+		if err != nil {
+			return gate, err
+		}
+		switch threshold.Kind {
+		case KindSeverity:
+			merged.Severities[threshold.Key] = threshold.Max
+		case KindProvider:
+			merged.Providers[threshold.Key] = threshold.Max
+		case KindRule:
+			merged.Rules[threshold.Key] = threshold.Max
+		}
+	}
+	return merged, nil
+}
+
+func copyThresholds(thresholds map[string]int) map[string]int {
+	copied := make(map[string]int)
+	for key, max := range thresholds {
+		copied[key] = max
+	}
+	return copied
+}
+
+// ParseThreshold parses a threshold given as SEVERITY=max, provider:NAME=max or rule:ID=max
+func ParseThreshold(value string) (Threshold, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "=", 2)
+	if len(parts) != 2 {
+		return Threshold{}, fmt.Errorf("invalid gate threshold '%s', expected e.g. HIGH=5, provider:aws=10 or rule:aws-s3-*=0", value)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || max < 0 {
+		return Threshold{}, fmt.Errorf("invalid gate threshold '%s', the maximum must be a whole number of zero or more", value)
+	}
+
+	key := strings.TrimSpace(parts[0])
+	threshold := Threshold{Kind: KindSeverity, Key: key, Max: max}
+	if kind := strings.SplitN(key, ":", 2); len(kind) == 2 {
+		threshold.Kind = Kind(strings.ToLower(kind[0]))
+		threshold.Key = kind[1]
+	}
+
+	switch threshold.Kind {
+	case KindSeverity:
+		sev := severity.StringToSeverity(threshold.Key)
+		if sev == severity.None {
+			return Threshold{}, fmt.Errorf("invalid gate threshold '%s', '%s' is not a severity", value, threshold.Key)
+		}
+		threshold.Key = string(sev)
+	case KindProvider:
+		threshold.Key = strings.ToLower(threshold.Key)
+	case KindRule:
+	default:
+		return Threshold{}, fmt.Errorf("invalid gate threshold '%s', expected severity, provider or rule but found '%s'", value, threshold.Kind)
+	}
+	return threshold, nil
+}
+
+// Check is the outcome of a single threshold
+type Check struct {
+	Threshold
+	Count int
+}
+
+// Passed returns true if the number of failed results is within the threshold
+func (c Check) Passed() bool {
+	return c.Count <= c.Max
+}
+
+// Outcome is the result of applying a policy to the results of a scan
+type Outcome struct {
+	Checks []Check
+}
+
+// Passed returns true if every threshold was met
+func (o Outcome) Passed() bool {
+	for _, check := range o.Checks {
+		if !check.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate counts the failed results for each threshold. Passed results are not counted, nor are results which were ignored or
+// excluded, as given by the suppressions of the scan, or the findings reported about ignores themselves.
+func (p Policy) Evaluate(results []rules.Result, suppressions scanner.Suppressions) Outcome {
+	outcome := Outcome{}
+	for _, threshold := range p.thresholds {
+		check := Check{Threshold: threshold}
+		for _, result := range results {
+			if counts(result, suppressions) && p.matches(threshold, result.Rule()) {
+				check.Count++
+			}
+		}
+		outcome.Checks = append(outcome.Checks, check)
+	}
+	return outcome
+}
+
+func counts(result rules.Result, suppressions scanner.Suppressions) bool {
+	if result.Status() != rules.StatusFailed || scanner.IsIgnoreFinding(result.Rule()) {
+		return false
+	}
+	return len(suppressions) == 0 || suppressions.Lookup(result) == nil
+}
+
+func (p Policy) matches(threshold Threshold, r rules.Rule) bool {
+	switch threshold.Kind {
+	case KindSeverity:
+		return string(r.Severity) == threshold.Key
+	case KindProvider:
+		return strings.EqualFold(string(r.Provider), threshold.Key)
+	case KindRule:
+		longID := r.LongID()
+		if scanner.MatchRuleID(threshold.Key, longID) {
+			return true
+		}
+		legacyID := p.legacyIDs[longID]
+		return legacyID != "" && scanner.MatchRuleID(threshold.Key, legacyID)
+	}
+	return false
+}
+
+// WriteSummary explains why the scan passed or failed, listing each threshold with the number of failed results counted against it
+func (o Outcome) WriteSummary(w io.Writer) {
+	if o.Passed() {
+		_, _ = fmt.Fprintln(w, "Gate passed: all thresholds were met")
+	} else {
+		_, _ = fmt.Fprintln(w, "Gate failed: one or more thresholds were exceeded")
+	}
+	for _, check := range o.Checks {
+		status := "ok"
+		if !check.Passed() {
+			status = "FAILED"
+		}
+		_, _ = fmt.Fprintf(w, "  %-6s %s: %d failed result(s), at most %d allowed\n", status, check.Threshold, check.Count, check.Max)
+	}
+}
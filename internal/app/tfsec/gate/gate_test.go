@@ -0,0 +1,160 @@
+package gate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aquasecurity/defsec/provider"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	_ "github.com/aquasecurity/tfsec/internal/app/tfsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	metadata types.Metadata
+}
+
+func (p *testProvider) GetMetadata() *types.Metadata {
+	return &p.metadata
+}
+
+func (p *testProvider) GetRawValue() interface{} {
+	return nil
+}
+
+func createResults(rule rules.Rule, failed int, passed int) rules.Results {
+	var results rules.Results
+	for i := 0; i < failed; i++ {
+		results.Add("failed", &testProvider{})
+	}
+	for i := 0; i < passed; i++ {
+		results.AddPassed(&testProvider{})
+	}
+	results.SetRule(rule)
+	return results
+}
+
+func Test_GateThresholds(t *testing.T) {
+	var results rules.Results
+	results = append(results, createResults(rules.Rule{Provider: provider.AWSProvider, Service: "s3", ShortCode: "enable-versioning", Severity: severity.Medium}, 3, 2)...)
+	results = append(results, createResults(rules.Rule{Provider: provider.GoogleProvider, Service: "gke", ShortCode: "enable-ip-aliasing", Severity: severity.High}, 2, 0)...)
+
+	tests := []struct {
+		name   string
+		gate   config.Gate
+		passed bool
+		counts []int
+	}{
+		{
+			name:   "no critical results allowed",
+			gate:   config.Gate{Severities: map[string]int{"CRITICAL": 0, "MEDIUM": 3}},
+			passed: true,
+			counts: []int{0, 3},
+		},
+		{
+			name:   "too many high results",
+			gate:   config.Gate{Severities: map[string]int{"HIGH": 1}},
+			passed: false,
+			counts: []int{2},
+		},
+		{
+			name:   "by provider",
+			gate:   config.Gate{Providers: map[string]int{"aws": 2, "google": 5}},
+			passed: false,
+			counts: []int{3, 2},
+		},
+		{
+			name:   "by rule pattern",
+			gate:   config.Gate{Rules: map[string]int{"aws-s3-*": 3, "google-gke-enable-ip-aliasing": 0}},
+			passed: false,
+			counts: []int{3, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outcome := New(test.gate, nil).Evaluate(results, nil)
+			assert.Equal(t, test.passed, outcome.Passed())
+			require.Len(t, outcome.Checks, len(test.counts))
+			for i, count := range test.counts {
+				assert.Equal(t, count, outcome.Checks[i].Count, outcome.Checks[i].Threshold.String())
+			}
+		})
+	}
+}
+
+func Test_GateOverrides(t *testing.T) {
+	gate, err := Override(config.Gate{Severities: map[string]int{"HIGH": 5, "LOW": 10}}, []string{"high=1", "provider:AWS=0", "rule:AWS002=2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"HIGH": 1, "LOW": 10}, gate.Severities)
+	assert.Equal(t, map[string]int{"aws": 0}, gate.Providers)
+	assert.Equal(t, map[string]int{"AWS002": 2}, gate.Rules)
+
+	for _, invalid := range []string{"HIGH", "HIGH=-1", "extreme=1", "module:foo=1"} {
+		_, err := Override(config.Gate{}, []string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func Test_GateSummary(t *testing.T) {
+	results := createResults(rules.Rule{Provider: provider.AWSProvider, Service: "s3", ShortCode: "enable-versioning", Severity: severity.High}, 1, 0)
+	outcome := New(config.Gate{Severities: map[string]int{"HIGH": 0}, Providers: map[string]int{"aws": 1}}, nil).Evaluate(results, nil)
+
+	buffer := bytes.NewBuffer(nil)
+	outcome.WriteSummary(buffer)
+	assert.Equal(t, `Gate failed: one or more thresholds were exceeded
+  FAILED HIGH: 1 failed result(s), at most 0 allowed
+  ok     provider aws: 1 failed result(s), at most 1 allowed
+`, buffer.String())
+}
+
+func Test_GateDoesNotCountSuppressedResults(t *testing.T) {
+	modules := testutil.CreateModulesFromSource(`
+resource "aws_s3_bucket" "public" {
+  #tfsec:ignore:aws-s3-no-public-access-with-acl
+  acl = "public-read"
+}
+
+resource "aws_instance" "unused" { #tfsec:ignore:aws-ec2-no-public-ip
+}
+`, ".tf", t)
+	results, suppressions, err := scanner.New(
+		scanner.OptionIncludeIgnored(),
+		scanner.OptionReportIgnores(),
+		scanner.OptionExcludeRules([]string{"aws-s3-enable-versioning"}),
+	).ScanWithSuppressions(modules)
+	require.NoError(t, err)
+
+	policy := New(config.Gate{
+		Providers: map[string]int{"general": 0},
+		Rules:     map[string]int{"aws-s3-no-public-access-with-acl": 0, "aws-s3-enable-versioning": 0},
+	}, nil)
+
+	var findings int
+	for _, result := range results {
+		if scanner.IsIgnoreFinding(result.Rule()) {
+			findings++
+		}
+	}
+	assert.Equal(t, 1, findings)
+
+	// the ignored and excluded results are included, so are counted without the suppressions, unlike the finding for the unused ignore
+	unsuppressed := policy.Evaluate(results, nil)
+	assert.False(t, unsuppressed.Passed())
+	require.Len(t, unsuppressed.Checks, 3)
+	for i, expected := range []int{0, 1, 1} {
+		assert.Equal(t, expected, unsuppressed.Checks[i].Count, unsuppressed.Checks[i].Threshold.String())
+	}
+
+	outcome := policy.Evaluate(results, suppressions)
+	assert.True(t, outcome.Passed())
+	for _, check := range outcome.Checks {
+		assert.Equal(t, 0, check.Count, check.Threshold.String())
+	}
+}
@@ -0,0 +1,247 @@
+package ignores
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+type additionStatistic struct {
+	Filename string
+	LineNo   int
+	RuleIDs  []string
+}
+
+type AdditionStatistics []*additionStatistic
+
+// insertion is an ignore comment to be added above a line of a file
+type insertion struct {
+	filename string
+	line     int
+	ruleIDs  []string
+}
+
+// AddIgnores inserts a tfsec:ignore comment above the code causing each failed result, so that the results are ignored by future scans.
+// Results within modules are ignored by a comment above the module call in the root module. Results for the same line, such as those
+// from count or for_each clones, share a single comment, and are added to any ignore comment already above the line. Results within
+// multi-line expressions, such as heredocs, are skipped. The expiry and reason are added to every comment if set.
+func AddIgnores(modules []block.Module, results rules.Results, expiry *time.Time, reason string) (AdditionStatistics, error) {
+	if strings.Contains(reason, `"`) {
+		return nil, fmt.Errorf("the reason cannot contain double quotes")
+	}
+
+	callSites := moduleCallSites(modules)
+	insertions := make(map[string]*insertion)
+	for _, result := range results {
+		if result.Status() == rules.StatusPassed {
+			continue
+		}
+		rng, ok := result.NarrowestRange().(block.HCLRange)
+		if !ok {
+			continue
+		}
+		filename, line := rng.GetFilename(), rng.GetStartLine()
+		if module := rng.GetModule(); module != "" && module != "root" {
+			callSite, ok := callSites[callName(module)]
+			if !ok {
+				debug.Log("No call site found for %s, so %s cannot be ignored", module, result.Rule().LongID())
+				continue
+			}
+			filename, line = callSite.GetFilename(), callSite.GetStartLine()
+		}
+		if filepath.Ext(filename) != ".tf" {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", filename, line)
+		if insertions[key] == nil {
+			insertions[key] = &insertion{filename: filename, line: line}
+		}
+		if !containsString(insertions[key].ruleIDs, result.Rule().LongID()) {
+			insertions[key].ruleIDs = append(insertions[key].ruleIDs, result.Rule().LongID())
+		}
+	}
+
+	byFile := make(map[string][]*insertion)
+	for _, ins := range insertions {
+		sort.Strings(ins.ruleIDs)
+		byFile[ins.filename] = append(byFile[ins.filename], ins)
+	}
+
+	var filenames []string
+	for filename := range byFile {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var stats AdditionStatistics
+	for _, filename := range filenames {
+		fileStats, err := addIgnoresToFile(filename, byFile[filename], expiry, reason)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, fileStats...)
+	}
+	return stats, nil
+}
+
+func addIgnoresToFile(filename string, insertions []*insertion, expiry *time.Time, reason string) (AdditionStatistics, error) {
+	debug.Log("Adding %d ignore comment(s) to %s", len(insertions), filename)
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+
+	// insert from the bottom of the file up, so the line numbers of the remaining insertions are unchanged
+	sort.Slice(insertions, func(i, j int) bool {
+		return insertions[i].line > insertions[j].line
+	})
+
+	continuations := continuationLines(filename, content)
+
+	var stats AdditionStatistics
+	for _, ins := range insertions {
+		if ins.line < 1 || ins.line > len(lines) {
+			continue
+		}
+		if continuations[ins.line] {
+			debug.Log("%s:%d is within a multi-line expression, so %s cannot be ignored", filename, ins.line, strings.Join(ins.ruleIDs, ", "))
+			continue
+		}
+		comment := ignoreComment(ins.ruleIDs, expiry, reason)
+		if ins.line > 1 && isIgnoreComment(lines[ins.line-2]) {
+			// an ignore comment above the line would no longer apply if it were moved up, so the new ignores are added to it instead
+			lines[ins.line-2] = mergeIgnoreComment(lines[ins.line-2], comment, reason != "")
+		} else {
+			target := lines[ins.line-1]
+			indent := target[:len(target)-len(strings.TrimLeft(target, " \t"))]
+			lines = append(lines[:ins.line-1], append([]string{indent + "#" + comment}, lines[ins.line-1:]...)...)
+		}
+		stats = append(stats, &additionStatistic{
+			Filename: filename,
+			LineNo:   ins.line,
+			RuleIDs:  ins.ruleIDs,
+		})
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")), info.Mode()&fs.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// report from the top of the file down
+	for i, j := 0, len(stats)-1; i < j; i, j = i+1, j-1 {
+		stats[i], stats[j] = stats[j], stats[i]
+	}
+	return stats, nil
+}
+
+// isIgnoreComment returns true if the line consists only of a comment containing tfsec ignores
+func isIgnoreComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return (strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//")) && strings.Contains(trimmed, "tfsec:ignore")
+}
+
+// mergeIgnoreComment adds the ignores to an existing ignore comment. As a reason applies to the ignores before it which do not
+// already have one, ignores with a reason are added first so the reason does not apply to the existing ignores, and those without
+// are added last so they do not take the reason of an existing ignore.
+func mergeIgnoreComment(line string, comment string, hasReason bool) string {
+	if !hasReason {
+		return strings.TrimRight(line, " \t") + " " + comment
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	existing := strings.TrimSpace(line)
+	marker := "#"
+	if strings.HasPrefix(existing, "//") {
+		marker = "//"
+	}
+	existing = strings.TrimPrefix(existing, marker)
+	body := strings.TrimLeft(existing, " ")
+	return indent + marker + existing[:len(existing)-len(body)] + comment + " " + body
+}
+
+// continuationLines returns the lines after the first line of each multi-line expression in the file, such as heredocs and lists
+// spread over several lines, where a comment cannot be inserted without changing or breaking the expression
+func continuationLines(filename string, content []byte) map[int]bool {
+	lines := make(map[int]bool)
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return lines
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return lines
+	}
+	var visit func(body *hclsyntax.Body)
+	visit = func(body *hclsyntax.Body) {
+		for _, attribute := range body.Attributes {
+			rng := attribute.Expr.Range()
+			for line := rng.Start.Line + 1; line <= rng.End.Line; line++ {
+				lines[line] = true
+			}
+		}
+		for _, b := range body.Blocks {
+			visit(b.Body)
+		}
+	}
+	visit(body)
+	return lines
+}
+
+func ignoreComment(ruleIDs []string, expiry *time.Time, reason string) string {
+	var parts []string
+	for _, id := range ruleIDs {
+		part := fmt.Sprintf("tfsec:ignore:%s", id)
+		if expiry != nil {
+			part += fmt.Sprintf(":exp:%s", expiry.Format("2006-01-02"))
+		}
+		parts = append(parts, part)
+	}
+	if reason != "" {
+		parts = append(parts, fmt.Sprintf(`reason="%s"`, reason))
+	}
+	return strings.Join(parts, " ")
+}
+
+// moduleCallSites returns the ranges of the module blocks in the root module, keyed by module name
+func moduleCallSites(modules []block.Module) map[string]block.HCLRange {
+	callSites := make(map[string]block.HCLRange)
+	for _, module := range modules {
+		for _, b := range module.GetBlocks().OfType("module") {
+			if b.Range().GetModule() != "root" {
+				continue
+			}
+			callSites[b.Label()] = b.Range()
+		}
+	}
+	return callSites
+}
+
+// callName returns the name of the module called from the root module, given the full name of a module such as module.network[0]:module.subnets
+func callName(module string) string {
+	name := strings.SplitN(module, ":", 2)[0]
+	name = strings.TrimPrefix(name, "module.")
+	return strings.SplitN(name, "[", 2)[0]
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
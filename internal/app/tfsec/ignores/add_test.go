@@ -0,0 +1,152 @@
+package ignores
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
+	_ "github.com/aquasecurity/tfsec/internal/app/tfsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AddIgnores(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `
+module "network" {
+  source = "./network"
+}
+
+resource "aws_s3_bucket" "logs" {
+  count = 2
+  acl   = "public-read"
+}
+`)
+	writeFile(t, filepath.Join(dir, "network", "main.tf"), `
+resource "aws_s3_bucket" "keys" {
+  acl = "public-read"
+}
+`)
+
+	modules, results := scan(t, dir)
+	require.NotEmpty(t, results)
+
+	expiry := time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+	stats, err := AddIgnores(modules, results, &expiry, "legacy repository")
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+	assert.Equal(t, 2, stats[0].LineNo)
+	assert.Equal(t, 6, stats[1].LineNo)
+	assert.Equal(t, 8, stats[2].LineNo)
+	assert.Contains(t, stats[2].RuleIDs, "aws-s3-no-public-access-with-acl")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "\n  #tfsec:ignore:aws-s3-no-public-access-with-acl:exp:"+expiry.Format("2006-01-02")+" reason=\"legacy repository\"\n  acl   = \"public-read\"\n")
+
+	_, results = scan(t, dir)
+	assert.Empty(t, results)
+}
+
+func Test_AddIgnoresMergesWithExistingIgnores(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `
+#tfsec:ignore:aws-s3-enable-versioning
+module "network" {
+  source = "./network"
+}
+
+resource "aws_s3_bucket" "logs" {
+  # tfsec:ignore:aws-s3-enable-bucket-logging
+  acl = "public-read"
+}
+`)
+	writeFile(t, filepath.Join(dir, "network", "main.tf"), `
+resource "aws_s3_bucket" "keys" {
+  acl = "public-read"
+}
+`)
+
+	modules, results := scan(t, dir)
+	require.NotEmpty(t, results)
+
+	stats, err := AddIgnores(modules, results, nil, "legacy repository")
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	lines := strings.Split(string(content), "\n")
+	// the existing ignores are not moved away from the lines they apply to
+	assert.True(t, strings.HasPrefix(lines[1], "#tfsec:ignore:"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[1], ` reason="legacy repository" tfsec:ignore:aws-s3-enable-versioning`), lines[1])
+	assert.Equal(t, `module "network" {`, lines[2])
+	// the results for the bucket itself are ignored by a new comment, as there was none above it
+	assert.True(t, strings.HasPrefix(lines[6], "#tfsec:ignore:"), lines[6])
+	assert.Equal(t, `resource "aws_s3_bucket" "logs" {`, lines[7])
+	assert.Equal(t, `  # tfsec:ignore:aws-s3-no-public-access-with-acl reason="legacy repository" tfsec:ignore:aws-s3-enable-bucket-logging`, lines[8])
+	assert.Equal(t, `  acl = "public-read"`, lines[9])
+
+	_, results = scan(t, dir)
+	assert.Empty(t, results)
+}
+
+func Test_MergeIgnoreComment(t *testing.T) {
+	// without a reason, the new ignores are added last so they do not take the reason of an existing ignore
+	assert.Equal(t, `  // tfsec:ignore:first reason="old" tfsec:ignore:second`, mergeIgnoreComment(`  // tfsec:ignore:first reason="old"`, "tfsec:ignore:second", false))
+	// with a reason, they are added first so the reason does not apply to an existing ignore without one
+	assert.Equal(t, `  #tfsec:ignore:second reason="new" tfsec:ignore:first`, mergeIgnoreComment(`  #tfsec:ignore:first`, `tfsec:ignore:second reason="new"`, true))
+}
+
+func Test_AddIgnoresSkipsMultiLineExpressions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	source := `
+resource "aws_s3_bucket" "logs" {
+  policy = <<EOF
+{"Statement": []}
+EOF
+  cidr_blocks = [
+    "0.0.0.0/0",
+  ]
+}
+`
+	writeFile(t, filename, source)
+
+	stats, err := addIgnoresToFile(filename, []*insertion{
+		{filename: filename, line: 4, ruleIDs: []string{"aws-s3-enable-versioning"}},
+		{filename: filename, line: 7, ruleIDs: []string{"aws-vpc-no-public-ingress-sgr"}},
+		{filename: filename, line: 6, ruleIDs: []string{"aws-vpc-no-public-ingress-sgr"}},
+	}, nil, "")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 6, stats[0].LineNo)
+
+	content, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(source, "  cidr_blocks", "  #tfsec:ignore:aws-vpc-no-public-ingress-sgr\n  cidr_blocks", 1), string(content))
+}
+
+func Test_AddIgnoresRejectsQuotesInReason(t *testing.T) {
+	_, err := AddIgnores(nil, nil, nil, `the "old" bucket`)
+	assert.Error(t, err)
+}
+
+func scan(t *testing.T, dir string) ([]block.Module, rules.Results) {
+	modules, err := parser.New(dir, parser.OptionStopOnHCLError()).ParseDirectory()
+	require.NoError(t, err)
+	results, err := scanner.New().Scan(modules)
+	require.NoError(t, err)
+	return modules, results
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+}
@@ -105,7 +105,7 @@ func getCodeMappings() map[string]string {
 	rules := scanner.GetRegisteredRules()
 	for _, r := range rules {
 		if r.LegacyID != "" {
-			legacyMapping[r.LegacyID] = r.Base.Rule().LongID()
+			legacyMapping[r.LegacyID] = r.ID()
 		}
 	}
 	return legacyMapping
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
@@ -12,8 +11,6 @@ import (
 	"github.com/zclconf/go-cty/cty/gocty"
 )
 
-const maxContextIterations = 32
-
 type visitedModule struct {
 	name                string
 	path                string
@@ -34,6 +31,17 @@ type Evaluator struct {
 	workingDir        string
 	workspace         string
 	ignores           block.Ignores
+	diagnostics       hcl.Diagnostics
+	workers           int
+	files             *fileSet
+	filesystem        filesystem
+	debug             debug.Logger
+	// expandedDynamic holds the dynamic blocks whose content has been injected, as blocks are expanded more than once
+	expandedDynamic map[block.Block]bool
+	// cache restores the values of unchanged modules, if a ValueCache was provided
+	cache *moduleCache
+	// treeDirs holds the hashes of the directories of this module and all of its children, once evaluated
+	treeDirs map[string]string
 }
 
 func NewEvaluator(
@@ -48,16 +56,20 @@ func NewEvaluator(
 	stopOnHCLError bool,
 	workspace string,
 	ignores []block.Ignore,
+	workers int,
+	files *fileSet,
+	fsys filesystem,
+	logger debug.Logger,
 ) *Evaluator {
 
 	ctx := block.NewContext(&hcl.EvalContext{
-		Functions: Functions(modulePath),
+		Functions: Functions(fsys.functionScope(modulePath)),
 	}, nil)
 
 	ctx.SetByDot(cty.StringVal(workspace), "terraform.workspace")
-	ctx.SetByDot(cty.StringVal(projectRootPath), "path.root")
-	ctx.SetByDot(cty.StringVal(modulePath), "path.module")
-	ctx.SetByDot(cty.StringVal(workingDir), "path.cwd")
+	ctx.SetByDot(cty.StringVal(fsys.pathValue(projectRootPath)), "path.root")
+	ctx.SetByDot(cty.StringVal(fsys.pathValue(modulePath)), "path.module")
+	ctx.SetByDot(cty.StringVal(fsys.pathValue(workingDir)), "path.cwd")
 
 	for _, b := range blocks {
 		b.OverrideContext(ctx.NewChild())
@@ -76,68 +88,160 @@ func NewEvaluator(
 		stopOnHCLError:  stopOnHCLError,
 		workspace:       workspace,
 		ignores:         ignores,
+		workers:         workers,
+		files:           files,
+		filesystem:      fsys,
+		debug:           logger,
+		expandedDynamic: make(map[block.Block]bool),
 	}
 }
 
-func (e *Evaluator) evaluateStep(i int) {
+// evaluateGraph resolves every referenceable block in dependency order, so each block is evaluated once, after everything it refers to.
+// The graph is evaluated again once blocks have been expanded, so cycles are only reported when reportCycles is set.
+func (e *Evaluator) evaluateGraph(reportCycles bool) {
 
 	evalTimer := metrics.Timer("timings", "evaluation")
 	evalTimer.Start()
-	debug.Log("Starting iteration %d of context evaluation...", i+1)
-
-	e.ctx.Set(e.getValuesByBlockType("variable"), "var")
-	e.ctx.Set(e.getValuesByBlockType("locals"), "local")
-	e.ctx.Set(e.getValuesByBlockType("provider"), "provider")
+	defer evalTimer.Stop()
 
-	resources := e.getValuesByBlockType("resource")
-	for key, resource := range resources.AsValueMap() {
-		e.ctx.Set(resource, key)
+	for _, namespace := range []string{"var", "local", "provider", "data", "output"} {
+		if e.ctx.Get(namespace) == cty.NilVal {
+			e.ctx.Set(cty.EmptyObjectVal, namespace)
+		}
 	}
 
-	e.ctx.Set(e.getValuesByBlockType("data"), "data")
-	e.ctx.Set(e.getValuesByBlockType("output"), "output")
+	levels, diags := newDependencyGraph(e.blocks).levels()
+	if reportCycles {
+		e.addDiagnostics(diags)
+	}
 
-	evalTimer.Stop()
+	for i, nodes := range levels {
+		e.debug.Log("Evaluating %d nodes at dependency level %d...", len(nodes), i)
+		var modules []*ModuleDefinition
+		for _, node := range nodes {
+			modules = append(modules, e.evaluateNode(node)...)
+		}
+		// module calls at the same level cannot depend on each other, so they can be evaluated concurrently
+		e.evaluateModules(modules)
+	}
 
-	e.evaluateModules()
+	e.evaluateModules(e.moduleDefinitions)
 }
 
-func (e *Evaluator) evaluateModules() {
-
-	for _, module := range e.moduleDefinitions {
-		if visited := func(module *ModuleDefinition) bool {
-			for _, v := range e.visitedModules {
-				if v.name == module.Name && v.path == module.Path && module.Definition.Reference().String() == v.definitionReference {
-					debug.Log("Module [%s:%s:%s] has already been seen", v.name, v.path, v.definitionReference)
-					return true
+// evaluateNode sets the values of the node in the evaluation context, returning any module definitions which it calls
+func (e *Evaluator) evaluateNode(node *graphNode) []*ModuleDefinition {
+	for _, attr := range node.attributes {
+		e.ctx.Set(attr.Value(), "local", attr.Name())
+	}
+	if node.attributes != nil {
+		return nil
+	}
+	var modules []*ModuleDefinition
+	for _, b := range node.blocks {
+		switch b.Type() {
+		case "variable":
+			if val, err := e.evaluateVariable(b); err == nil {
+				e.ctx.Set(val, "var", b.Label())
+			}
+		case "output":
+			if val, err := e.evaluateOutput(b); err == nil {
+				e.ctx.Set(val, "output", b.Label())
+			}
+		case "provider":
+			e.ctx.Set(b.Values(), "provider", b.Label())
+		case "resource":
+			e.ctx.Set(b.Values(), b.TypeLabel(), b.NameLabel())
+		case "data":
+			e.ctx.Set(b.Values(), "data", b.TypeLabel(), b.NameLabel())
+		case "module":
+			for _, definition := range e.moduleDefinitions {
+				if definition.Definition.Reference().NameLabel() == b.Reference().NameLabel() {
+					modules = append(modules, definition)
 				}
 			}
-			return false
-		}(module); visited {
-			continue
 		}
+	}
+	return modules
+}
 
-		e.visitedModules = append(e.visitedModules, &visitedModule{module.Name, module.Path, module.Definition.Reference().String()})
+type moduleEvaluation struct {
+	definition *ModuleDefinition
+	evaluator  *Evaluator
+}
+
+// evaluateModules evaluates the given module calls concurrently and exports their outputs. Modules which have already been evaluated are skipped.
+func (e *Evaluator) evaluateModules(modules []*ModuleDefinition) {
 
-		evalTimer := metrics.Timer("timings", "evaluation")
-		evalTimer.Start()
-		vars := module.Definition.Values().AsValueMap()
+	var pending []moduleEvaluation
 
-		moduleIgnores := module.Modules[0].Ignores()
-		if ignore := e.ignores.Covering(module.Definition.Range(), e.workspace); ignore != nil {
-			moduleIgnore := *ignore
-			moduleIgnore.ModuleKey = module.Definition.FullName()
-			moduleIgnores = append(moduleIgnores, moduleIgnore)
+	// inputs are resolved up front, as the parent context must not be read while the modules are being evaluated
+	for _, module := range modules {
+		if e.isVisited(module) {
+			continue
 		}
+		e.visitedModules = append(e.visitedModules, &visitedModule{module.Name, module.Path, module.Definition.Reference().String()})
+		pending = append(pending, moduleEvaluation{
+			definition: module,
+			evaluator:  e.newModuleEvaluator(module),
+		})
+	}
 
-		moduleEvaluator := NewEvaluator(e.projectRootPath, module.Path, e.workingDir, module.Definition.FullName(), module.Modules[0].GetBlocks(), vars, e.moduleMetadata, e.visitedModules, e.stopOnHCLError, e.workspace, moduleIgnores)
-		module.Modules, _ = moduleEvaluator.EvaluateAll()
+	if len(pending) == 0 {
+		return
+	}
+
+	evalTimer := metrics.Timer("timings", "evaluation")
+	evalTimer.Start()
+	defer evalTimer.Stop()
+
+	runConcurrently(e.workers, len(pending), func(i int) {
+		pending[i].definition.Modules, _ = pending[i].evaluator.EvaluateAll()
+	})
+
+	for _, evaluation := range pending {
+		e.addDiagnostics(evaluation.evaluator.Diagnostics())
+		for dir, hash := range evaluation.evaluator.treeDirs {
+			e.treeDirs[dir] = hash
+		}
 		// export module outputs
-		e.ctx.Set(moduleEvaluator.ExportOutputs(), "module", module.Name)
-		evalTimer.Stop()
+		e.ctx.Set(evaluation.evaluator.ExportOutputs(), "module", evaluation.definition.Name)
 	}
 }
 
+func (e *Evaluator) isVisited(module *ModuleDefinition) bool {
+	for _, v := range e.visitedModules {
+		if v.name == module.Name && v.path == module.Path && module.Definition.Reference().String() == v.definitionReference {
+			e.debug.Log("Module [%s:%s:%s] has already been seen", v.name, v.path, v.definitionReference)
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) newModuleEvaluator(module *ModuleDefinition) *Evaluator {
+
+	vars := module.Definition.Values().AsValueMap()
+
+	moduleIgnores := module.Modules[0].Ignores()
+	// every ignore covering the module call applies to the whole module, except file ignores in the calling file
+	for _, ignore := range e.ignores {
+		if ignore.Scope == block.IgnoreScopeFile || !ignore.Covering(module.Definition.Range(), e.workspace) {
+			continue
+		}
+		moduleIgnore := ignore
+		moduleIgnore.ModuleKey = module.Definition.FullName()
+		moduleIgnores = append(moduleIgnores, moduleIgnore)
+	}
+
+	// each child gets its own copy of the visited modules, so concurrent evaluations never append to a shared slice
+	visited := make([]*visitedModule, len(e.visitedModules))
+	copy(visited, e.visitedModules)
+
+	evaluator := NewEvaluator(e.projectRootPath, module.Path, e.workingDir, module.Definition.FullName(), module.Modules[0].GetBlocks(), vars, e.moduleMetadata, visited, e.stopOnHCLError, e.workspace, moduleIgnores, e.workers, e.files, e.filesystem, e.debug)
+	evaluator.cache = e.cache
+	return evaluator
+}
+
 // export module outputs to a parent
 func (e *Evaluator) ExportOutputs() cty.Value {
 	data := make(map[string]cty.Value)
@@ -153,56 +257,68 @@ func (e *Evaluator) ExportOutputs() cty.Value {
 
 func (e *Evaluator) EvaluateAll() ([]block.Module, error) {
 
-	var lastContext hcl.EvalContext
+	var cacheKey string
+	e.treeDirs = make(map[string]string)
+	if e.cache != nil {
+		key, dirs, err := e.cache.key(e)
+		if err != nil {
+			e.debug.Log("Values of module '%s' cannot be cached: %s", e.moduleName, err)
+		} else if variables, treeDirs, ok := e.cache.load(key); ok {
+			e.debug.Log("Restored values of module '%s' from cache", e.moduleName)
+			e.ctx.Inner().Variables = variables
+			e.treeDirs = treeDirs
+			return e.evaluateCached()
+		} else {
+			cacheKey = key
+			e.treeDirs = dirs
+		}
+	}
 
-	for i := 0; i < maxContextIterations; i++ {
+	e.evaluateGraph(false)
 
-		e.evaluateStep(i)
+	e.debug.Log("Loading modules...")
+	e.moduleDefinitions = e.loadModules(true)
 
-		// if ctx matches the last evaluation, we can bail, nothing left to resolve
-		if i > 0 && reflect.DeepEqual(lastContext.Variables, e.ctx.Inner().Variables) {
-			break
-		}
+	// expand out resources and modules via count
+	e.blocks = e.expandBlocks(e.blocks)
+	e.blocks = e.expandBlocks(e.blocks)
 
-		if len(e.ctx.Inner().Variables) != len(lastContext.Variables) {
-			lastContext.Variables = make(map[string]cty.Value, len(e.ctx.Inner().Variables))
-		}
-		for k, v := range e.ctx.Inner().Variables {
-			lastContext.Variables[k] = v
+	e.evaluateGraph(true)
+
+	if cacheKey != "" {
+		if err := e.cache.save(cacheKey, e.ctx.Inner().Variables, e.treeDirs); err != nil {
+			e.debug.Log("Values of module '%s' were not cached: %s", e.moduleName, err)
 		}
 	}
 
-	debug.Log("Loading modules...")
+	return e.modules(), nil
+}
+
+// evaluateCached completes the evaluation of a module whose values were restored from the cache. Blocks still need to be expanded,
+// and child modules loaded, but nothing needs to be evaluated other than any children whose own values are not cached.
+func (e *Evaluator) evaluateCached() ([]block.Module, error) {
+
 	e.moduleDefinitions = e.loadModules(true)
 
-	// expand out resources and modules via count
 	e.blocks = e.expandBlocks(e.blocks)
 	e.blocks = e.expandBlocks(e.blocks)
 
-	for i := 0; i < maxContextIterations; i++ {
+	e.evaluateModules(e.moduleDefinitions)
 
-		e.evaluateStep(i)
+	_, diags := newDependencyGraph(e.blocks).levels()
+	e.addDiagnostics(diags)
 
-		// if ctx matches the last evaluation, we can bail, nothing left to resolve
-		if i > 0 && reflect.DeepEqual(lastContext.Variables, e.ctx.Inner().Variables) {
-			break
-		}
+	return e.modules(), nil
+}
 
-		if len(e.ctx.Inner().Variables) != len(lastContext.Variables) {
-			lastContext.Variables = make(map[string]cty.Value, len(e.ctx.Inner().Variables))
-		}
-		for k, v := range e.ctx.Inner().Variables {
-			lastContext.Variables[k] = v
-		}
-	}
+func (e *Evaluator) modules() []block.Module {
 
 	var modules []block.Module
 	modules = append(modules, block.NewHCLModule(e.projectRootPath, e.modulePath, e.blocks, e.ignores))
 	for _, definition := range e.moduleDefinitions {
 		modules = append(modules, definition.Modules...)
 	}
-
-	return modules, nil
+	return modules
 }
 
 func (e *Evaluator) expandBlocks(blocks block.Blocks) block.Blocks {
@@ -221,6 +337,10 @@ func (e *Evaluator) expandDynamicBlock(b block.Block) {
 		e.expandDynamicBlock(sub)
 	}
 	for _, sub := range b.AllBlocks().OfType("dynamic") {
+		if e.expandedDynamic[sub] {
+			continue
+		}
+		e.expandedDynamic[sub] = true
 		blockName := sub.TypeLabel()
 		expanded := e.expandBlockForEaches([]block.Block{sub})
 		for _, ex := range expanded {
@@ -266,7 +386,7 @@ func (e *Evaluator) expandBlockForEaches(blocks block.Blocks) block.Blocks {
 				ctx.Set(key, block.TypeLabel(), "key")
 				ctx.Set(val, block.TypeLabel(), "value")
 
-				debug.Log("Added %s from for_each", clone.Reference())
+				e.debug.Log("Added %s from for_each", clone.Reference())
 				forEachFiltered = append(forEachFiltered, clone)
 
 				clones = append(clones, clone.Values())
@@ -305,7 +425,7 @@ func (e *Evaluator) expandBlockCounts(blocks block.Blocks) block.Blocks {
 			clone := block.Clone(c)
 			clones = append(clones, clone.Values())
 			block.TypeLabel()
-			debug.Log("Added %s from count var", clone.Reference())
+			e.debug.Log("Added %s from count var", clone.Reference())
 			countFiltered = append(countFiltered, clone)
 			e.ctx.SetByDot(clone.Values(), clone.Reference().String())
 		}
@@ -341,7 +461,7 @@ func (e *Evaluator) copyVariables(from, to block.Block) {
 
 	srcValue := e.ctx.Root().Get(fromBase, fromRel)
 	if srcValue == cty.NilVal {
-		debug.Log("error trying to copy variable from the source of '%s.%s'", fromBase, fromRel)
+		e.debug.Log("error trying to copy variable from the source of '%s.%s'", fromBase, fromRel)
 		return
 	}
 	e.ctx.Root().Set(srcValue, fromBase, toRel)
@@ -378,59 +498,11 @@ func (e *Evaluator) evaluateOutput(b block.Block) (cty.Value, error) {
 	return attribute.Value(), nil
 }
 
-// returns true if all evaluations were successful
-func (e *Evaluator) getValuesByBlockType(blockType string) cty.Value {
-
-	blocksOfType := e.blocks.OfType(blockType)
-	values := make(map[string]cty.Value)
-
-	for _, b := range blocksOfType {
-
-		switch b.Type() {
-		case "variable": // variables are special in that their value comes from the "default" attribute
-			val, err := e.evaluateVariable(b)
-			if err != nil {
-				continue
-			}
-			values[b.Label()] = val
-		case "output":
-			val, err := e.evaluateOutput(b)
-			if err != nil {
-				continue
-			}
-			values[b.Label()] = val
-		case "locals", "moved":
-			for key, val := range b.Values().AsValueMap() {
-				values[key] = val
-			}
-		case "provider", "module":
-			if b.Label() == "" {
-				continue
-			}
-			values[b.Label()] = b.Values()
-		case "resource", "data":
-
-			if len(b.Labels()) < 2 {
-				continue
-			}
-
-			blockMap, ok := values[b.Label()]
-			if !ok {
-				values[b.Labels()[0]] = cty.ObjectVal(make(map[string]cty.Value))
-				blockMap = values[b.Labels()[0]]
-			}
-
-			valueMap := blockMap.AsValueMap()
-			if valueMap == nil {
-				valueMap = make(map[string]cty.Value)
-			}
-
-			valueMap[b.Labels()[1]] = b.Values()
-			values[b.Labels()[0]] = cty.ObjectVal(valueMap)
-		}
-
-	}
-
-	return cty.ObjectVal(values)
+// Diagnostics returns any problems found while evaluating the module and its children, such as reference cycles
+func (e *Evaluator) Diagnostics() hcl.Diagnostics {
+	return e.diagnostics
+}
 
+func (e *Evaluator) addDiagnostics(diags hcl.Diagnostics) {
+	e.diagnostics = append(e.diagnostics, diags...)
 }
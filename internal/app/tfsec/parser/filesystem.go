@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// filesystem gives the parser access to the files being scanned, which are read from disk unless an fs.FS was provided with OptionWithFS.
+// Paths passed to its methods are the paths used by the parser, e.g. in block ranges, rather than paths within the fs.FS.
+type filesystem struct {
+	target fs.FS
+}
+
+func (f filesystem) readDir(dir string) ([]fs.DirEntry, error) {
+	if f.target == nil {
+		return os.ReadDir(dir)
+	}
+	return fs.ReadDir(f.target, f.path(dir))
+}
+
+func (f filesystem) readFile(filename string) ([]byte, error) {
+	if f.target == nil {
+		return ioutil.ReadFile(filename)
+	}
+	return fs.ReadFile(f.target, f.path(filename))
+}
+
+// path converts a path used by the parser into a path within the fs.FS, where absolute paths are treated as relative to its root
+func (f filesystem) path(p string) string {
+	cleaned := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+	if cleaned == "" {
+		return "."
+	}
+	return cleaned
+}
+
+// pathValue returns the value exposed to terraform code as path.root, path.module and path.cwd for the given directory
+func (f filesystem) pathValue(dir string) string {
+	if f.target == nil {
+		return dir
+	}
+	return path.Join("/", f.path(dir))
+}
+
+// functionScope returns the fs.FS and base directory used by file functions, such as file() and fileset(), evaluated within the given directory
+func (f filesystem) functionScope(dir string) (fs.FS, string) {
+	if f.target != nil {
+		return f.target, f.path(dir)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	volume := filepath.VolumeName(dir)
+	return os.DirFS(volume + string(filepath.Separator)), f.path(strings.TrimPrefix(dir, volume))
+}
+
+// workingDir returns the directory exposed to terraform code as path.cwd, which is the root of the fs.FS if one was provided
+func (f filesystem) workingDir() string {
+	if f.target == nil {
+		wd, _ := os.Getwd()
+		return wd
+	}
+	return "."
+}
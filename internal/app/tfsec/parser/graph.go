@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// graphNode is a single referenceable item within a module, e.g. "var.region" or "aws_s3_bucket.logs".
+// Expanded (count/for_each) copies of a block share a node, as do the keys of all locals blocks with the same name.
+type graphNode struct {
+	key        string
+	blocks     block.Blocks
+	attributes []block.Attribute // only used for locals, where each attribute is its own node
+	dependsOn  []string
+}
+
+type dependencyGraph struct {
+	nodes map[string]*graphNode
+	order []string // insertion order, used to keep evaluation deterministic
+}
+
+func newDependencyGraph(blocks block.Blocks) *dependencyGraph {
+	g := &dependencyGraph{
+		nodes: make(map[string]*graphNode),
+	}
+
+	for _, b := range blocks {
+		switch b.Type() {
+		case "locals":
+			for _, attr := range b.GetAttributes() {
+				node := g.node(fmt.Sprintf("local.%s", attr.Name()))
+				node.blocks = append(node.blocks, b)
+				node.attributes = append(node.attributes, attr)
+			}
+		default:
+			if key := nodeKeyForBlock(b); key != "" {
+				node := g.node(key)
+				node.blocks = append(node.blocks, b)
+			}
+		}
+	}
+
+	for _, key := range g.order {
+		node := g.nodes[key]
+		var traversals []hcl.Traversal
+		if node.attributes != nil {
+			for _, attr := range node.attributes {
+				traversals = append(traversals, attr.Traversals()...)
+			}
+		} else {
+			for _, b := range node.blocks {
+				traversals = append(traversals, blockTraversals(b)...)
+			}
+		}
+		seen := make(map[string]bool)
+		for _, traversal := range traversals {
+			dep := nodeKeyForTraversal(traversal)
+			if dep == "" || dep == key || seen[dep] {
+				continue
+			}
+			if _, exists := g.nodes[dep]; !exists {
+				continue
+			}
+			seen[dep] = true
+			node.dependsOn = append(node.dependsOn, dep)
+		}
+	}
+
+	return g
+}
+
+func (g *dependencyGraph) node(key string) *graphNode {
+	if node, exists := g.nodes[key]; exists {
+		return node
+	}
+	node := &graphNode{key: key}
+	g.nodes[key] = node
+	g.order = append(g.order, key)
+	return node
+}
+
+// sort returns the nodes of the graph such that every node appears after all of the nodes it depends on.
+// Any cycles found are reported as diagnostics; the nodes involved are still returned so they can be evaluated on a best-effort basis.
+func (g *dependencyGraph) sort() ([]*graphNode, hcl.Diagnostics) {
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var sorted []*graphNode
+	var diags hcl.Diagnostics
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(key string)
+	visit = func(key string) {
+		switch state[key] {
+		case visited:
+			return
+		case visiting:
+			diags = append(diags, g.cycleDiagnostic(stack, key))
+			return
+		}
+		state[key] = visiting
+		stack = append(stack, key)
+		for _, dep := range g.nodes[key].dependsOn {
+			visit(dep)
+		}
+		stack = stack[:len(stack)-1]
+		state[key] = visited
+		sorted = append(sorted, g.nodes[key])
+	}
+
+	for _, key := range g.order {
+		visit(key)
+	}
+
+	return sorted, diags
+}
+
+// levels groups the sorted nodes so that no node depends on another node in the same group.
+// Every node in a group only depends on nodes in earlier groups, so the nodes within a group can be evaluated independently.
+func (g *dependencyGraph) levels() ([][]*graphNode, hcl.Diagnostics) {
+	sorted, diags := g.sort()
+
+	depths := make(map[string]int)
+	var levels [][]*graphNode
+	for _, node := range sorted {
+		var depth int
+		for _, dep := range node.dependsOn {
+			// dependencies which are part of a cycle will not have a depth yet, so they are skipped
+			if depDepth, ok := depths[dep]; ok && depDepth+1 > depth {
+				depth = depDepth + 1
+			}
+		}
+		depths[node.key] = depth
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], node)
+	}
+
+	return levels, diags
+}
+
+func (g *dependencyGraph) cycleDiagnostic(stack []string, key string) *hcl.Diagnostic {
+	var start int
+	for i, item := range stack {
+		if item == key {
+			start = i
+			break
+		}
+	}
+	cycle := append(append([]string{}, stack[start:]...), key)
+
+	diag := &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Cycle detected",
+		Detail:   fmt.Sprintf("References form a cycle and may not be fully resolved: %s", strings.Join(cycle, " -> ")),
+	}
+	if node := g.nodes[key]; len(node.blocks) > 0 {
+		rng := node.blocks[0].Range()
+		diag.Subject = &hcl.Range{
+			Filename: rng.GetFilename(),
+			Start:    hcl.Pos{Line: rng.GetStartLine()},
+			End:      hcl.Pos{Line: rng.GetEndLine()},
+		}
+	}
+	return diag
+}
+
+func nodeKeyForBlock(b block.Block) string {
+	ref := b.Reference()
+	switch b.Type() {
+	case "variable":
+		return fmt.Sprintf("var.%s", b.Label())
+	case "output", "provider":
+		if b.Label() == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s.%s", b.Type(), b.Label())
+	case "module":
+		if ref.NameLabel() == "" {
+			return ""
+		}
+		return fmt.Sprintf("module.%s", ref.NameLabel())
+	case "resource":
+		if len(b.Labels()) < 2 {
+			return ""
+		}
+		return fmt.Sprintf("%s.%s", ref.TypeLabel(), ref.NameLabel())
+	case "data":
+		if len(b.Labels()) < 2 {
+			return ""
+		}
+		return fmt.Sprintf("data.%s.%s", ref.TypeLabel(), ref.NameLabel())
+	}
+	return ""
+}
+
+func nodeKeyForTraversal(traversal hcl.Traversal) string {
+	// only the leading names are needed to identify a node - anything after an index step is irrelevant
+	var names []string
+steps:
+	for _, step := range traversal {
+		switch part := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, part.Name)
+		case hcl.TraverseAttr:
+			names = append(names, part.Name)
+		default:
+			break steps
+		}
+		if len(names) == 3 {
+			break
+		}
+	}
+	if len(names) < 2 {
+		return ""
+	}
+	switch names[0] {
+	case "var", "local", "module":
+		return fmt.Sprintf("%s.%s", names[0], names[1])
+	case "data":
+		if len(names) < 3 {
+			return ""
+		}
+		return fmt.Sprintf("data.%s.%s", names[1], names[2])
+	case "each", "count", "self", "path", "terraform":
+		return ""
+	}
+	return fmt.Sprintf("%s.%s", names[0], names[1])
+}
+
+// blockTraversals returns all traversals referenced by the attributes of a block and its nested blocks
+func blockTraversals(b block.Block) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	for _, attr := range b.GetAttributes() {
+		traversals = append(traversals, attr.Traversals()...)
+	}
+	for _, child := range b.AllBlocks() {
+		traversals = append(traversals, blockTraversals(child)...)
+	}
+	return traversals
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -10,13 +12,24 @@ import (
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/schema"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 func LoadBlocksFromFile(file File, moduleName string) (hcl.Blocks, []block.Ignore, error) {
 
+	isJSON := strings.HasSuffix(file.path, ".tf.json")
+	parsed := parseIgnores(file.file.Bytes)
+	if isJSON {
+		parsed = parseJSONIgnores(file.file.Bytes)
+	}
+
 	var ignores []block.Ignore
-	for _, ignore := range parseIgnores(file.file.Bytes) {
-		ignore.Range = block.NewRange(file.path, ignore.Range.GetStartLine(), ignore.Range.GetEndLine(), moduleName)
+	for _, ignore := range parsed {
+		endLine := ignore.Range.GetEndLine()
+		if ignore.Scope == block.IgnoreScopeBlock && !isJSON {
+			endLine = followingBlockEnd(file.file.Body, ignore.Range.GetStartLine(), endLine)
+		}
+		ignore.Range = block.NewRange(file.path, ignore.Range.GetStartLine(), endLine, moduleName)
 		ignores = append(ignores, ignore)
 	}
 
@@ -36,6 +49,30 @@ func LoadBlocksFromFile(file File, moduleName string) (hcl.Blocks, []block.Ignor
 	return contents.Blocks, ignores, nil
 }
 
+// followingBlockEnd returns the end line of the outermost block which starts first at or after the given line, at any depth, or the given default if there is none
+func followingBlockEnd(body hcl.Body, line int, defaultEnd int) int {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return defaultEnd
+	}
+	var following *hcl.Range
+	var visit func(blocks hclsyntax.Blocks)
+	visit = func(blocks hclsyntax.Blocks) {
+		for _, b := range blocks {
+			rng := b.Range()
+			if rng.Start.Line >= line && (following == nil || rng.Start.Line < following.Start.Line || (rng.Start.Line == following.Start.Line && rng.End.Line > following.End.Line)) {
+				following = &rng
+			}
+			visit(b.Body.Blocks)
+		}
+	}
+	visit(syntaxBody.Blocks)
+	if following == nil {
+		return defaultEnd
+	}
+	return following.End.Line
+}
+
 func parseIgnores(data []byte) []block.Ignore {
 	var ignores []block.Ignore
 	for i, line := range strings.Split(string(data), "\n") {
@@ -50,12 +87,86 @@ func parseIgnores(data []byte) []block.Ignore {
 
 }
 
+// jsonFrame is an object or array being decoded by parseJSONIgnores
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	startLine int
+	// comment is set when the value being decoded belongs to a "//" key
+	comment bool
+	// blockIgnores holds the indexes of block ignores found directly within the object, which cover it from its opening to its closing brace
+	blockIgnores []int
+}
+
+// parseJSONIgnores parses ignores from the comment properties of a JSON file, e.g. "//": "tfsec:ignore:aws-s3-enable-versioning".
+// As JSON has no comments outside of objects, a block ignore covers the object containing it rather than the block following it.
+func parseJSONIgnores(data []byte) []block.Ignore {
+	var ignores []block.Ignore
+	var stack []*jsonFrame
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ignores
+		}
+		var current *jsonFrame
+		if len(stack) > 0 {
+			current = stack[len(stack)-1]
+		}
+		switch token := token.(type) {
+		case json.Delim:
+			switch token {
+			case '{', '[':
+				current.valueDecoded()
+				stack = append(stack, &jsonFrame{object: token == '{', expectKey: true, startLine: lineAt(decoder.InputOffset())})
+			default:
+				end := lineAt(decoder.InputOffset())
+				for _, index := range current.blockIgnores {
+					ignores[index].Range = block.NewRange("", current.startLine, end, "")
+				}
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if current != nil && current.object && current.expectKey {
+				current.expectKey = false
+				current.comment = token == "//"
+				continue
+			}
+			if current != nil && current.comment {
+				line := lineAt(decoder.InputOffset())
+				for _, ignore := range parseIgnoresFromLine(token) {
+					ignore.Range = block.NewRange("", line, line, "")
+					if ignore.Scope == block.IgnoreScopeBlock {
+						current.blockIgnores = append(current.blockIgnores, len(ignores))
+					}
+					ignores = append(ignores, ignore)
+				}
+			}
+			current.valueDecoded()
+		default:
+			current.valueDecoded()
+		}
+	}
+}
+
+// valueDecoded records that the value of the current key has been decoded, so the next string in an object is a key
+func (f *jsonFrame) valueDecoded() {
+	if f != nil && f.object {
+		f.expectKey = true
+		f.comment = false
+	}
+}
+
 func parseIgnoresFromLine(input string) []block.Ignore {
 
 	var ignores []block.Ignore
 
-	bits := strings.Split(input, " ")
-	for _, bit := range bits {
+	// a reason applies to the ignores before it which do not already have one
+	var unreasoned int
+	for _, bit := range splitComment(input) {
 		bit := strings.TrimSpace(bit)
 		bit = strings.TrimPrefix(bit, "#")
 		bit = strings.TrimPrefix(bit, "//")
@@ -67,12 +178,38 @@ func parseIgnoresFromLine(input string) []block.Ignore {
 				continue
 			}
 			ignores = append(ignores, *ignore)
+		} else if strings.HasPrefix(bit, "reason=") {
+			reason := strings.Trim(strings.TrimPrefix(bit, "reason="), `"`)
+			for i := unreasoned; i < len(ignores); i++ {
+				ignores[i].Reason = reason
+			}
+			unreasoned = len(ignores)
 		}
 	}
 
 	return ignores
 }
 
+// splitComment splits a line on spaces, except for those within double quotes
+func splitComment(input string) []string {
+	var bits []string
+	var current strings.Builder
+	var quoted bool
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ' ' && !quoted:
+			bits = append(bits, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(bits, current.String())
+}
+
 func parseIgnoreFromComment(input string) (*block.Ignore, error) {
 	var ignore block.Ignore
 	if !strings.HasPrefix(input, "tfsec:") {
@@ -89,6 +226,12 @@ func parseIgnoreFromComment(input string) (*block.Ignore, error) {
 		switch key {
 		case "ignore":
 			ignore.RuleID = val
+		case "ignore-block":
+			ignore.RuleID = val
+			ignore.Scope = block.IgnoreScopeBlock
+		case "ignore-file":
+			ignore.RuleID = val
+			ignore.Scope = block.IgnoreScopeFile
 		case "exp":
 			parsed, err := time.Parse("2006-01-02", val)
 			if err != nil {
@@ -2,76 +2,178 @@ package parser
 
 import (
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
 )
 
-var knownFiles = make(map[string]struct{})
+// fileSet records every file parsed during a single parse, including those belonging to modules
+type fileSet struct {
+	sync.Mutex
+	paths map[string]struct{}
+}
+
+func newFileSet() *fileSet {
+	return &fileSet{
+		paths: make(map[string]struct{}),
+	}
+}
 
 type File struct {
 	file *hcl.File
 	path string
 }
 
-func CountFiles() int {
-	return len(knownFiles)
+func (s *fileSet) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.paths)
+}
+
+func (s *fileSet) list() []string {
+	s.Lock()
+	defer s.Unlock()
+	var paths []string
+	for path := range s.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *fileSet) add(path string) {
+	s.Lock()
+	defer s.Unlock()
+	s.paths[path] = struct{}{}
 }
 
-func LoadDirectory(fullPath string, stopOnHCLError bool) ([]File, error) {
+// LoadDirectory parses the terraform files found directly within the directory. Files which fail to parse are skipped and returned
+// as diagnostics, which are left to the caller to report, unless stopOnHCLError is set.
+func LoadDirectory(fullPath string, stopOnHCLError bool) ([]File, hcl.Diagnostics, error) {
+	return loadDirectories(filesystem{}, []string{fullPath}, stopOnHCLError, runtime.NumCPU(), newFileSet())
+}
+
+// loadDirectories parses the terraform files found directly within each of the given directories of fsys, using up to the given number of workers.
+// The path of each successfully parsed file is added to the given set. Files which fail to parse are skipped and returned as diagnostics, unless stopOnHCLError is set.
+// Files are returned grouped by directory, in the order the directories were provided, and sorted by path within each directory.
+func loadDirectories(fsys filesystem, dirs []string, stopOnHCLError bool, workers int, known *fileSet) ([]File, hcl.Diagnostics, error) {
 
 	t := metrics.Timer("timings", "disk i/o")
 	t.Start()
 	defer t.Stop()
 
-	hclParser := hclparse.NewParser()
-
-	fileInfos, err := ioutil.ReadDir(fullPath)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, info := range fileInfos {
-		if info.IsDir() {
-			continue
+	var paths []string
+	for _, dir := range dirs {
+		fileInfos, err := fsys.readDir(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, info := range fileInfos {
+			if info.IsDir() {
+				continue
+			}
+			if strings.HasSuffix(info.Name(), ".tf") || strings.HasSuffix(info.Name(), ".tf.json") {
+				paths = append(paths, filepath.Join(dir, info.Name()))
+			}
 		}
+	}
 
-		var parseFunc func(filename string) (*hcl.File, hcl.Diagnostics)
+	parsed := make([]*hcl.File, len(paths))
+	errs := make([]hcl.Diagnostics, len(paths))
 
-		switch true {
-		case strings.HasSuffix(info.Name(), ".tf"):
-			parseFunc = hclParser.ParseHCLFile
-		case strings.HasSuffix(info.Name(), ".tf.json"):
-			parseFunc = hclParser.ParseJSONFile
-		default:
-			continue
+	runConcurrently(workers, len(paths), func(i int) {
+		file, diag := parseFile(fsys, paths[i])
+		if diag != nil && diag.HasErrors() {
+			errs[i] = diag
+			return
 		}
+		parsed[i] = file
+		known.add(paths[i])
+	})
 
-		path := filepath.Join(fullPath, info.Name())
-		_, diag := parseFunc(path)
-		if diag != nil && diag.HasErrors() {
+	var files []File
+	var diags hcl.Diagnostics
+	for i, path := range paths {
+		if errs[i] != nil {
 			if stopOnHCLError {
-				return nil, diag
+				return nil, nil, errs[i]
 			}
-			_, _ = fmt.Fprintf(os.Stderr, "WARNING: HCL error: %s\n", diag)
+			diags = append(diags, errs[i]...)
 			continue
 		}
-
-		knownFiles[path] = struct{}{}
-	}
-
-	var files []File
-	for path, file := range hclParser.Files() {
 		files = append(files, File{
-			file: file,
+			file: parsed[i],
 			path: path,
 		})
 	}
 
-	return files, nil
+	return files, diags, nil
+}
+
+// asDiagnostics converts an error found while loading a file into diagnostics, so that it can be reported without stopping the parse
+func asDiagnostics(err error) hcl.Diagnostics {
+	if diags, ok := err.(hcl.Diagnostics); ok {
+		return diags
+	}
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to load file",
+			Detail:   err.Error(),
+		},
+	}
+}
+
+func parseFile(fsys filesystem, path string) (*hcl.File, hcl.Diagnostics) {
+	src, err := fsys.readFile(path)
+	if err != nil {
+		return nil, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to read file",
+				Detail:   fmt.Sprintf("The file %q could not be read.", path),
+			},
+		}
+	}
+	if strings.HasSuffix(path, ".tf.json") {
+		return hcljson.Parse(src, path)
+	}
+	return hclsyntax.ParseConfig(src, path, hcl.Pos{Byte: 0, Line: 1, Column: 1})
+}
+
+// runConcurrently calls fn once for every index in [0, count), using at most the given number of goroutines at a time
+func runConcurrently(workers int, count int, fn func(i int)) {
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		for i := 0; i < count; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 }
@@ -10,7 +10,6 @@ import (
 
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
-	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -80,17 +79,30 @@ func (e *Evaluator) loadModules(stopOnHCLError bool) []*ModuleDefinition {
 				}
 				continue
 			}
-			_, _ = fmt.Fprintf(os.Stderr, "WARNING: Failed to load module: %s\n", err)
+			e.addDiagnostics(hcl.Diagnostics{
+				{
+					Severity: hcl.DiagWarning,
+					Summary:  "Failed to load module",
+					Detail:   err.Error(),
+				},
+			})
 			continue
 		}
 		moduleDefinitions = append(moduleDefinitions, moduleDefinition)
 	}
 
 	if len(loadErrors) > 0 {
-		_, _ = fmt.Fprintf(os.Stderr, "WARNING: Did you forget to 'terraform init'? The following modules failed to load:\n")
+		var sources []string
 		for _, err := range loadErrors {
-			_, _ = fmt.Fprintf(os.Stderr, " - %s\n", err.source)
+			sources = append(sources, err.source)
 		}
+		e.addDiagnostics(hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Failed to load modules",
+				Detail:   fmt.Sprintf("Did you forget to 'terraform init'? The following modules failed to load: %s", strings.Join(sources, ", ")),
+			},
+		})
 	}
 
 	return moduleDefinitions
@@ -151,14 +163,14 @@ func (e *Evaluator) loadModule(b block.Block, stopOnHCLError bool) (*ModuleDefin
 		modulePath = filepath.Join(e.modulePath, source)
 	}
 
-	blocks, ignores, err := getModuleBlocks(b, modulePath, e.moduleName, stopOnHCLError)
+	blocks, ignores, err := e.getModuleBlocks(b, modulePath, stopOnHCLError)
 	if err != nil {
 		return nil, &moduleLoadError{
 			source: source,
 			err:    err,
 		}
 	}
-	debug.Log("Loaded module '%s' (requested at %s)", modulePath, b.Range())
+	e.debug.Log("Loaded module '%s' (requested at %s)", modulePath, b.Range())
 	metrics.Counter("counts", "modules").Increment(1)
 
 	return &ModuleDefinition{
@@ -169,27 +181,28 @@ func (e *Evaluator) loadModule(b block.Block, stopOnHCLError bool) (*ModuleDefin
 	}, nil
 }
 
-func getModuleBlocks(b block.Block, modulePath string, moduleName string, stopOnHCLError bool) (block.Blocks, []block.Ignore, error) {
-	moduleFiles, err := LoadDirectory(modulePath, stopOnHCLError)
+func (e *Evaluator) getModuleBlocks(b block.Block, modulePath string, stopOnHCLError bool) (block.Blocks, []block.Ignore, error) {
+	moduleFiles, diags, err := loadDirectories(e.filesystem, []string{modulePath}, stopOnHCLError, e.workers, e.files)
 	if err != nil {
 		return nil, nil, err
 	}
+	e.addDiagnostics(diags)
 
 	var blocks block.Blocks
 	var ignores []block.Ignore
 
 	moduleCtx := block.NewContext(&hcl.EvalContext{}, nil)
 	for _, file := range moduleFiles {
-		fileBlocks, fileIgnores, err := LoadBlocksFromFile(file, moduleName)
+		fileBlocks, fileIgnores, err := LoadBlocksFromFile(file, e.moduleName)
 		if err != nil {
 			if stopOnHCLError {
 				return nil, nil, err
 			}
-			_, _ = fmt.Fprintf(os.Stderr, "WARNING: HCL error: %s\n", err)
+			e.addDiagnostics(asDiagnostics(err))
 			continue
 		}
 		if len(fileBlocks) > 0 {
-			debug.Log("Added %d blocks from %s...", len(fileBlocks), fileBlocks[0].DefRange.Filename)
+			e.debug.Log("Added %d blocks from %s...", len(fileBlocks), fileBlocks[0].DefRange.Filename)
 		}
 		for _, fileBlock := range fileBlocks {
 			blocks = append(blocks, block.NewHCLBlock(fileBlock, moduleCtx, b))
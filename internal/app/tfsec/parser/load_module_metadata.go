@@ -2,7 +2,6 @@ package parser
 
 import (
 	"encoding/json"
-	"os"
 	"path/filepath"
 )
 
@@ -18,19 +17,18 @@ type ModuleMetadata struct {
 }
 
 func LoadModuleMetadata(fullPath string) (*ModulesMetadata, error) {
-	metadataPath := filepath.Join(fullPath, ".terraform/modules/modules.json")
-	if _, err := os.Stat(metadataPath); err != nil {
-		return nil, err
-	}
+	return loadModuleMetadataFrom(filesystem{}, fullPath)
+}
 
-	f, err := os.Open(metadataPath)
+func loadModuleMetadataFrom(fsys filesystem, fullPath string) (*ModulesMetadata, error) {
+	metadataPath := filepath.Join(fullPath, ".terraform/modules/modules.json")
+	src, err := fsys.readFile(metadataPath)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = f.Close() }()
 
 	var metadata ModulesMetadata
-	if err := json.NewDecoder(f).Decode(&metadata); err != nil {
+	if err := json.Unmarshal(src, &metadata); err != nil {
 		return nil, err
 	}
 
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"fmt"
-	"io/ioutil"
 
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
@@ -11,11 +10,15 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
-func LoadTFVars(filenames []string) (map[string]cty.Value, error) {
+func LoadTFVars(filenames []string, logger debug.Logger) (map[string]cty.Value, error) {
+	return loadTFVarsFrom(filesystem{}, filenames, logger)
+}
+
+func loadTFVarsFrom(fsys filesystem, filenames []string, logger debug.Logger) (map[string]cty.Value, error) {
 	combinedVars := make(map[string]cty.Value)
 
 	for _, filename := range filenames {
-		vars, err := loadTFVars(filename)
+		vars, err := loadTFVars(fsys, filename, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load the tfvars. %s", err.Error())
 		}
@@ -27,7 +30,7 @@ func LoadTFVars(filenames []string) (map[string]cty.Value, error) {
 	return combinedVars, nil
 }
 
-func loadTFVars(filename string) (map[string]cty.Value, error) {
+func loadTFVars(fsys filesystem, filename string, logger debug.Logger) (map[string]cty.Value, error) {
 
 	diskTimer := metrics.Timer("timings", "disk i/o")
 	diskTimer.Start()
@@ -38,8 +41,8 @@ func loadTFVars(filename string) (map[string]cty.Value, error) {
 		return inputVars, nil
 	}
 
-	debug.Log("loading tfvars-file [%s]", filename)
-	src, err := ioutil.ReadFile(filename)
+	logger.Log("loading tfvars-file [%s]", filename)
+	src, err := fsys.readFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +57,7 @@ func loadTFVars(filename string) (map[string]cty.Value, error) {
 	attrs, _ := variableFile.Body.JustAttributes()
 
 	for _, attr := range attrs {
-		debug.Log("Setting '%s' from tfvars file at %s", attr.Name, filename)
+		logger.Log("Setting '%s' from tfvars file at %s", attr.Name, filename)
 		inputVars[attr.Name], _ = attr.Expr.Value(&hcl.EvalContext{})
 	}
 
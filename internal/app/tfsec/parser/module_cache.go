@@ -0,0 +1,334 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// moduleCacheFormat must be incremented whenever the layout of a cached module changes
+const moduleCacheFormat = "1"
+
+// ValueCache stores the evaluated values of modules between parses. Keys already describe everything the values depend on, other
+// than the version of tfsec, so implementations only need to add that.
+type ValueCache interface {
+	LoadValues(key string) ([]byte, bool)
+	StoreValues(key string, data []byte) error
+}
+
+// moduleCache restores the evaluation context of modules whose terraform files and inputs are unchanged since they were stored,
+// so that only modules affected by a change are evaluated again. Blocks are always parsed and expanded, as they are needed for scanning.
+type moduleCache struct {
+	store      ValueCache
+	filesystem filesystem
+	lock       sync.Mutex
+	// dirs holds the hashes of the directories seen so far, as a directory is checked by every module which contains it
+	dirs map[string]string
+}
+
+type cachedModule struct {
+	// Dirs holds the hash of every directory in the module tree, including those of child modules, so that the entry is only used
+	// while none of them have changed
+	Dirs      map[string]string      `json:"dirs"`
+	Variables map[string]cachedValue `json:"variables"`
+}
+
+// cachedValue is a cty value which can be stored as JSON. Collections are stored element by element, as the evaluation context
+// holds NilVal and unknown values for anything which could not be evaluated, neither of which can be marshalled.
+type cachedValue struct {
+	Kind       string                 `json:"kind,omitempty"`
+	Type       json.RawMessage        `json:"type,omitempty"`
+	Value      json.RawMessage        `json:"value,omitempty"`
+	Attributes map[string]cachedValue `json:"attributes,omitempty"`
+	Elements   []cachedValue          `json:"elements,omitempty"`
+}
+
+const (
+	cachedNil     = "nil"
+	cachedUnknown = "unknown"
+	cachedObject  = "object"
+	cachedMap     = "map"
+	cachedTuple   = "tuple"
+	cachedList    = "list"
+	cachedSet     = "set"
+)
+
+func newModuleCache(store ValueCache, fsys filesystem) *moduleCache {
+	return &moduleCache{
+		store:      store,
+		filesystem: fsys,
+		dirs:       make(map[string]string),
+	}
+}
+
+// key returns the key of the module being evaluated, along with the hashes of the directories its own blocks were loaded from
+func (c *moduleCache) key(e *Evaluator) (string, map[string]string, error) {
+
+	ownDirs := map[string]bool{e.modulePath: true}
+	for _, b := range e.blocks {
+		ownDirs[filepath.Dir(b.Range().GetFilename())] = true
+	}
+	dirs := make(map[string]string)
+	for dir := range ownDirs {
+		hash, err := c.hashDir(dir)
+		if err != nil {
+			return "", nil, err
+		}
+		dirs[dir] = hash
+	}
+
+	inputs := make(map[string]cachedValue)
+	for name, value := range e.inputVars {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("input variable %s cannot be cached: %w", name, err)
+		}
+		inputs[name] = encoded
+	}
+	inputData, err := json.Marshal(inputs)
+	if err != nil {
+		return "", nil, err
+	}
+	metadataData, err := json.Marshal(e.moduleMetadata)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hasher := sha256.New()
+	_, _ = fmt.Fprintf(hasher, "format:%s\n", moduleCacheFormat)
+	_, _ = fmt.Fprintf(hasher, "module:%s:%s:%s:%s\n", e.projectRootPath, e.modulePath, e.workingDir, e.moduleName)
+	_, _ = fmt.Fprintf(hasher, "workspace:%s\n", e.workspace)
+	_, _ = fmt.Fprintf(hasher, "inputs:%s\n", inputData)
+	_, _ = fmt.Fprintf(hasher, "metadata:%s\n", metadataData)
+	for _, dir := range sortedKeys(dirs) {
+		_, _ = fmt.Fprintf(hasher, "dir:%s:%s\n", dir, dirs[dir])
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), dirs, nil
+}
+
+// load returns the cached evaluation context variables of the module, if every directory in its tree is unchanged
+func (c *moduleCache) load(key string) (map[string]cty.Value, map[string]string, bool) {
+	data, ok := c.store.LoadValues(key)
+	if !ok {
+		return nil, nil, false
+	}
+	var cached cachedModule
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, nil, false
+	}
+	for dir, expected := range cached.Dirs {
+		if actual, err := c.hashDir(dir); err != nil || actual != expected {
+			return nil, nil, false
+		}
+	}
+	variables := make(map[string]cty.Value)
+	for name, value := range cached.Variables {
+		decoded, err := value.decode()
+		if err != nil {
+			return nil, nil, false
+		}
+		variables[name] = decoded
+	}
+	return variables, cached.Dirs, true
+}
+
+func (c *moduleCache) save(key string, variables map[string]cty.Value, dirs map[string]string) error {
+	cached := cachedModule{
+		Dirs:      dirs,
+		Variables: make(map[string]cachedValue),
+	}
+	for name, value := range variables {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return fmt.Errorf("%s cannot be cached: %w", name, err)
+		}
+		cached.Variables[name] = encoded
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return c.store.StoreValues(key, data)
+}
+
+// hashDir hashes the names and content of the terraform files directly within the directory, so the hash changes whenever
+// any of them is added, removed or edited
+func (c *moduleCache) hashDir(dir string) (string, error) {
+	c.lock.Lock()
+	hash, ok := c.dirs[dir]
+	c.lock.Unlock()
+	if ok {
+		return hash, nil
+	}
+
+	entries, err := c.filesystem.readDir(dir)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")) {
+			continue
+		}
+		content, err := c.filesystem.readFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fileHash := sha256.Sum256(content)
+		_, _ = fmt.Fprintf(hasher, "%s:%s\n", name, hex.EncodeToString(fileHash[:]))
+	}
+	hash = hex.EncodeToString(hasher.Sum(nil))
+
+	c.lock.Lock()
+	c.dirs[dir] = hash
+	c.lock.Unlock()
+	return hash, nil
+}
+
+// encodeValue converts the value into a form which can be stored, failing for values which cannot be, such as marked values
+func encodeValue(val cty.Value) (encoded cachedValue, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("value cannot be encoded: %v", r)
+		}
+	}()
+	return encode(val)
+}
+
+func encode(val cty.Value) (cachedValue, error) {
+	typ := val.Type()
+	if typ == cty.NilType {
+		return cachedValue{Kind: cachedNil}, nil
+	}
+
+	var encoded cachedValue
+	structural := val.IsKnown() && !val.IsNull() && !val.IsMarked()
+	switch {
+	case structural && typ.IsObjectType():
+		encoded.Kind = cachedObject
+	case structural && typ.IsTupleType():
+		encoded.Kind = cachedTuple
+	default:
+		// the types of objects and tuples are not stored, as they may contain NilVal
+		typeData, err := ctyjson.MarshalType(typ)
+		if err != nil {
+			return cachedValue{}, err
+		}
+		encoded.Type = typeData
+		switch {
+		case !val.IsKnown():
+			encoded.Kind = cachedUnknown
+			return encoded, nil
+		case structural && typ.IsMapType():
+			encoded.Kind = cachedMap
+		case structural && typ.IsListType():
+			encoded.Kind = cachedList
+		case structural && typ.IsSetType():
+			encoded.Kind = cachedSet
+		default:
+			// anything else is left to cty, which rejects marked values
+			encoded.Value, err = ctyjson.Marshal(val, typ)
+			return encoded, err
+		}
+	}
+
+	if encoded.Kind == cachedObject || encoded.Kind == cachedMap {
+		encoded.Attributes = make(map[string]cachedValue)
+		for name, attribute := range val.AsValueMap() {
+			attributeValue, err := encode(attribute)
+			if err != nil {
+				return cachedValue{}, err
+			}
+			encoded.Attributes[name] = attributeValue
+		}
+		return encoded, nil
+	}
+	for _, element := range val.AsValueSlice() {
+		elementValue, err := encode(element)
+		if err != nil {
+			return cachedValue{}, err
+		}
+		encoded.Elements = append(encoded.Elements, elementValue)
+	}
+	return encoded, nil
+}
+
+func (v cachedValue) decode() (decoded cty.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("value cannot be decoded: %v", r)
+		}
+	}()
+
+	switch v.Kind {
+	case cachedNil:
+		return cty.NilVal, nil
+	case cachedObject, cachedMap:
+		attributes := make(map[string]cty.Value)
+		for name, attribute := range v.Attributes {
+			if attributes[name], err = attribute.decode(); err != nil {
+				return cty.NilVal, err
+			}
+		}
+		if v.Kind == cachedObject {
+			return cty.ObjectVal(attributes), nil
+		}
+		if len(attributes) > 0 {
+			return cty.MapVal(attributes), nil
+		}
+	case cachedTuple, cachedList, cachedSet:
+		var elements []cty.Value
+		for _, element := range v.Elements {
+			decodedElement, err := element.decode()
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elements = append(elements, decodedElement)
+		}
+		switch {
+		case v.Kind == cachedTuple:
+			if len(elements) == 0 {
+				return cty.EmptyTupleVal, nil
+			}
+			return cty.TupleVal(elements), nil
+		case len(elements) > 0 && v.Kind == cachedList:
+			return cty.ListVal(elements), nil
+		case len(elements) > 0:
+			return cty.SetVal(elements), nil
+		}
+	}
+
+	typ, err := ctyjson.UnmarshalType(v.Type)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	switch v.Kind {
+	case cachedUnknown:
+		return cty.UnknownVal(typ), nil
+	case cachedMap:
+		return cty.MapValEmpty(typ.ElementType()), nil
+	case cachedList:
+		return cty.ListValEmpty(typ.ElementType()), nil
+	case cachedSet:
+		return cty.SetValEmpty(typ.ElementType()), nil
+	}
+	return ctyjson.Unmarshal(v.Value, typ)
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
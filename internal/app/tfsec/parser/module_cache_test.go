@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type memoryValueCache struct {
+	lock    sync.Mutex
+	entries map[string][]byte
+	hits    int
+}
+
+func (c *memoryValueCache) LoadValues(key string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return data, ok
+}
+
+func (c *memoryValueCache) StoreValues(key string, data []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = data
+	return nil
+}
+
+func (c *memoryValueCache) takeHits() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hits := c.hits
+	c.hits = 0
+	return hits
+}
+
+func Test_ModuleValuesAreCached(t *testing.T) {
+
+	path := createTestFileWithModule(`
+locals {
+	name = "bucket"
+}
+
+module "storage" {
+	source = "../module"
+	name   = local.name
+}
+
+resource "aws_s3_bucket_policy" "policy" {
+	bucket = module.storage.bucket_id
+	count  = 2
+}
+`,
+		`
+variable "name" {}
+
+locals {
+	tags = { Name = var.name, Missing = aws_instance.missing.id }
+}
+
+resource "aws_s3_bucket" "bucket" {
+	bucket = "${var.name}-logs"
+	tags   = local.tags
+}
+
+output "bucket_id" {
+	value = aws_s3_bucket.bucket.bucket
+}
+`,
+		"module",
+	)
+
+	valueCache := &memoryValueCache{entries: make(map[string][]byte)}
+	parse := func() []block.Module {
+		modules, err := New(path, OptionStopOnHCLError(), OptionWithValueCache(valueCache)).ParseDirectory()
+		require.NoError(t, err)
+		require.Len(t, modules, 2)
+		return modules
+	}
+	assertValues := func(modules []block.Module, expectedName string) {
+		policies := modules[0].GetResourcesByType("aws_s3_bucket_policy")
+		require.Len(t, policies, 2)
+		for _, policy := range policies {
+			assert.Equal(t, expectedName+"-logs", policy.GetAttribute("bucket").Value().AsString())
+		}
+		buckets := modules[1].GetResourcesByType("aws_s3_bucket")
+		require.Len(t, buckets, 1)
+		tags := buckets[0].GetAttribute("tags").Value()
+		require.True(t, tags.Type().IsObjectType())
+		assert.Equal(t, cty.StringVal(expectedName), tags.GetAttr("Name"))
+	}
+
+	assertValues(parse(), "bucket")
+	assert.Len(t, valueCache.entries, 2)
+	assert.Equal(t, 0, valueCache.takeHits())
+
+	// nothing has changed, so the root module is restored along with its child
+	assertValues(parse(), "bucket")
+	assert.Equal(t, 2, valueCache.takeHits())
+
+	// a change to the root module which does not affect the inputs of its child leaves the child cached
+	rootFile := filepath.Join(path, "main.tf")
+	content, err := ioutil.ReadFile(rootFile)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(rootFile, append(content, []byte("\nresource \"aws_instance\" \"extra\" {}\n")...), 0600))
+	assertValues(parse(), "bucket")
+	assert.Equal(t, 1, valueCache.takeHits())
+
+	// a change to the inputs of the child means both are evaluated again
+	require.NoError(t, ioutil.WriteFile(rootFile, []byte(`
+module "storage" {
+	source = "../module"
+	name   = "renamed"
+}
+
+resource "aws_s3_bucket_policy" "policy" {
+	bucket = module.storage.bucket_id
+	count  = 2
+}
+`), 0600))
+	assertValues(parse(), "renamed")
+	assert.Equal(t, 0, valueCache.takeHits())
+
+	// a change to the child alone invalidates the root too, as the root depends on the outputs of its child - the entry of the root
+	// is found, but is not used as the directory of its child has changed
+	moduleFile := filepath.Join(filepath.Dir(path), "module", "main.tf")
+	content, err = ioutil.ReadFile(moduleFile)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(moduleFile, []byte(strings.Replace(string(content), `"${var.name}-logs"`, `"${var.name}-audit"`, 1)), 0600))
+	modules := parse()
+	assert.Equal(t, 1, valueCache.takeHits())
+	policies := modules[0].GetResourcesByType("aws_s3_bucket_policy")
+	require.Len(t, policies, 2)
+	assert.Equal(t, "renamed-audit", policies[0].GetAttribute("bucket").Value().AsString())
+}
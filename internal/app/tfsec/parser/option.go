@@ -1,5 +1,12 @@
 package parser
 
+import (
+	"io"
+	"io/fs"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+)
+
 type Option func(p *Parser)
 
 func OptionDoNotSearchTfFiles() Option {
@@ -37,3 +44,39 @@ func OptionWithExcludePaths(paths []string) Option {
 		p.excludePaths = paths
 	}
 }
+
+// OptionWithConcurrency sets the maximum number of files parsed, or modules evaluated, at the same time
+func OptionWithConcurrency(workers int) Option {
+	return func(p *Parser) {
+		p.workers = workers
+	}
+}
+
+// OptionWithDebugWriter enables debug logging for this parser only, writing to the given writer
+func OptionWithDebugWriter(writer io.Writer) Option {
+	return func(p *Parser) {
+		p.debug = debug.New(writer)
+	}
+}
+
+// OptionWithWarningWriter sets where non-fatal problems found while parsing are written, defaulting to stderr. A nil writer disables them - they are still available from Parser.Diagnostics.
+func OptionWithWarningWriter(writer io.Writer) Option {
+	return func(p *Parser) {
+		p.warnings = writer
+	}
+}
+
+// OptionWithFS reads files from the given filesystem rather than from disk, in which case the initial path, tfvars paths and exclude paths are paths within it
+func OptionWithFS(target fs.FS) Option {
+	return func(p *Parser) {
+		p.filesystem = filesystem{target: target}
+	}
+}
+
+// OptionWithValueCache stores the evaluated values of each module in the given cache, so that later parses only evaluate modules
+// whose terraform files or inputs have changed
+func OptionWithValueCache(cache ValueCache) Option {
+	return func(p *Parser) {
+		p.valueCache = cache
+	}
+}
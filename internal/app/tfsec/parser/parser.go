@@ -2,18 +2,20 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"io/fs"
 	"strings"
 
 	"github.com/aquasecurity/defsec/metrics"
+	"github.com/hashicorp/hcl/v2"
 
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
 
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
 
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 )
 
 // Parser is a tool for parsing terraform templates at a given file system location
@@ -25,6 +27,13 @@ type Parser struct {
 	stopOnHCLError bool
 	workspaceName  string
 	skipDownloaded bool
+	workers        int
+	diagnostics    hcl.Diagnostics
+	files          *fileSet
+	filesystem     filesystem
+	debug          debug.Logger
+	warnings       io.Writer
+	valueCache     ValueCache
 }
 
 // New creates a new Parser
@@ -33,6 +42,9 @@ func New(initialPath string, options ...Option) *Parser {
 		initialPath:   initialPath,
 		stopOnFirstTf: true,
 		workspaceName: "default",
+		workers:       runtime.NumCPU(),
+		files:         newFileSet(),
+		warnings:      os.Stderr,
 	}
 
 	for _, option := range options {
@@ -46,31 +58,48 @@ func (parser *Parser) parseDirectoryFiles(files []File) (block.Blocks, block.Ign
 	var blocks block.Blocks
 	var ignores block.Ignores
 
-	for _, file := range files {
-		fileBlocks, fileIgnores, err := LoadBlocksFromFile(file, "root")
+	fileBlocks := make([]block.Blocks, len(files))
+	fileIgnores := make([][]block.Ignore, len(files))
+	errs := make([]error, len(files))
+
+	runConcurrently(parser.workers, len(files), func(i int) {
+		hclBlocks, ignores, err := LoadBlocksFromFile(files[i], "root")
 		if err != nil {
+			errs[i] = err
+			return
+		}
+		for _, hclBlock := range hclBlocks {
+			fileBlocks[i] = append(fileBlocks[i], block.NewHCLBlock(hclBlock, nil, nil))
+		}
+		fileIgnores[i] = ignores
+	})
+
+	for i := range files {
+		if errs[i] != nil {
 			if parser.stopOnHCLError {
-				return nil, nil, err
+				return nil, nil, errs[i]
 			}
-			_, _ = fmt.Fprintf(os.Stderr, "WARNING: HCL error: %s\n", err)
+			parser.diagnostics = append(parser.diagnostics, asDiagnostics(errs[i])...)
 			continue
 		}
-		if len(fileBlocks) > 0 {
-			debug.Log("Added %d blocks from %s...", len(fileBlocks), fileBlocks[0].DefRange.Filename)
+		if len(fileBlocks[i]) > 0 {
+			parser.debug.Log("Added %d blocks from %s...", len(fileBlocks[i]), files[i].path)
 		}
-		for _, fileBlock := range fileBlocks {
-			blocks = append(blocks, block.NewHCLBlock(fileBlock, nil, nil))
-		}
-		ignores = append(ignores, fileIgnores...)
+		blocks = append(blocks, fileBlocks[i]...)
+		ignores = append(ignores, fileIgnores[i]...)
 	}
 
 	return blocks, ignores, nil
 }
 
-// ParseDirectory parses all terraform files within a given directory
+// ParseDirectory parses all terraform files within a given directory.
+// Problems which do not stop the parse, such as HCL errors when OptionStopOnHCLError is not set, are written as warnings and are available from Diagnostics afterwards.
 func (parser *Parser) ParseDirectory() ([]block.Module, error) {
 
-	debug.Log("Finding Terraform subdirectories...")
+	parser.diagnostics = nil
+	defer parser.writeWarnings()
+
+	parser.debug.Log("Finding Terraform subdirectories...")
 	diskTimer := metrics.Timer("timings", "disk i/o")
 	diskTimer.Start()
 	subdirectories, err := parser.getSubdirectories(parser.initialPath)
@@ -79,27 +108,25 @@ func (parser *Parser) ParseDirectory() ([]block.Module, error) {
 	}
 	diskTimer.Stop()
 
-	var blocks block.Blocks
-	var ignores block.Ignores
-
+	var dirs []string
 	for _, dir := range subdirectories {
 		if parser.skipDownloaded && strings.Contains(dir, ".terraform") {
-			fmt.Printf("skipping download module file %s\n", dir)
+			parser.debug.Log("Skipping downloaded module directory '%s'...", dir)
 			continue
 		}
-		debug.Log("Beginning parse for directory '%s'...", dir)
-		files, err := LoadDirectory(dir, parser.stopOnHCLError)
-		if err != nil {
-			return nil, err
-		}
+		parser.debug.Log("Beginning parse for directory '%s'...", dir)
+		dirs = append(dirs, dir)
+	}
 
-		parsedBlocks, dirIgnores, err := parser.parseDirectoryFiles(files)
-		if err != nil {
-			return nil, err
-		}
-		ignores = append(ignores, dirIgnores...)
+	files, diags, err := loadDirectories(parser.filesystem, dirs, parser.stopOnHCLError, parser.workers, parser.files)
+	if err != nil {
+		return nil, err
+	}
+	parser.diagnostics = append(parser.diagnostics, diags...)
 
-		blocks = append(blocks, parsedBlocks...)
+	blocks, ignores, err := parser.parseDirectoryFiles(files)
+	if err != nil {
+		return nil, err
 	}
 
 	metrics.Counter("counts", "blocks").Increment(len(blocks))
@@ -111,39 +138,70 @@ func (parser *Parser) ParseDirectory() ([]block.Module, error) {
 	tfPath := parser.initialPath
 	if len(subdirectories) > 0 && parser.stopOnFirstTf {
 		tfPath = subdirectories[0]
-		debug.Log("Project root set to '%s'...", tfPath)
+		parser.debug.Log("Project root set to '%s'...", tfPath)
 	}
 
-	debug.Log("Loading TFVars...")
+	parser.debug.Log("Loading TFVars...")
 
-	inputVars, err := LoadTFVars(parser.tfvarsPaths)
+	inputVars, err := loadTFVarsFrom(parser.filesystem, parser.tfvarsPaths, parser.debug)
 	if err != nil {
 		return nil, err
 	}
 
 	var modulesMetadata *ModulesMetadata
 	if parser.skipDownloaded {
-		debug.Log("Skipping module metadata loading, --exclude-downloaded-modules passed")
+		parser.debug.Log("Skipping module metadata loading, --exclude-downloaded-modules passed")
 	} else {
-		debug.Log("Loading module metadata...")
+		parser.debug.Log("Loading module metadata...")
 		diskTimer.Start()
-		modulesMetadata, _ = LoadModuleMetadata(tfPath)
+		modulesMetadata, _ = loadModuleMetadataFrom(parser.filesystem, tfPath)
 		diskTimer.Stop()
 	}
 
-	debug.Log("Evaluating expressions...")
-	workingDir, _ := os.Getwd()
-	evaluator := NewEvaluator(tfPath, tfPath, workingDir, "root", blocks, inputVars, modulesMetadata, nil, parser.stopOnHCLError, parser.workspaceName, ignores)
+	parser.debug.Log("Evaluating expressions...")
+	evaluator := NewEvaluator(tfPath, tfPath, parser.filesystem.workingDir(), "root", blocks, inputVars, modulesMetadata, nil, parser.stopOnHCLError, parser.workspaceName, ignores, parser.workers, parser.files, parser.filesystem, parser.debug)
+	if parser.valueCache != nil {
+		evaluator.cache = newModuleCache(parser.valueCache, parser.filesystem)
+	}
 	modules, err := evaluator.EvaluateAll()
 	if err != nil {
 		return nil, err
 	}
+	parser.diagnostics = append(parser.diagnostics, evaluator.Diagnostics()...)
 	return modules, nil
 
 }
 
+func (parser *Parser) writeWarnings() {
+	if parser.warnings == nil {
+		return
+	}
+	for _, diag := range parser.diagnostics {
+		if diag.Subject == nil {
+			_, _ = fmt.Fprintf(parser.warnings, "WARNING: %s; %s\n", diag.Summary, diag.Detail)
+			continue
+		}
+		_, _ = fmt.Fprintf(parser.warnings, "WARNING: %s\n", diag.Error())
+	}
+}
+
+// CountFiles returns the number of files parsed by this parser, including those belonging to modules
+func (parser *Parser) CountFiles() int {
+	return parser.files.count()
+}
+
+// ParsedFiles returns the paths of all files parsed by this parser, including those belonging to modules
+func (parser *Parser) ParsedFiles() []string {
+	return parser.files.list()
+}
+
+// Diagnostics returns any non-fatal problems found during the most recent parse, such as HCL errors, modules which could not be loaded and reference cycles
+func (parser *Parser) Diagnostics() hcl.Diagnostics {
+	return parser.diagnostics
+}
+
 func (parser *Parser) getSubdirectories(path string) ([]string, error) {
-	entries, err := ioutil.ReadDir(path)
+	entries, err := parser.filesystem.readDir(path)
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +212,7 @@ func (parser *Parser) getSubdirectories(path string) ([]string, error) {
 	for _, entry := range entries {
 
 		if !entry.IsDir() && (filepath.Ext(entry.Name()) == ".tf" || strings.HasSuffix(entry.Name(), ".tf.json")) {
-			debug.Log("Found qualifying subdirectory containing .tf files: %s", path)
+			parser.debug.Log("Found qualifying subdirectory containing .tf files: %s", path)
 			results = append(results, path)
 			if parser.stopOnFirstTf {
 				return results, nil
@@ -177,7 +235,7 @@ func (parser *Parser) getSubdirectories(path string) ([]string, error) {
 	return results, nil
 }
 
-func (parser *Parser) RemoveExcluded(path string, entries []fs.FileInfo) (valid []fs.FileInfo) {
+func (parser *Parser) RemoveExcluded(path string, entries []fs.DirEntry) (valid []fs.DirEntry) {
 	if len(parser.excludePaths) == 0 {
 		return entries
 	}
@@ -186,14 +244,14 @@ func (parser *Parser) RemoveExcluded(path string, entries []fs.FileInfo) (valid
 		var remove bool
 		fullPath := filepath.Join(path, entry.Name())
 		for _, excludePath := range parser.excludePaths {
-			if fullPath == excludePath {
+			if parser.filesystem.path(fullPath) == parser.filesystem.path(excludePath) {
 				remove = true
 			}
 		}
 		if !remove {
 			valid = append(valid, entry)
 		} else {
-			debug.Log("Excluding path %s", fullPath)
+			parser.debug.Log("Excluding path %s", fullPath)
 		}
 	}
 	return valid
@@ -1,13 +1,14 @@
 package parser
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
-
-	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"testing/fstest"
 
 	"github.com/zclconf/go-cty/cty"
 
@@ -53,8 +54,7 @@ data "cats_cat" "the-cats-mother" {
 
 `)
 
-	debug.Enabled = true
-	parser := New(filepath.Dir(path), OptionStopOnHCLError())
+	parser := New(filepath.Dir(path), OptionStopOnHCLError(), OptionWithDebugWriter(os.Stdout))
 	modules, err := parser.ParseDirectory()
 	if err != nil {
 		t.Fatal(err)
@@ -233,6 +233,152 @@ output "mod_result" {
 	assert.Equal(t, "ok", childValAttr.Value().AsString())
 }
 
+func Test_DeepReferenceChain(t *testing.T) {
+
+	var source strings.Builder
+	source.WriteString(`
+locals {
+	link_0 = "end"
+}
+`)
+	// declare the chain in reverse so that evaluation order cannot rely on the order of declaration
+	for i := 64; i > 0; i-- {
+		source.WriteString(fmt.Sprintf("locals {\n\tlink_%d = local.link_%d\n}\n", i, i-1))
+	}
+	source.WriteString(`
+resource "cats_cat" "mittens" {
+	name = local.link_64
+}
+`)
+
+	path := createTestFile("test.tf", source.String())
+
+	parser := New(filepath.Dir(path), OptionStopOnHCLError())
+	modules, err := parser.ParseDirectory()
+	require.NoError(t, err)
+	assert.Empty(t, parser.Diagnostics())
+
+	resources := modules[0].GetBlocks().OfType("resource")
+	require.Len(t, resources, 1)
+	name := resources[0].GetAttribute("name")
+	require.Equal(t, cty.String, name.Type())
+	assert.Equal(t, "end", name.Value().AsString())
+}
+
+func Test_ReferenceCycleIsReported(t *testing.T) {
+
+	path := createTestFile("test.tf", `
+locals {
+	a = local.b
+	b = local.a
+}
+
+resource "cats_cat" "mittens" {
+	name = "mittens"
+}
+`)
+
+	parser := New(filepath.Dir(path), OptionStopOnHCLError())
+	modules, err := parser.ParseDirectory()
+	require.NoError(t, err)
+
+	require.Len(t, parser.Diagnostics(), 1)
+	assert.Equal(t, "Cycle detected", parser.Diagnostics()[0].Summary)
+	assert.Contains(t, parser.Diagnostics()[0].Detail, "local.a")
+
+	resources := modules[0].GetBlocks().OfType("resource")
+	require.Len(t, resources, 1)
+	assert.Equal(t, "mittens", resources[0].GetAttribute("name").Value().AsString())
+}
+
+func Test_LoadDirectoryReturnsHCLErrorsAsDiagnostics(t *testing.T) {
+
+	path := createTestFile("good.tf", `
+resource "cats_cat" "mittens" {
+	name = "mittens"
+}
+`)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(path), "bad.tf"), []byte(`resource "cats_cat" {`), 0600))
+
+	files, diags, err := LoadDirectory(filepath.Dir(path), false)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Error(), "bad.tf")
+
+	_, _, err = LoadDirectory(filepath.Dir(path), true)
+	assert.Error(t, err)
+}
+
+func Test_DynamicBlocksAreExpandedOnce(t *testing.T) {
+
+	path := createTestFile("test.tf", `
+resource "cats_cat" "mittens" {
+	dynamic "toy" {
+		for_each = ["mouse", "ball"]
+		content {
+			name = toy.value
+		}
+	}
+}
+`)
+
+	parser := New(filepath.Dir(path), OptionStopOnHCLError())
+	modules, err := parser.ParseDirectory()
+	require.NoError(t, err)
+
+	toys := modules[0].GetResourcesByType("cats_cat")[0].GetBlocks("toy")
+	require.Len(t, toys, 2)
+	assert.Equal(t, "mouse", toys[0].GetAttribute("name").Value().AsString())
+	assert.Equal(t, "ball", toys[1].GetAttribute("name").Value().AsString())
+}
+
+func Test_IndependentModules(t *testing.T) {
+
+	path := createTestFileWithModule(`
+module "first" {
+	source = "../module"
+	input = "one"
+}
+
+module "second" {
+	source = "../module"
+	input = "two"
+}
+
+module "third" {
+	source = "../module"
+	input = "${module.first.mod_result}-${module.second.mod_result}"
+}
+
+output "result" {
+	value = module.third.mod_result
+}
+`,
+		`
+variable "input" {
+	default = "?"
+}
+
+output "mod_result" {
+	value = var.input
+}
+`,
+		"module",
+	)
+
+	for _, workers := range []int{1, 4} {
+		parser := New(path, OptionStopOnHCLError(), OptionWithConcurrency(workers))
+		modules, err := parser.ParseDirectory()
+		require.NoError(t, err)
+		require.Len(t, modules, 4)
+
+		outputs := modules[0].GetBlocks().OfType("output")
+		require.Len(t, outputs, 1)
+		assert.Equal(t, "one-two", outputs[0].GetAttribute("value").Value().AsString())
+	}
+}
+
 func createTestFile(filename, contents string) string {
 	dir, err := ioutil.TempDir(os.TempDir(), "tfsec")
 	if err != nil {
@@ -277,3 +423,81 @@ func createTestFileWithModule(contents string, moduleContents string, moduleName
 
 	return rootPath
 }
+
+func Test_FilesAreCountedPerParser(t *testing.T) {
+
+	first := createTestFile("main.tf", `resource "aws_s3_bucket" "first" {}`)
+	second := createTestFile("main.tf", `
+resource "aws_s3_bucket" "second" {}
+`)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(second), "other.tf"), []byte(`resource "aws_s3_bucket" "third" {}`), 0600))
+
+	firstParser := New(filepath.Dir(first), OptionStopOnHCLError())
+	_, err := firstParser.ParseDirectory()
+	require.NoError(t, err)
+
+	secondParser := New(filepath.Dir(second), OptionStopOnHCLError())
+	_, err = secondParser.ParseDirectory()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, firstParser.CountFiles())
+	assert.Equal(t, []string{first}, firstParser.ParsedFiles())
+	assert.Equal(t, 2, secondParser.CountFiles())
+}
+
+func Test_ParsingFromFS(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"project/main.tf": &fstest.MapFile{Data: []byte(`
+variable "name" {}
+
+module "bucket" {
+	source = "../modules/bucket"
+	name = var.name
+}
+`)},
+		"project/prod.tfvars": &fstest.MapFile{Data: []byte(`name = "logs"`)},
+		"modules/bucket/main.tf": &fstest.MapFile{Data: []byte(`
+variable "name" {}
+
+resource "aws_s3_bucket" "this" {
+	bucket = var.name
+	policy = file("${path.module}/policy.json")
+	tags = {
+		files = join(",", fileset(path.module, "*.json"))
+		exists = fileexists("missing.json")
+	}
+}
+`)},
+		"modules/bucket/policy.json": &fstest.MapFile{Data: []byte(`{}`)},
+	}
+
+	parser := New("project", OptionWithFS(fsys), OptionWithTFVarsPaths([]string{"project/prod.tfvars"}), OptionStopOnHCLError())
+	modules, err := parser.ParseDirectory()
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+
+	buckets := modules[1].GetResourcesByType("aws_s3_bucket")
+	require.Len(t, buckets, 1)
+	bucket := buckets[0]
+
+	assert.Equal(t, "logs", bucket.GetAttribute("bucket").Value().AsString())
+	assert.Equal(t, "{}", bucket.GetAttribute("policy").Value().AsString())
+	tags := bucket.GetAttribute("tags").Value().AsValueMap()
+	assert.Equal(t, "policy.json", tags["files"].AsString())
+	assert.False(t, tags["exists"].True())
+	assert.Equal(t, filepath.Join("modules", "bucket", "main.tf"), bucket.Range().GetFilename())
+	assert.Equal(t, []string{filepath.Join("modules", "bucket", "main.tf"), filepath.Join("project", "main.tf")}, parser.ParsedFiles())
+}
+
+func Test_IgnoreReasons(t *testing.T) {
+	ignores := parseIgnoresFromLine(`# tfsec:ignore:aws-s3-enable-versioning:exp:2026-12-31 reason="static assets bucket" tfsec:ignore:AWS002 tfsec:ignore:AWS003 reason="logged elsewhere" tfsec:ignore:AWS004`)
+	require.Len(t, ignores, 4)
+
+	assert.Equal(t, "aws-s3-enable-versioning", ignores[0].RuleID)
+	assert.NotNil(t, ignores[0].Expiry)
+	assert.Equal(t, "static assets bucket", ignores[0].Reason)
+	assert.Equal(t, "logged elsewhere", ignores[1].Reason)
+	assert.Equal(t, "logged elsewhere", ignores[2].Reason)
+	assert.Equal(t, "", ignores[3].Reason)
+}
@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/bmatcuk/doublestar"
+)
+
+// IgnorePolicy ignores results matching all of its fields which are set, so that exceptions can be kept in one place rather than in comments
+type IgnorePolicy struct {
+	// RuleID is the ID, legacy ID or pattern of the rules to ignore
+	RuleID string
+	// Root is the directory Path is relative to
+	Root string
+	// Path is a pattern such as prod/**/*.tf, matched against the path of the file containing the result
+	Path string
+	// Resource is the address of a resource or module, or a pattern matching it, e.g. module.network.aws_security_group.bastion
+	Resource string
+	// Workspace restricts the policy to scans of the given workspace
+	Workspace     string
+	Justification string
+	Owner         string
+	// Expiry is when the policy stops applying, if set
+	Expiry *time.Time
+	// Filename and Line give where the policy was defined
+	Filename string
+	Line     int
+}
+
+// Expired returns true if the policy has an expiry which has passed
+func (p IgnorePolicy) Expired() bool {
+	return p.Expiry != nil && time.Now().After(*p.Expiry)
+}
+
+// covers returns true if the policy applies to the given result
+func (p IgnorePolicy) covers(result rules.Result, legacyID string, workspace string) bool {
+	if p.Expired() {
+		return false
+	}
+	if p.Workspace != "" && p.Workspace != workspace {
+		return false
+	}
+	if !checkInList(result.Rule().LongID(), legacyID, []string{p.RuleID}) {
+		return false
+	}
+	if p.Path != "" && !p.coversFile(result) {
+		return false
+	}
+	if p.Resource != "" && !matchResource(p.Resource, ResourceAddress(result)) {
+		return false
+	}
+	return true
+}
+
+func (p IgnorePolicy) coversFile(result rules.Result) bool {
+	if result.NarrowestRange() == nil {
+		return false
+	}
+	filename := result.NarrowestRange().GetFilename()
+	if p.Root != "" {
+		rel, err := filepath.Rel(p.Root, filename)
+		if err != nil {
+			return false
+		}
+		filename = rel
+	}
+	matched, err := doublestar.Match(filepath.ToSlash(p.Path), filepath.ToSlash(filename))
+	return err == nil && matched
+}
+
+// matchResource returns true if the address matches the pattern, or is within a module matching the pattern
+func matchResource(pattern string, address string) bool {
+	if address == "" {
+		return false
+	}
+	if pattern == address || strings.HasPrefix(address, pattern+".") {
+		return true
+	}
+	matched, err := path.Match(pattern, address)
+	return err == nil && matched
+}
+
+// ResourceAddress returns the terraform address of the resource a result was found in, e.g. module.network.aws_security_group.bastion
+func ResourceAddress(result rules.Result) string {
+	code := result.CodeBlockMetadata()
+	if code == nil || code.Reference() == nil {
+		return ""
+	}
+	if ref, ok := code.Reference().(*block.Reference); ok {
+		return strings.ReplaceAll(ref.HumanReadable(), ":", ".")
+	}
+	address := code.Reference().String()
+	if hclRange, ok := result.NarrowestRange().(block.HCLRange); ok && hclRange.GetModule() != "" && hclRange.GetModule() != "root" {
+		address = strings.ReplaceAll(hclRange.GetModule(), ":", ".") + "." + address
+	}
+	return address
+}
+
+// ignoredByPolicy returns the index of the policy which covers the result, or -1 if there is none
+func (scanner *Scanner) ignoredByPolicy(result rules.Result) int {
+	legacyID := scanner.findLegacyID(result.Rule().LongID())
+	for i, policy := range scanner.ignorePolicies {
+		if policy.covers(result, legacyID, scanner.workspaceName) {
+			return i
+		}
+	}
+	return -1
+}
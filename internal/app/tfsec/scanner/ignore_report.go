@@ -0,0 +1,184 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/defsec/provider"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+)
+
+// Rules for the results added by OptionReportIgnores, which are reported like any other result so they appear in every output format
+var (
+	UnusedIgnoreRule = rules.Rule{
+		Provider:    provider.GeneralProvider,
+		Service:     "ignores",
+		ShortCode:   "no-unused",
+		Summary:     "Ignores should suppress at least one result",
+		Explanation: "An ignore which no longer matches any result is likely to be left over from code which has since changed, and could hide a new problem in the future.",
+		Impact:      "Stale ignores may hide future problems",
+		Resolution:  "Remove the ignore",
+		Severity:    severity.Low,
+	}
+	ExpiredIgnoreRule = rules.Rule{
+		Provider:    provider.GeneralProvider,
+		Service:     "ignores",
+		ShortCode:   "no-expired",
+		Summary:     "Ignores should be removed or renewed once they have expired",
+		Explanation: "An expired ignore no longer suppresses any results, so should be removed once the problem is fixed, or renewed if it is still accepted.",
+		Impact:      "Expired ignores make it unclear which problems are accepted",
+		Resolution:  "Remove the ignore or extend its expiry",
+		Severity:    severity.Low,
+	}
+	UnknownIgnoreRule = rules.Rule{
+		Provider:    provider.GeneralProvider,
+		Service:     "ignores",
+		ShortCode:   "no-unknown-rules",
+		Summary:     "Ignores should refer to rules which exist",
+		Explanation: "An ignore for a rule ID which does not exist, for example due to a typo, will never suppress any results.",
+		Impact:      "The intended result is not ignored",
+		Resolution:  "Correct the rule ID of the ignore",
+		Severity:    severity.Low,
+	}
+)
+
+// IsIgnoreFinding returns true if the rule is one of those reported by OptionReportIgnores, which describe ignores rather than code
+func IsIgnoreFinding(r rules.Rule) bool {
+	for _, findingRule := range []rules.Rule{UnusedIgnoreRule, ExpiredIgnoreRule, UnknownIgnoreRule} {
+		if r.LongID() == findingRule.LongID() {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreUsage records which ignore comments and policies suppressed at least one failed result
+type ignoreUsage struct {
+	comments map[string]bool
+	policies map[int]bool
+}
+
+func newIgnoreUsage() *ignoreUsage {
+	return &ignoreUsage{
+		comments: make(map[string]bool),
+		policies: make(map[int]bool),
+	}
+}
+
+// commentKey identifies an ignore comment, which may be copied to each module it is used in
+func commentKey(ignore block.Ignore) string {
+	return fmt.Sprintf("%s:%d:%s:%s", ignore.Range.GetFilename(), ignore.Range.GetStartLine(), ignore.RuleID, ignore.Workspace)
+}
+
+func (u *ignoreUsage) useComment(ignore block.Ignore, result rules.Result) {
+	if result.Status() == rules.StatusFailed {
+		u.comments[commentKey(ignore)] = true
+	}
+}
+
+func (u *ignoreUsage) usePolicy(index int, result rules.Result) {
+	if result.Status() == rules.StatusFailed {
+		u.policies[index] = true
+	}
+}
+
+// ignoreFindings returns results for ignore comments and policies which are unused, expired or refer to unknown rules
+func (scanner *Scanner) ignoreFindings(ignores block.Ignores, usage *ignoreUsage) []rules.Result {
+	var findings rules.Results
+
+	reported := make(map[string]bool)
+	for _, ignore := range ignores {
+		key := commentKey(ignore)
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+
+		source := ignoreSource{
+			rng:         ignore.Range,
+			description: fmt.Sprintf("tfsec:%s:%s", ignore.Directive(), ignore.RuleID),
+		}
+		ruleID := strings.SplitN(ignore.RuleID, "[", 2)[0]
+		switch {
+		case ruleID != "*" && !scanner.isKnownRuleID(ruleID, false):
+			findings = append(findings, source.finding(UnknownIgnoreRule, fmt.Sprintf("Ignore refers to unknown rule '%s'", ruleID)))
+		case ignore.Expiry != nil && time.Now().After(*ignore.Expiry):
+			findings = append(findings, source.finding(ExpiredIgnoreRule, fmt.Sprintf("Ignore for '%s' expired on %s", ruleID, ignore.Expiry.Format("2006-01-02"))))
+		case !usage.comments[key] && (ignore.Workspace == "" || ignore.Workspace == scanner.workspaceName):
+			findings = append(findings, source.finding(UnusedIgnoreRule, fmt.Sprintf("Ignore for '%s' did not match any results", ruleID)))
+		}
+	}
+
+	for i, policy := range scanner.ignorePolicies {
+		source := ignoreSource{
+			rng:         block.NewRange(policy.Filename, policy.Line, policy.Line, "root"),
+			description: fmt.Sprintf("ignore policy for %s", policy.RuleID),
+		}
+		switch {
+		case !scanner.isKnownRuleID(policy.RuleID, true):
+			findings = append(findings, source.finding(UnknownIgnoreRule, fmt.Sprintf("Ignore policy refers to unknown rule '%s'", policy.RuleID)))
+		case policy.Expired():
+			findings = append(findings, source.finding(ExpiredIgnoreRule, fmt.Sprintf("Ignore policy for '%s' expired on %s", policy.RuleID, policy.Expiry.Format("2006-01-02"))))
+		case !usage.policies[i] && (policy.Workspace == "" || policy.Workspace == scanner.workspaceName):
+			findings = append(findings, source.finding(UnusedIgnoreRule, fmt.Sprintf("Ignore policy for '%s' did not match any results", policy.RuleID)))
+		}
+	}
+
+	return findings
+}
+
+// isKnownRuleID returns true if the ID matches the long or legacy ID of any rule run by the scanner, as a pattern if allowed
+func (scanner *Scanner) isKnownRuleID(id string, allowPatterns bool) bool {
+	for _, r := range scanner.rules {
+		if allowPatterns {
+			if MatchRuleID(id, r.ID()) || (r.LegacyID != "" && MatchRuleID(id, r.LegacyID)) {
+				return true
+			}
+		} else if id == r.ID() || (r.LegacyID != "" && id == r.LegacyID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreSource is the location of an ignore, which findings about the ignore are reported against
+type ignoreSource struct {
+	rng         block.HCLRange
+	description string
+}
+
+func (s ignoreSource) finding(rule rules.Rule, description string) rules.Result {
+	var results rules.Results
+	results.Add(description, &s)
+	results.SetRule(rule)
+	return results[0]
+}
+
+func (s *ignoreSource) GetMetadata() *types.Metadata {
+	metadata := types.NewMetadata(s.rng, &ignoreReference{description: s.description})
+	return &metadata
+}
+
+func (s *ignoreSource) GetRawValue() interface{} {
+	return nil
+}
+
+type ignoreReference struct {
+	description string
+}
+
+func (r *ignoreReference) String() string {
+	return r.description
+}
+
+func (r *ignoreReference) LogicalID() string {
+	return r.description
+}
+
+func (r *ignoreReference) RefersTo(types.Reference) bool {
+	return false
+}
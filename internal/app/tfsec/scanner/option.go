@@ -1,5 +1,13 @@
 package scanner
 
+import (
+	"io"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+)
+
 type Option func(s *Scanner)
 
 func OptionIncludePassed() func(s *Scanner) {
@@ -26,6 +34,34 @@ func OptionIncludeRules(ruleIDs []string) func(s *Scanner) {
 	}
 }
 
+// OptionIncludeProviders only returns results for rules belonging to the given providers, e.g. aws or google, which may contain wildcards
+func OptionIncludeProviders(providers []string) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.includedProviders = providers
+	}
+}
+
+// OptionExcludeProviders prevents results for rules belonging to the given providers, which may contain wildcards
+func OptionExcludeProviders(providers []string) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.excludedProviders = providers
+	}
+}
+
+// OptionIncludeServices only returns results for rules belonging to the given services, given as provider-service e.g. aws-s3, which may contain wildcards
+func OptionIncludeServices(services []string) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.includedServices = services
+	}
+}
+
+// OptionExcludeServices prevents results for rules belonging to the given services, given as provider-service e.g. aws-s3, which may contain wildcards
+func OptionExcludeServices(services []string) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.excludedServices = services
+	}
+}
+
 func OptionStopOnErrors() func(s *Scanner) {
 	return func(s *Scanner) {
 		s.ignoreCheckErrors = false
@@ -43,3 +79,67 @@ func OptionWithSingleThread(single bool) func(s *Scanner) {
 		s.useSingleThread = single
 	}
 }
+
+// OptionWithRules replaces the rules run by the scanner, which default to all registered rules
+func OptionWithRules(rules []rule.Rule) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.rules = append([]rule.Rule{}, rules...)
+	}
+}
+
+// OptionWithCustomRules adds rules, such as loaded custom checks, to be run by this scanner only
+func OptionWithCustomRules(rules []rule.Rule) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.rules = append(s.rules, rules...)
+	}
+}
+
+// OptionWithDebugWriter enables debug logging for this scanner only, writing to the given writer
+func OptionWithDebugWriter(writer io.Writer) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.debug = debug.New(writer)
+	}
+}
+
+// OptionWithMinimumSeverity removes results with a lower severity than the given severity, after any overrides have been applied
+func OptionWithMinimumSeverity(minimum severity.Severity) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.minimumSeverity = minimum
+	}
+}
+
+// OptionWithPathScopes applies different settings to results found in files matching each scope, where scope paths are relative to the given root
+func OptionWithPathScopes(root string, scopes []PathScope) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.scopeRoot = root
+		s.scopes = scopes
+	}
+}
+
+// OptionWithIgnorePolicies ignores results covered by any of the given policies, unless ignored results are included
+func OptionWithIgnorePolicies(policies []IgnorePolicy) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.ignorePolicies = policies
+	}
+}
+
+// OptionReportIgnores adds results for ignore comments and policies which are unused, expired or refer to unknown rules
+func OptionReportIgnores() func(s *Scanner) {
+	return func(s *Scanner) {
+		s.reportIgnores = true
+	}
+}
+
+// OptionRequireIgnoreReason disregards ignore comments which do not give a reason="..."
+func OptionRequireIgnoreReason() func(s *Scanner) {
+	return func(s *Scanner) {
+		s.requireIgnoreReason = true
+	}
+}
+
+// OptionWithSeverityOverrides changes the severity of results for the given rules, keyed by long or legacy ID
+func OptionWithSeverityOverrides(overrides map[string]string) func(s *Scanner) {
+	return func(s *Scanner) {
+		s.severityOverrides = overrides
+	}
+}
@@ -32,13 +32,23 @@ func DeregisterCheckRule(r rule.Rule) {
 
 // GetRegisteredRules provides all Checks which have been registered with this package
 func GetRegisteredRules() []rule.Rule {
-	sort.Slice(registeredRules, func(i, j int) bool {
-		return registeredRules[i].ID() < registeredRules[j].ID()
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
+	registered := make([]rule.Rule, len(registeredRules))
+	copy(registered, registeredRules)
+	sortRules(registered)
+	return registered
+}
+
+func sortRules(rules []rule.Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID() < rules[j].ID()
 	})
-	return registeredRules
 }
 
 func GetRuleById(ID string) (*rule.Rule, error) {
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
 	for _, r := range registeredRules {
 		if r.ID() == ID {
 			return &r, nil
@@ -48,6 +58,8 @@ func GetRuleById(ID string) (*rule.Rule, error) {
 }
 
 func GetRuleByLegacyID(legacyID string) (*rule.Rule, error) {
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
 	for _, r := range registeredRules {
 		if r.LegacyID == legacyID {
 			return &r, nil
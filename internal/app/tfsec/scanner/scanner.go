@@ -1,50 +1,114 @@
 package scanner
 
 import (
+	"fmt"
+	"path"
 	"runtime"
 	"sort"
+	"strings"
 
 	"github.com/aquasecurity/defsec/metrics"
 	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/adapter"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/pkg/rule"
 )
 
-// Scanner scans HCL blocks by running all registered rules against them
+// Scanner scans HCL blocks by running its rules against them. Each scanner owns its rules, so scanners with different custom checks can be used concurrently.
 type Scanner struct {
-	includePassed     bool
-	includeIgnored    bool
-	excludedRuleIDs   []string
-	includedRuleIDs   []string
-	ignoreCheckErrors bool
-	workspaceName     string
-	useSingleThread   bool
+	rules               []rule.Rule
+	includePassed       bool
+	includeIgnored      bool
+	excludedRuleIDs     []string
+	includedRuleIDs     []string
+	includedProviders   []string
+	excludedProviders   []string
+	includedServices    []string
+	excludedServices    []string
+	ignoreCheckErrors   bool
+	workspaceName       string
+	useSingleThread     bool
+	severityOverrides   map[string]string
+	minimumSeverity     severity.Severity
+	scopeRoot           string
+	scopes              []PathScope
+	scopedRuleIDs       map[string]bool
+	ignorePolicies      []IgnorePolicy
+	reportIgnores       bool
+	requireIgnoreReason bool
+	debug               debug.Logger
 }
 
-// New creates a new Scanner
+// New creates a new Scanner which runs all rules registered at the time of creation, along with any rules provided as options
 func New(options ...Option) *Scanner {
 	s := &Scanner{
+		rules:             GetRegisteredRules(),
 		ignoreCheckErrors: true,
 	}
 	for _, option := range options {
 		option(s)
 	}
+	s.addScopedRules()
+	sortRules(s.rules)
 	return s
 }
 
-// Find element in list
+// Rules returns all rules which are run by this scanner
+func (scanner *Scanner) Rules() []rule.Rule {
+	return scanner.rules
+}
+
+// Find element in list, which may contain wildcards
 func checkInList(id string, legacyID string, list []string) bool {
 	for _, codeIgnored := range list {
-		if codeIgnored == id || (legacyID != "" && codeIgnored == legacyID) {
+		if MatchRuleID(codeIgnored, id) || (legacyID != "" && MatchRuleID(codeIgnored, legacyID)) {
 			return true
 		}
 	}
 	return false
 }
 
+// MatchRuleID returns true if the rule ID matches the pattern, which is either an ID or contains * and ? wildcards, e.g. aws-s3-* or *-enable-logging
+func MatchRuleID(pattern string, id string) bool {
+	if pattern == id {
+		return true
+	}
+	matched, err := path.Match(pattern, id)
+	return err == nil && matched
+}
+
+// isSelected returns true if results for the rule should be returned, based on the included and excluded rules, providers and services.
+// Each include list is ignored if empty, otherwise the rule must match it, and the rule must not match any of the exclude lists.
+func (scanner *Scanner) isSelected(r rules.Rule) bool {
+	longID := r.LongID()
+	legacyID := scanner.findLegacyID(longID)
+	provider := strings.ToLower(string(r.Provider))
+	service := strings.ToLower(fmt.Sprintf("%s-%s", r.Provider, r.Service))
+
+	if len(scanner.includedRuleIDs) > 0 && !checkInList(longID, legacyID, scanner.includedRuleIDs) {
+		return false
+	}
+	if len(scanner.includedProviders) > 0 && !checkInList(provider, "", scanner.includedProviders) {
+		return false
+	}
+	if len(scanner.includedServices) > 0 && !checkInList(service, "", scanner.includedServices) {
+		return false
+	}
+	return !checkInList(provider, "", scanner.excludedProviders) && !checkInList(service, "", scanner.excludedServices)
+}
+
 func FindLegacyID(longID string) string {
-	for _, rule := range GetRegisteredRules() {
+	return findLegacyID(GetRegisteredRules(), longID)
+}
+
+func (scanner *Scanner) findLegacyID(longID string) string {
+	return findLegacyID(scanner.rules, longID)
+}
+
+func findLegacyID(candidates []rule.Rule, longID string) string {
+	for _, rule := range candidates {
 		if rule.ID() == longID {
 			return rule.LegacyID
 		}
@@ -53,6 +117,12 @@ func FindLegacyID(longID string) string {
 }
 
 func (scanner *Scanner) Scan(modules []block.Module) (rules.Results, error) {
+	results, _, err := scanner.ScanWithSuppressions(modules)
+	return results, err
+}
+
+// ScanWithSuppressions scans the modules as Scan does, also returning why each ignored result was ignored when ignored results are included
+func (scanner *Scanner) ScanWithSuppressions(modules []block.Module) (rules.Results, Suppressions, error) {
 
 	adaptationTimer := metrics.Timer("timings", "adaptation")
 	adaptationTimer.Start()
@@ -69,51 +139,80 @@ func (scanner *Scanner) Scan(modules []block.Module) (rules.Results, error) {
 
 	checkTimer := metrics.Timer("timings", "running checks")
 	checkTimer.Start()
-	results, err := NewPool(threads, GetRegisteredRules(), modules, infra, scanner.ignoreCheckErrors).Run()
+	results, err := NewPool(threads, scanner.rules, modules, infra, scanner.ignoreCheckErrors).Run()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	checkTimer.Stop()
 
-	var resultsAfterIgnores []rules.Result
-	if !scanner.includeIgnored {
-		var ignores block.Ignores
-		for _, module := range modules {
-			ignores = append(ignores, module.Ignores()...)
+	var ignores, effective block.Ignores
+	for _, module := range modules {
+		ignores = append(ignores, module.Ignores()...)
+	}
+	for _, ignore := range ignores {
+		if scanner.requireIgnoreReason && ignore.Reason == "" {
+			scanner.debug.Log("Disregarding the ignore for '%s' at %s as it has no reason", ignore.RuleID, ignore.Range)
+			continue
 		}
+		effective = append(effective, ignore)
+	}
+	usage := newIgnoreUsage()
+	suppressions := make(Suppressions)
 
-		for _, result := range results {
-			if !scanner.includeIgnored && ignores.Covering(
-				result.NarrowestRange(),
-				scanner.workspaceName,
-				result.Rule().LongID(),
-				FindLegacyID(result.Rule().LongID()),
-			) != nil {
-				debug.Log("Ignoring '%s'", result.Rule().LongID())
+	var resultsAfterIgnores []rules.Result
+	for _, result := range results {
+		if ignore := effective.Covering(
+			result.NarrowestRange(),
+			scanner.workspaceName,
+			result.Rule().LongID(),
+			scanner.findLegacyID(result.Rule().LongID()),
+		); ignore != nil {
+			usage.useComment(*ignore, result)
+			if !scanner.includeIgnored {
+				scanner.debug.Log("Ignoring '%s'", result.Rule().LongID())
 				continue
 			}
-			resultsAfterIgnores = append(resultsAfterIgnores, result)
+			suppressions.addComment(result, *ignore)
+		} else if index := scanner.ignoredByPolicy(result); index >= 0 {
+			usage.usePolicy(index, result)
+			policy := scanner.ignorePolicies[index]
+			if !scanner.includeIgnored {
+				scanner.debug.Log("Ignoring '%s' at %s due to the ignore policy at %s:%d: %s", result.Rule().LongID(), result.NarrowestRange(), policy.Filename, policy.Line, policy.Justification)
+				continue
+			}
+			suppressions.addPolicy(result, policy)
 		}
-	} else {
-		resultsAfterIgnores = results
+		resultsAfterIgnores = append(resultsAfterIgnores, result)
 	}
 
 	metrics.Counter("results", "ignored").Increment(len(results) - len(resultsAfterIgnores))
 
-	filtered := scanner.filterResults(resultsAfterIgnores)
+	if scanner.reportIgnores {
+		resultsAfterIgnores = append(resultsAfterIgnores, scanner.ignoreFindings(ignores, usage)...)
+	}
+
+	filtered := scanner.filterResults(resultsAfterIgnores, suppressions)
+	filtered = scanner.applyScopes(filtered, suppressions)
 	scanner.sortResults(filtered)
-	return filtered, nil
+	return filtered, suppressions, nil
 }
 
-func (scanner *Scanner) filterResults(results []rules.Result) []rules.Result {
+// filterResults drops results for rules which are not selected, and for excluded rules unless ignored results are included, in which
+// case the exclusion is recorded as a suppression. Findings about ignores bypass the included rules and the included and excluded
+// providers and services, which select the code being checked, though they can still be excluded by rule ID.
+func (scanner *Scanner) filterResults(results []rules.Result, suppressions Suppressions) []rules.Result {
 	var filtered []rules.Result
 	excludeCounter := metrics.Counter("results", "excluded")
 	for _, result := range results {
-		if len(scanner.includedRuleIDs) == 0 || len(scanner.includedRuleIDs) > 0 && checkInList(result.Rule().LongID(), FindLegacyID(result.Rule().LongID()), scanner.includedRuleIDs) {
-			if !scanner.includeIgnored && checkInList(result.Rule().LongID(), FindLegacyID(result.Rule().LongID()), scanner.excludedRuleIDs) {
+		if IsIgnoreFinding(result.Rule()) || scanner.isSelected(result.Rule()) {
+			excluded := checkInList(result.Rule().LongID(), scanner.findLegacyID(result.Rule().LongID()), scanner.excludedRuleIDs)
+			if excluded && !scanner.includeIgnored {
 				excludeCounter.Increment(1)
-				debug.Log("Ignoring '%s'", result.Rule().LongID())
+				scanner.debug.Log("Ignoring '%s'", result.Rule().LongID())
 			} else if scanner.includePassed || result.Status() != rules.StatusPassed {
+				if excluded {
+					suppressions.addExclusion(result)
+				}
 				filtered = append(filtered, result)
 			}
 		}
@@ -121,6 +220,23 @@ func (scanner *Scanner) filterResults(results []rules.Result) []rules.Result {
 	return filtered
 }
 
+// overrideSeverity changes the severity of the result if its rule has an override, which can be keyed by long or legacy ID.
+// If both are present, the override keyed by long ID is used.
+func overrideSeverity(result rules.Result, legacyID string, severityOverrides map[string]string) rules.Result {
+	sev, ok := severityOverrides[result.Rule().LongID()]
+	if !ok && legacyID != "" {
+		sev, ok = severityOverrides[legacyID]
+	}
+	if !ok {
+		return result
+	}
+	overrides := rules.Results([]rules.Result{result})
+	override := result.Rule()
+	override.Severity = severity.Severity(sev)
+	overrides.SetRule(override)
+	return overrides[0]
+}
+
 func (scanner *Scanner) sortResults(results []rules.Result) {
 	sort.Slice(results, func(i, j int) bool {
 		switch {
@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"path/filepath"
+
+	"github.com/aquasecurity/defsec/metrics"
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+	"github.com/bmatcuk/doublestar"
+)
+
+// PathScope changes how results are filtered for files matching any of its paths. Where several scopes match a file, they are applied in order.
+type PathScope struct {
+	// Paths are patterns such as prod/** or **/sandbox/*.tf, matched against the path of each file relative to the scope root
+	Paths []string
+	// ExcludedRuleIDs prevents results for the given rules, in addition to any excluded for the whole scan
+	ExcludedRuleIDs []string
+	// SeverityOverrides changes the severity of results, taking precedence over overrides for the whole scan
+	SeverityOverrides map[string]string
+	// MinimumSeverity replaces the minimum severity for the whole scan, if set
+	MinimumSeverity severity.Severity
+	// CustomRules are only reported for files matching the scope
+	CustomRules []rule.Rule
+}
+
+var severityRanks = map[severity.Severity]int{
+	severity.Low:      1,
+	severity.Medium:   2,
+	severity.High:     3,
+	severity.Critical: 4,
+}
+
+// matches returns true if the file, given relative to the scope root with forward slashes, matches any of the scope paths
+func (p PathScope) matches(relPath string) bool {
+	for _, pattern := range p.Paths {
+		if matched, err := doublestar.Match(filepath.ToSlash(pattern), relPath); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (p PathScope) hasRule(longID string) bool {
+	for _, r := range p.CustomRules {
+		if r.ID() == longID {
+			return true
+		}
+	}
+	return false
+}
+
+// addScopedRules adds the custom rules of each scope to the rules run by the scanner, and records those which only run within scopes
+func (scanner *Scanner) addScopedRules() {
+	known := make(map[string]bool)
+	for _, r := range scanner.rules {
+		known[r.ID()] = true
+	}
+	scanner.scopedRuleIDs = make(map[string]bool)
+	for _, scope := range scanner.scopes {
+		for _, r := range scope.CustomRules {
+			if known[r.ID()] {
+				continue
+			}
+			known[r.ID()] = true
+			scanner.scopedRuleIDs[r.ID()] = true
+			scanner.rules = append(scanner.rules, r)
+		}
+	}
+}
+
+// matchingScopes returns the scopes which apply to the file a result was found in
+func (scanner *Scanner) matchingScopes(result rules.Result) []PathScope {
+	if len(scanner.scopes) == 0 || result.NarrowestRange() == nil {
+		return nil
+	}
+	relPath, err := filepath.Rel(scanner.scopeRoot, result.NarrowestRange().GetFilename())
+	if err != nil {
+		return nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	var matching []PathScope
+	for _, scope := range scanner.scopes {
+		if scope.matches(relPath) {
+			matching = append(matching, scope)
+		}
+	}
+	return matching
+}
+
+// applyScopes filters and changes the severity of results according to the minimum severity, the severity overrides and the scopes which apply to each result
+func (scanner *Scanner) applyScopes(results []rules.Result, suppressions Suppressions) []rules.Result {
+	var filtered []rules.Result
+	for _, result := range results {
+		longID := result.Rule().LongID()
+		legacyID := scanner.findLegacyID(longID)
+		scopes := scanner.matchingScopes(result)
+
+		if scanner.scopedRuleIDs[longID] && !scopesHaveRule(scopes, longID) {
+			continue
+		}
+
+		overrides := []map[string]string{scanner.severityOverrides}
+		minimumSeverity := scanner.minimumSeverity
+		var excluded bool
+		for _, scope := range scopes {
+			if checkInList(longID, legacyID, scope.ExcludedRuleIDs) {
+				excluded = true
+			}
+			overrides = append(overrides, scope.SeverityOverrides)
+			if scope.MinimumSeverity != severity.None {
+				minimumSeverity = scope.MinimumSeverity
+			}
+		}
+		if excluded && !scanner.includeIgnored {
+			metrics.Counter("results", "excluded").Increment(1)
+			scanner.debug.Log("Ignoring '%s' at %s", longID, result.NarrowestRange())
+			continue
+		}
+		if excluded {
+			suppressions.addExclusion(result)
+		}
+
+		for _, override := range overrides {
+			result = overrideSeverity(result, legacyID, override)
+		}
+
+		if severityRanks[result.Rule().Severity] < severityRanks[minimumSeverity] {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+func scopesHaveRule(scopes []PathScope, longID string) bool {
+	for _, scope := range scopes {
+		if scope.hasRule(longID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+)
+
+// Suppression kinds, named as in SARIF
+const (
+	SuppressionInSource = "inSource"
+	SuppressionExternal = "external"
+)
+
+// Suppression describes the ignore which covers a result included by OptionIncludeIgnored
+type Suppression struct {
+	// Kind is SuppressionInSource for ignore comments, or SuppressionExternal for ignore files and rules excluded by the config
+	Kind     string `json:"kind"`
+	Reason   string `json:"reason,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+}
+
+// Suppressions holds the suppression of each ignored result in a scan
+type Suppressions map[string]Suppression
+
+// Lookup returns the suppression of the given result, or nil if it was not ignored
+func (s Suppressions) Lookup(result rules.Result) *Suppression {
+	if suppression, ok := s[SuppressionKey(result)]; ok {
+		return &suppression
+	}
+	return nil
+}
+
+// SuppressionKey identifies a result, regardless of any changes to its severity
+func SuppressionKey(result rules.Result) string {
+	rng := result.NarrowestRange()
+	return fmt.Sprintf("%s:%s:%d:%d:%s", result.Rule().LongID(), rng.GetFilename(), rng.GetStartLine(), rng.GetEndLine(), result.Description())
+}
+
+func (s Suppressions) addComment(result rules.Result, ignore block.Ignore) {
+	s[SuppressionKey(result)] = Suppression{
+		Kind:     SuppressionInSource,
+		Reason:   ignore.Reason,
+		Filename: ignore.Range.GetFilename(),
+		Line:     ignore.Range.GetStartLine(),
+	}
+}
+
+func (s Suppressions) addPolicy(result rules.Result, policy IgnorePolicy) {
+	s[SuppressionKey(result)] = Suppression{
+		Kind:     SuppressionExternal,
+		Reason:   policy.Justification,
+		Owner:    policy.Owner,
+		Filename: policy.Filename,
+		Line:     policy.Line,
+	}
+}
+
+// excludedReason is the reason given for results of rules excluded by the config or a path override
+const excludedReason = "Excluded by the tfsec config"
+
+// addExclusion records that the rule of the result was excluded, unless the result was already ignored
+func (s Suppressions) addExclusion(result rules.Result) {
+	key := SuppressionKey(result)
+	if _, ok := s[key]; ok {
+		return
+	}
+	s[key] = Suppression{
+		Kind:   SuppressionExternal,
+		Reason: excludedReason,
+	}
+}
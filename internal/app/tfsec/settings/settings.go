@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+)
+
+// PathScopes converts the path overrides in the config into scopes for the scanner, loading any custom checks they specify.
+// Relative custom check directories are resolved against root, unless root is empty.
+func PathScopes(root string, overrides []config.PathOverride, logger debug.Logger, options ...custom.Option) ([]scanner.PathScope, error) {
+	var scopes []scanner.PathScope
+	for _, override := range overrides {
+		scope := scanner.PathScope{
+			Paths:             override.Paths,
+			ExcludedRuleIDs:   override.ExcludedChecks,
+			SeverityOverrides: override.SeverityOverrides,
+			MinimumSeverity:   severity.StringToSeverity(override.MinimumSeverity),
+		}
+		if override.CustomCheckDir != "" {
+			scopedCheckDir := override.CustomCheckDir
+			if !filepath.IsAbs(scopedCheckDir) && root != "" {
+				scopedCheckDir = filepath.Join(root, scopedCheckDir)
+			}
+			logger.Log("Loading custom checks for %s from %s", strings.Join(override.Paths, ","), scopedCheckDir)
+			scopedRules, err := custom.Load(scopedCheckDir, options...)
+			if err != nil {
+				return nil, err
+			}
+			scope.CustomRules = scopedRules
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// IgnorePolicies loads the entries from the given ignore files as policies for the scanner
+func IgnorePolicies(ignoreFilePaths []string, logger debug.Logger) ([]scanner.IgnorePolicy, error) {
+	entries, err := config.LoadIgnores(ignoreFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	var policies []scanner.IgnorePolicy
+	for _, entry := range entries {
+		logger.Log("Loaded ignore for %s from %s", entry.Rule, entry.Filename())
+		policies = append(policies, scanner.IgnorePolicy{
+			RuleID:        entry.Rule,
+			Root:          entry.Root(),
+			Path:          entry.Path,
+			Resource:      entry.Resource,
+			Workspace:     entry.Workspace,
+			Justification: entry.Justification,
+			Owner:         entry.Owner,
+			Expiry:        entry.ExpiryTime(),
+			Filename:      entry.Filename(),
+			Line:          entry.Line(),
+		})
+	}
+	return policies, nil
+}
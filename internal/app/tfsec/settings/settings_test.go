@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scopedCheckFile = `
+checks:
+- code: CUS601
+  description: Instances must have an owner
+  requiredTypes: [resource]
+  requiredLabels: [aws_instance]
+  severity: LOW
+  matchSpec:
+    name: tags
+    action: contains
+    value: Owner
+`
+
+func TestPathScopesResolveCheckDirsAgainstRoot(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "checks"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "checks", "owner_tfchecks.yaml"), []byte(scopedCheckFile), 0600))
+
+	scopes, err := PathScopes(root, []config.PathOverride{
+		{
+			Paths:           []string{"legacy/**"},
+			ExcludedChecks:  []string{"aws-s3-enable-versioning"},
+			MinimumSeverity: "HIGH",
+		},
+		{
+			Paths:          []string{"teams/**"},
+			CustomCheckDir: "checks",
+		},
+	}, debug.Logger{})
+	require.NoError(t, err)
+	require.Len(t, scopes, 2)
+
+	assert.Equal(t, []string{"aws-s3-enable-versioning"}, scopes[0].ExcludedRuleIDs)
+	assert.Equal(t, severity.High, scopes[0].MinimumSeverity)
+	assert.Empty(t, scopes[0].CustomRules)
+
+	require.Len(t, scopes[1].CustomRules, 1)
+	assert.Equal(t, "custom-custom-cus601", scopes[1].CustomRules[0].ID())
+
+	// without a root, the directory is relative to the working directory, where there are no checks
+	scopes, err = PathScopes("", []config.PathOverride{{Paths: []string{"teams/**"}, CustomCheckDir: "checks"}}, debug.Logger{})
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Empty(t, scopes[0].CustomRules)
+}
+
+func TestIgnorePoliciesAreConvertedFromEntries(t *testing.T) {
+	root := t.TempDir()
+	ignoreFile := filepath.Join(root, ".tfsec", "ignores.yml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(ignoreFile), 0700))
+	require.NoError(t, ioutil.WriteFile(ignoreFile, []byte(`
+ignores:
+  - rule: aws-s3-*
+    path: prod/**
+    resource: aws_s3_bucket.logs
+    justification: Accepted by the security team
+    owner: security@example.com
+    expiry: 2030-06-01
+`), 0600))
+
+	policies, err := IgnorePolicies([]string{ignoreFile}, debug.Logger{})
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	policy := policies[0]
+	assert.Equal(t, "aws-s3-*", policy.RuleID)
+	assert.Equal(t, root, policy.Root)
+	assert.Equal(t, "prod/**", policy.Path)
+	assert.Equal(t, "aws_s3_bucket.logs", policy.Resource)
+	assert.Equal(t, "security@example.com", policy.Owner)
+	assert.Equal(t, ignoreFile, policy.Filename)
+	assert.Equal(t, 3, policy.Line)
+	require.NotNil(t, policy.Expiry)
+	assert.Equal(t, "2030-06-01", policy.Expiry.Format("2006-01-02"))
+}
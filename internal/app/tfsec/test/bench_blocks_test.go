@@ -20,13 +20,13 @@ func BenchmarkBlockParsing(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		modules, err := parser.New(fs.RealPath("/project"), parser.OptionStopOnHCLError()).ParseDirectory()
+		modules, err := parser.New(fs.Path("/project"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 		if err != nil {
 			panic(err)
 		}
 
 		for _, m := range modules {
-			block.NewHCLModule(fs.RealPath("/project"), "", m.GetBlocks(), nil)
+			block.NewHCLModule(fs.Path("/project"), "", m.GetBlocks(), nil)
 		}
 
 	}
@@ -277,3 +277,243 @@ func TestBlockLevelIgnoresForAllRules(t *testing.T) {
 		}
 	}
 }
+
+func Test_ReportIgnores(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	source := `
+resource "bad" "my-rule" {
+    secure = false // tfsec:ignore:ABC123
+}
+
+// tfsec:ignore:aws-service-abc123
+resource "bad" "my-secure-rule" {
+    secure = true
+}
+
+// tfsec:ignore:aws-service-abc123:exp:2000-01-01
+resource "bad" "my-expired-rule" {
+    secure = true
+}
+
+// tfsec:ignore:aws-service-abc124
+resource "bad" "my-misspelled-rule" {
+    secure = true
+}
+
+// tfsec:ignore:aws-service-abc123:ws:production
+resource "bad" "my-workspace-rule" {
+    secure = true
+}
+`
+	results := testutil.ScanHCL(source, t)
+	assert.Len(t, results, 0)
+
+	results = testutil.ScanHCL(source, t, scanner.OptionReportIgnores())
+	var found []string
+	for _, result := range results {
+		found = append(found, fmt.Sprintf("%s:%d", result.Rule().LongID(), result.NarrowestRange().GetStartLine()))
+	}
+	assert.ElementsMatch(t, []string{
+		"general-ignores-no-unused:6",
+		"general-ignores-no-expired:11",
+		"general-ignores-no-unknown-rules:16",
+	}, found)
+
+	// findings about ignores are not removed by filters which select the rules being scanned
+	results = testutil.ScanHCL(source, t,
+		scanner.OptionReportIgnores(),
+		scanner.OptionIncludeRules([]string{"aws-service-abc123"}),
+		scanner.OptionIncludeProviders([]string{"aws"}),
+		scanner.OptionIncludeServices([]string{"aws-service"}),
+		scanner.OptionExcludeProviders([]string{"general"}),
+	)
+	assert.Len(t, results, 3)
+
+	results = testutil.ScanHCL(source, t, scanner.OptionReportIgnores(), scanner.OptionExcludeRules([]string{"general-ignores-no-unused"}))
+	assert.Len(t, results, 2)
+}
+
+func Test_IgnoreWithReason(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	source := `
+resource "bad" "with-reason" {
+    // tfsec:ignore:aws-service-abc123:exp:2221-01-02 reason="only holds public assets"
+    secure = false
+}
+
+resource "bad" "without-reason" {
+    // tfsec:ignore:aws-service-abc123
+    secure = false
+}
+`
+	results := testutil.ScanHCL(source, t)
+	assert.Len(t, results, 0)
+
+	results = testutil.ScanHCL(source, t, scanner.OptionRequireIgnoreReason())
+	require.Len(t, results, 1)
+	assert.Equal(t, 9, results[0].NarrowestRange().GetStartLine())
+
+	modules := testutil.CreateModulesFromSource(source, ".tf", t)
+	results, suppressions, err := scanner.New(scanner.OptionIncludeIgnored()).ScanWithSuppressions(modules)
+	require.NoError(t, err)
+	var reasons []string
+	for _, result := range results {
+		if suppression := suppressions.Lookup(result); suppression != nil {
+			assert.Equal(t, scanner.SuppressionInSource, suppression.Kind)
+			reasons = append(reasons, suppression.Reason)
+		}
+	}
+	assert.ElementsMatch(t, []string{"only holds public assets", ""}, reasons)
+}
+
+func Test_IgnoreBlock(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	results := testutil.ScanHCL(`
+// tfsec:ignore-block:aws-service-abc123
+resource "bad" "ignored" {
+    count = 2
+    name  = "ignored-${count.index}"
+    tags  = {
+        Name = "ignored"
+    }
+    secure = false
+}
+
+resource "bad" "not-ignored" {
+    name   = "not-ignored"
+    secure = false
+}
+`, t)
+	require.Len(t, results, 1)
+	assert.Equal(t, 14, results[0].NarrowestRange().GetStartLine())
+}
+
+func Test_IgnoreBlockCoversNestedBlocks(t *testing.T) {
+	nestedRule := rule.Rule{
+		Base: rules.Register(rules.Rule{
+			Provider:  provider.AWSProvider,
+			Service:   "service",
+			ShortCode: "nested",
+			Severity:  severity.High,
+		}, nil),
+		RequiredLabels: []string{"nested"},
+		CheckTerraform: func(resourceBlock block.Block, _ block.Module) (results rules.Results) {
+			for _, setting := range resourceBlock.GetBlocks("setting") {
+				if attr := setting.GetAttribute("secure"); attr.IsFalse() {
+					results.Add("insecure setting", attr)
+				}
+			}
+			return
+		},
+	}
+	scanner.RegisterCheckRule(nestedRule)
+	defer scanner.DeregisterCheckRule(nestedRule)
+
+	results := testutil.ScanHCL(`
+resource "nested" "partly-ignored" {
+    setting {
+        secure = false
+    }
+    # tfsec:ignore-block:aws-service-nested
+    setting {
+        name = "legacy"
+
+        secure = false
+    }
+}
+`, t)
+	require.Len(t, results, 1)
+	assert.Equal(t, 4, results[0].NarrowestRange().GetStartLine())
+}
+
+func Test_IgnoreFile(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	results := testutil.ScanHCL(`
+# tfsec:ignore-file:aws-service-abc123
+resource "bad" "first" {
+    secure = false
+}
+
+resource "bad" "second" {
+    name = "second"
+
+    secure = false
+}
+`, t)
+	assert.Len(t, results, 0)
+}
+
+func Test_IgnoreFileAndBlockWithExpiryAndWorkspace(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	results := testutil.ScanHCL(`
+# tfsec:ignore-file:aws-service-abc123:exp:2000-01-01
+# tfsec:ignore-block:aws-service-abc123:ws:production
+resource "bad" "first" {
+    name = "first"
+
+    secure = false
+}
+`, t)
+	assert.Len(t, results, 1)
+
+	results = testutil.ScanHCL(`
+# tfsec:ignore-file:aws-service-abc123:exp:2000-01-01
+# tfsec:ignore-block:aws-service-abc123:ws:production
+resource "bad" "first" {
+    name = "first"
+
+    secure = false
+}
+`, t, scanner.OptionWithWorkspaceName("production"))
+	assert.Len(t, results, 0)
+}
+
+func Test_IgnoreInJSON(t *testing.T) {
+	scanner.RegisterCheckRule(exampleRule)
+	defer scanner.DeregisterCheckRule(exampleRule)
+
+	results := testutil.ScanJSON(`{
+  "resource": {
+    "bad": {
+      "line": {
+        "//": "tfsec:ignore:aws-service-abc123",
+        "secure": false
+      },
+      "block": {
+        "//": "tfsec:ignore-block:aws-service-abc123 reason=\"accepted\"",
+        "name": "block",
+        "secure": false
+      },
+      "not-ignored": {
+        "name": "not-ignored",
+        "secure": false
+      }
+    }
+  }
+}`, t)
+	require.Len(t, results, 1)
+	assert.Equal(t, 15, results[0].NarrowestRange().GetStartLine())
+
+	results = testutil.ScanJSON(`{
+  "//": "tfsec:ignore-file:aws-service-abc123",
+  "resource": {
+    "bad": {
+      "first": {
+        "secure": false
+      },
+      "second": {}
+    }
+  }
+}`, t)
+	assert.Len(t, results, 0)
+}
@@ -69,7 +69,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("/project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("/project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -96,7 +96,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -123,7 +123,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -159,7 +159,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -196,7 +196,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -250,7 +250,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -296,7 +296,7 @@ resource "problem" "uhoh" {
 	{"Modules":[{"Key":"something","Source":"../modules/somewhere","Version":"2.35.0","Dir":"../modules/somewhere"},{"Key":"something.something_nested","Source":"git::https://github.com/some/module.git","Version":"2.35.0","Dir":".terraform/modules/something.something_nested"}]}
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -333,7 +333,7 @@ resource "problem" "uhoh" {
 	{"Modules":[{"Key":"something","Source":"/nowhere","Version":"2.35.0","Dir":".terraform/modules/a"},{"Key":"something2","Source":"/nowhere","Version":"2.35.0","Dir":".terraform/modules/a"}]}
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -387,7 +387,7 @@ resource "problem" "uhoh" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, badRule.ID(), "", results)
@@ -437,7 +437,7 @@ resource "bad" "thing" {
 	scanner.RegisterCheckRule(r1)
 	defer scanner.DeregisterCheckRule(r1)
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, r1.ID(), "", results)
@@ -486,7 +486,7 @@ resource "bad" "thing" {
 	scanner.RegisterCheckRule(r1)
 	defer scanner.DeregisterCheckRule(r1)
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, "", r1.ID(), results)
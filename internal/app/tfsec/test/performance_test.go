@@ -20,7 +20,7 @@ func BenchmarkCalculate(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		blocks, err := parser.New(fs.RealPath("/project"), parser.OptionStopOnHCLError()).ParseDirectory()
+		blocks, err := parser.New(fs.Path("/project"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 		if err != nil {
 			panic(err)
 		}
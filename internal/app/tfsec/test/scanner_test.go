@@ -1,6 +1,7 @@
 package test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/testutil/filesystem"
@@ -55,7 +56,7 @@ resource "problem" "this" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, "", panicRule.ID(), results)
@@ -76,7 +77,7 @@ resource "problem" "this" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	_, err = scanner.New(scanner.OptionStopOnErrors()).Scan(blocks)
 	assert.Error(t, err)
@@ -97,7 +98,7 @@ resource "problem" "this" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 	results, _ := scanner.New().Scan(blocks)
 	testutil.AssertCheckCode(t, "", panicRule.ID(), results)
@@ -118,9 +119,187 @@ resource "problem" "this" {
 }
 `))
 
-	blocks, err := parser.New(fs.RealPath("project/"), parser.OptionStopOnHCLError()).ParseDirectory()
+	blocks, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	require.NoError(t, err)
 
 	_, err = scanner.New(scanner.OptionStopOnErrors()).Scan(blocks)
 	assert.Error(t, err)
 }
+
+func Test_ScannersOwnTheirRules(t *testing.T) {
+
+	customRule := rule.Rule{
+		LegacyID: "CUS001",
+		Base: rules.Register(
+			rules.Rule{
+				Provider:  provider.CustomProvider,
+				Service:   "custom",
+				ShortCode: "owned-by-scanner",
+				Severity:  severity.High,
+			},
+			nil,
+		),
+		RequiredTypes:  []string{"resource"},
+		RequiredLabels: []string{"problem"},
+		CheckTerraform: func(resourceBlock block.Block, _ block.Module) (results rules.Results) {
+			results.Add("Custom check failed", resourceBlock)
+			return
+		},
+	}
+
+	fs, err := filesystem.New()
+	require.NoError(t, err)
+	defer fs.Close()
+
+	require.NoError(t, fs.WriteTextFile("project/main.tf", `
+resource "problem" "this" {
+}
+`))
+
+	var wg sync.WaitGroup
+	found := make([]bool, 8)
+	for i := range found {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			modules, err := parser.New(fs.Path("project/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
+			require.NoError(t, err)
+
+			var options []scanner.Option
+			if i%2 == 0 {
+				options = append(options, scanner.OptionWithCustomRules([]rule.Rule{customRule}))
+			}
+			results, err := scanner.New(options...).Scan(modules)
+			require.NoError(t, err)
+			for _, result := range results {
+				if result.Rule().LongID() == customRule.ID() {
+					found[i] = true
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, ok := range found {
+		assert.Equal(t, i%2 == 0, ok, "scanner %d", i)
+	}
+	assert.Empty(t, scanner.FindLegacyID(customRule.ID()))
+}
+
+func Test_RuleSelection(t *testing.T) {
+
+	source := `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`
+
+	tests := []struct {
+		name     string
+		options  []scanner.Option
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "include by wildcard",
+			options:  []scanner.Option{scanner.OptionIncludeRules([]string{"aws-s3-*-versioning"})},
+			included: []string{"aws-s3-enable-versioning"},
+			excluded: []string{"aws-s3-no-public-access-with-acl"},
+		},
+		{
+			name:     "include by legacy ID",
+			options:  []scanner.Option{scanner.OptionIncludeRules([]string{"AWS00?"})},
+			included: []string{"aws-s3-no-public-access-with-acl"},
+			excluded: []string{"aws-s3-enable-versioning"},
+		},
+		{
+			name:     "exclude by wildcard",
+			options:  []scanner.Option{scanner.OptionExcludeRules([]string{"*-versioning"})},
+			included: []string{"aws-s3-no-public-access-with-acl"},
+			excluded: []string{"aws-s3-enable-versioning"},
+		},
+		{
+			name: "include provider and exclude service",
+			options: []scanner.Option{
+				scanner.OptionIncludeProviders([]string{"aws"}),
+				scanner.OptionExcludeServices([]string{"aws-s3"}),
+			},
+			excluded: []string{"aws-s3-no-public-access-with-acl", "aws-s3-enable-versioning"},
+		},
+		{
+			name:     "include service",
+			options:  []scanner.Option{scanner.OptionIncludeServices([]string{"aws-s*"})},
+			included: []string{"aws-s3-no-public-access-with-acl", "aws-s3-enable-versioning"},
+		},
+		{
+			name:     "exclude provider",
+			options:  []scanner.Option{scanner.OptionExcludeProviders([]string{"google", "aws"})},
+			excluded: []string{"aws-s3-no-public-access-with-acl", "aws-s3-enable-versioning"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := testutil.ScanHCL(source, t, test.options...)
+			for _, id := range test.included {
+				testutil.AssertCheckCode(t, id, "", results)
+			}
+			for _, id := range test.excluded {
+				testutil.AssertCheckCode(t, "", id, results)
+			}
+		})
+	}
+}
+
+func Test_PathScopes(t *testing.T) {
+
+	fs, err := filesystem.New()
+	require.NoError(t, err)
+	defer fs.Close()
+
+	bucket := `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`
+	require.NoError(t, fs.WriteTextFile("project/main.tf", `
+module "sandbox" {
+	source = "./sandbox"
+}
+module "prod" {
+	source = "./prod"
+}
+`))
+	require.NoError(t, fs.WriteTextFile("project/sandbox/main.tf", bucket))
+	require.NoError(t, fs.WriteTextFile("project/prod/main.tf", bucket))
+
+	modules, err := parser.New(fs.Path("project"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
+	require.NoError(t, err)
+
+	results, err := scanner.New(
+		scanner.OptionWithMinimumSeverity(severity.Medium),
+		scanner.OptionWithPathScopes(fs.Path("project"), []scanner.PathScope{
+			{
+				Paths:           []string{"sandbox/**"},
+				ExcludedRuleIDs: []string{"aws-s3-enable-versioning"},
+				MinimumSeverity: severity.Critical,
+			},
+			{
+				Paths:             []string{"**/prod/*.tf"},
+				SeverityOverrides: map[string]string{"AWS001": string(severity.Critical)},
+			},
+		}),
+	).Scan(modules)
+	require.NoError(t, err)
+
+	bySeverity := make(map[string]severity.Severity)
+	for _, result := range results {
+		bySeverity[result.Rule().LongID()+" "+result.NarrowestRange().GetFilename()] = result.Rule().Severity
+		assert.NotEqual(t, severity.Low, result.Rule().Severity)
+	}
+
+	assert.NotContains(t, bySeverity, "aws-s3-no-public-access-with-acl project/sandbox/main.tf")
+	assert.NotContains(t, bySeverity, "aws-s3-enable-versioning project/sandbox/main.tf")
+	assert.Equal(t, severity.Critical, bySeverity["aws-s3-no-public-access-with-acl project/prod/main.tf"])
+	assert.Equal(t, severity.Medium, bySeverity["aws-s3-enable-versioning project/prod/main.tf"])
+}
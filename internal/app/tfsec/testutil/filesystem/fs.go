@@ -1,56 +1,55 @@
 package filesystem
 
 import (
-	"io/ioutil"
-	"os"
-	"path/filepath"
-	"runtime"
+	"io/fs"
+	"path"
 	"strings"
+	"testing/fstest"
 )
 
+// FileSystem is an in-memory filesystem for tests, which can be scanned by passing it to parser.OptionWithFS
 type FileSystem struct {
-	root string
+	files fstest.MapFS
 }
 
 func New() (*FileSystem, error) {
-
-	tempDir := os.TempDir()
-	if runtime.GOOS == "darwin" {
-		// osx tmpdir path is a symlink to /private/var/... which messes with tests
-		osxTmpDir := os.TempDir()
-		if strings.HasPrefix(osxTmpDir, "/var") {
-			tempDir = filepath.Join("/private/", osxTmpDir)
-		}
-	}
-
-	dir, err := ioutil.TempDir(tempDir, "tfsec")
-	if err != nil {
-		return nil, err
-	}
 	return &FileSystem{
-		root: dir,
+		files: make(fstest.MapFS),
 	}, nil
 }
 
-func (fs *FileSystem) RealPath(path string) string {
-	return filepath.Join(fs.root, path)
+// Path returns the given path as a path within the filesystem
+func (f *FileSystem) Path(p string) string {
+	cleaned := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if cleaned == "" {
+		return "."
+	}
+	return cleaned
+}
+
+func (f *FileSystem) Open(name string) (fs.File, error) {
+	return f.files.Open(name)
 }
 
-func (fs *FileSystem) Close() error {
-	return os.RemoveAll(fs.root)
+func (f *FileSystem) Close() error {
+	return nil
 }
 
-func (fs *FileSystem) AddDir(path string) error {
-	return os.MkdirAll(filepath.Join(fs.root, path), 0700)
+func (f *FileSystem) AddDir(p string) error {
+	f.files[f.Path(p)] = &fstest.MapFile{
+		Mode: fs.ModeDir | 0700,
+	}
+	return nil
 }
 
-func (fs *FileSystem) WriteFile(path string, data []byte) error {
-	if err := fs.AddDir(filepath.Dir(path)); err != nil {
-		return err
+func (f *FileSystem) WriteFile(p string, data []byte) error {
+	f.files[f.Path(p)] = &fstest.MapFile{
+		Data: data,
+		Mode: 0600,
 	}
-	return ioutil.WriteFile(filepath.Join(fs.root, path), data, 0600)
+	return nil
 }
 
-func (fs *FileSystem) WriteTextFile(path string, text string) error {
-	return fs.WriteFile(path, []byte(text))
+func (f *FileSystem) WriteTextFile(p string, text string) error {
+	return f.WriteFile(p, []byte(text))
 }
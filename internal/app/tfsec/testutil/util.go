@@ -2,7 +2,6 @@ package testutil
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 	"testing"
 
@@ -46,8 +45,7 @@ func CreateModulesFromSource(source string, ext string, t *testing.T) []block.Mo
 	if err := fs.WriteTextFile("test"+ext, source); err != nil {
 		t.Fatal(err)
 	}
-	path := fs.RealPath("test" + ext)
-	modules, err := parser.New(filepath.Dir(path), parser.OptionStopOnHCLError()).ParseDirectory()
+	modules, err := parser.New(fs.Path("/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 	if err != nil {
 		t.Fatalf("parse error: %s", err)
 	}
@@ -13,11 +13,14 @@ import (
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
 	_ "github.com/aquasecurity/tfsec/internal/app/tfsec/rules"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/pkg/rule"
 )
 
 type ExternalScanner struct {
 	paths           []string
 	internalOptions []scanner.Option
+	parserOptions   []parser.Option
+	customRules     []rule.Rule
 }
 
 const customChecksDir = ".tfsec"
@@ -36,9 +39,11 @@ func (t *ExternalScanner) AddPath(path string) error {
 		return err
 	}
 	customCheckDir := filepath.Join(filepath.Dir(path), customChecksDir)
-	if err := custom.Load(customCheckDir); err != nil {
+	customRules, err := custom.Load(customCheckDir)
+	if err != nil {
 		return err
 	}
+	t.customRules = append(t.customRules, customRules...)
 	t.paths = append(t.paths, abs)
 	return nil
 }
@@ -53,7 +58,7 @@ func (t *ExternalScanner) Scan() ([]rules.FlatResult, error) {
 	}
 
 	for _, dir := range dirs {
-		modules, err := parser.New(dir).ParseDirectory()
+		modules, err := parser.New(dir, t.parserOptions...).ParseDirectory()
 		if err != nil {
 			return nil, err
 		}
@@ -61,7 +66,7 @@ func (t *ExternalScanner) Scan() ([]rules.FlatResult, error) {
 	}
 
 	var results rules.Results
-	internal := scanner.New(t.internalOptions...)
+	internal := scanner.New(append(t.internalOptions, scanner.OptionWithCustomRules(t.customRules))...)
 	for _, modules := range projectModules {
 		projectResults, _ := internal.Scan(modules)
 		results = append(results, projectResults...)
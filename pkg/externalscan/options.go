@@ -1,7 +1,9 @@
 package externalscan
 
 import (
-	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"os"
+
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
 	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
 )
 
@@ -15,6 +17,10 @@ func OptionIncludePassed() Option {
 
 func OptionDebugEnabled(debugEnabled bool) Option {
 	return func(e *ExternalScanner) {
-		debug.Enabled = debugEnabled
+		if !debugEnabled {
+			return
+		}
+		e.internalOptions = append(e.internalOptions, scanner.OptionWithDebugWriter(os.Stdout))
+		e.parserOptions = append(e.parserOptions, parser.OptionWithDebugWriter(os.Stdout))
 	}
 }
@@ -14,9 +14,16 @@ import (
 )
 
 func (r *Rule) CheckAgainstState(s *state.State) rules.Results {
-	results := r.Base.Evaluate(s)
+	var results rules.Results
+	if r.Definition != nil {
+		if r.CheckState != nil {
+			results = r.CheckState(s)
+		}
+	} else {
+		results = r.Base.Evaluate(s)
+	}
 	if len(results) > 0 {
-		base := r.Base.Rule()
+		base := r.BaseRule()
 		base.Links = append(r.Links, base.Links...)
 		results.SetRule(base)
 	}
@@ -39,7 +46,7 @@ func (r *Rule) CheckAgainstBlock(b block.Block, m block.Module) rules.Results {
 	}
 	results := r.CheckTerraform(b, m)
 	if len(results) > 0 {
-		base := r.Base.Rule()
+		base := r.BaseRule()
 		base.Links = append(r.Links, base.Links...)
 		results.SetRule(base)
 	}
@@ -131,6 +138,11 @@ func (r *Rule) checkRequiredSourcesMatch(b block.Block) bool {
 func cleanPathRelativeToWorkingDir(dir, path string) (string, error) {
 	absPath := filepath.Clean(filepath.Join(dir, path))
 
+	// paths are already relative when scanning an fs.FS, where the root of the filesystem stands in for the working directory
+	if !filepath.IsAbs(absPath) {
+		return absPath, nil
+	}
+
 	wDir, err := os.Getwd()
 	if err != nil {
 		return "", err
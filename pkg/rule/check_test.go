@@ -1,7 +1,6 @@
 package rule
 
 import (
-	"os"
 	"path/filepath"
 	"testing"
 
@@ -149,10 +148,6 @@ module "custom_module" {
 			expected: true,
 		},
 	}
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
-	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 
@@ -170,15 +165,13 @@ module "custom_module" {
 				t.Fatal(err)
 			}
 
-			modules, err := parser.New(fs.RealPath("src/"), parser.OptionStopOnHCLError()).ParseDirectory()
+			modules, err := parser.New(fs.Path("src/"), parser.OptionWithFS(fs), parser.OptionStopOnHCLError()).ParseDirectory()
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			os.Chdir(fs.RealPath("/")) // change directory for relative path tests to work
 			result := test.rule.isRuleRequiredForBlock(modules[0].GetBlocks()[0])
 			assert.Equal(t, test.expected, result, "`IsRuleRequiredForBlock` match function evaluating incorrectly for requiredSources test.")
 		})
 	}
-	os.Chdir(wd)
 }
@@ -21,12 +21,28 @@ type Rule struct {
 	// Links are URLs which contain further reading related to the check
 	Links []string
 
+	// ComplianceTags identify the compliance controls the check relates to, e.g. cis-aws-1.2-2.1
+	ComplianceTags []string
+
 	RequiredTypes   []string
 	RequiredLabels  []string
 	RequiredSources []string
 	CheckTerraform  func(block.Block, block.Module) rules.Results
+
+	// Definition and CheckState are used instead of Base by rules which are not registered with defsec, such as custom checks,
+	// as registered rules are run by every defsec scanner in the process and are never removed
+	Definition *rules.Rule
+	CheckState rules.CheckFunc
+}
+
+// BaseRule returns the defsec rule which describes this rule
+func (r Rule) BaseRule() rules.Rule {
+	if r.Definition != nil {
+		return *r.Definition
+	}
+	return r.Base.Rule()
 }
 
 func (r Rule) ID() string {
-	return r.Base.Rule().LongID()
+	return r.BaseRule().LongID()
 }
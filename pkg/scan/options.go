@@ -0,0 +1,147 @@
+package scan
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/aquasecurity/defsec/severity"
+)
+
+type Option func(s *Scanner)
+
+// OptionWithTFVarsPaths sets the .tfvars files to load, which are evaluated in the given order (--tfvars-file)
+func OptionWithTFVarsPaths(paths ...string) Option {
+	return func(s *Scanner) {
+		s.tfvarsPaths = append(s.tfvarsPaths, paths...)
+	}
+}
+
+// OptionWithWorkspace sets the terraform workspace, which is used to evaluate terraform.workspace and workspace specific ignores (--workspace)
+func OptionWithWorkspace(workspace string) Option {
+	return func(s *Scanner) {
+		s.workspace = workspace
+	}
+}
+
+// OptionWithConfigFile sets the config file to use. Otherwise, config.json or config.yml files in the .tfsec directories of the scanned directory and its parents, up to the repository root, are merged (--config-file)
+func OptionWithConfigFile(path string) Option {
+	return func(s *Scanner) {
+		s.configFile = path
+	}
+}
+
+// OptionWithCustomCheckDir sets the directory to load custom checks from, which defaults to the .tfsec directory of the scanned directory (--custom-check-dir)
+func OptionWithCustomCheckDir(dir string) Option {
+	return func(s *Scanner) {
+		s.customCheckDir = dir
+	}
+}
+
+// OptionWithExcludePaths prevents the given paths from being parsed (--exclude-path)
+func OptionWithExcludePaths(paths ...string) Option {
+	return func(s *Scanner) {
+		s.excludePaths = append(s.excludePaths, paths...)
+	}
+}
+
+// OptionExcludeRules prevents results for the given rules, by long or legacy ID or a pattern such as aws-s3-*, in addition to any excluded by the config file (--exclude)
+func OptionExcludeRules(ruleIDs ...string) Option {
+	return func(s *Scanner) {
+		s.excludedRuleIDs = append(s.excludedRuleIDs, ruleIDs...)
+	}
+}
+
+// OptionFilterResults only returns results for the given rules, by long ID or a pattern such as aws-s3-* (--filter-results)
+func OptionFilterResults(ruleIDs ...string) Option {
+	return func(s *Scanner) {
+		s.filteredRuleIDs = append(s.filteredRuleIDs, ruleIDs...)
+	}
+}
+
+// OptionWithSeverityOverrides changes the severity of results for the given rules, keyed by long or legacy ID. These take precedence over overrides in the config file.
+func OptionWithSeverityOverrides(overrides map[string]severity.Severity) Option {
+	return func(s *Scanner) {
+		for id, sev := range overrides {
+			s.severityOverrides[id] = string(sev)
+		}
+	}
+}
+
+// OptionExcludeDownloadedModules removes results for modules downloaded into the .terraform directory (--exclude-downloaded-modules)
+func OptionExcludeDownloadedModules() Option {
+	return func(s *Scanner) {
+		s.excludeDownloaded = true
+	}
+}
+
+// OptionIncludePassed includes results for passed checks (--include-passed)
+func OptionIncludePassed() Option {
+	return func(s *Scanner) {
+		s.includePassed = true
+	}
+}
+
+// OptionIncludeIgnored includes results which are covered by ignore comments (--include-ignored)
+func OptionIncludeIgnored() Option {
+	return func(s *Scanner) {
+		s.includeIgnored = true
+	}
+}
+
+// OptionReportIgnores adds results for ignore comments and ignore file entries which are unused, expired or refer to unknown rules (--report-ignores)
+func OptionReportIgnores() Option {
+	return func(s *Scanner) {
+		s.reportIgnores = true
+	}
+}
+
+// OptionWithGate sets gate thresholds, given as SEVERITY=max, provider:NAME=max or rule:ID=max, overriding thresholds set in the gate of the config (--gate).
+// When a gate is set, its outcome is given by Report.Gate.
+func OptionWithGate(thresholds ...string) Option {
+	return func(s *Scanner) {
+		s.gateThresholds = append(s.gateThresholds, thresholds...)
+	}
+}
+
+// OptionIgnoreHCLErrors skips files containing HCL errors rather than failing the scan. Skipped files are reported as diagnostics (--ignore-hcl-errors)
+func OptionIgnoreHCLErrors() Option {
+	return func(s *Scanner) {
+		s.ignoreHCLErrors = true
+	}
+}
+
+// OptionForceAllDirs scans every directory below the scanned directory, rather than stopping at the first containing terraform files (--force-all-dirs)
+func OptionForceAllDirs() Option {
+	return func(s *Scanner) {
+		s.forceAllDirs = true
+	}
+}
+
+// OptionSingleThread runs parsing and checks using a single thread (--single-thread)
+func OptionSingleThread() Option {
+	return func(s *Scanner) {
+		s.singleThread = true
+	}
+}
+
+// OptionStopOnCheckErrors returns an error if a check panics, rather than skipping it (--allow-checks-to-panic)
+func OptionStopOnCheckErrors() Option {
+	return func(s *Scanner) {
+		s.stopOnCheckErrors = true
+	}
+}
+
+// OptionWithDebugWriter writes debug logging for scans to the given writer (--debug)
+func OptionWithDebugWriter(writer io.Writer) Option {
+	return func(s *Scanner) {
+		s.debugWriter = writer
+	}
+}
+
+// OptionWithFS scans the given filesystem rather than the disk, e.g. an archive or an fstest.MapFS. The scanned directory, tfvars paths and exclude paths are then paths within it.
+// A config file and custom checks are only loaded if set explicitly, and are always read from disk.
+func OptionWithFS(fsys fs.FS) Option {
+	return func(s *Scanner) {
+		s.fsys = fsys
+	}
+}
@@ -0,0 +1,183 @@
+package scan
+
+import (
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/defsec/types"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/block"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/gate"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Report is the outcome of scanning a single directory
+type Report struct {
+	// Results holds a result for every failed check, and for passed and ignored checks if requested
+	Results []Result
+	// Diagnostics holds any problems found which did not stop the scan, such as HCL errors or modules which could not be loaded
+	Diagnostics []Diagnostic
+	// Files lists every file which was parsed, including those belonging to modules
+	Files []string
+	// Gate is the outcome of the gate, if the config or OptionWithGate set one
+	Gate *GateOutcome
+
+	raw rules.Results
+}
+
+// RuleResults returns the underlying results, which can be passed to the formatters provided by defsec
+func (r *Report) RuleResults() rules.Results {
+	return r.raw
+}
+
+// GateOutcome decides whether a scan passed, from the number of failed results counted against each threshold of the gate.
+// Ignored and excluded results are not counted, nor are the findings reported by OptionReportIgnores.
+type GateOutcome struct {
+	Passed bool
+	Checks []GateCheck
+}
+
+// GateCheck is the outcome of a single threshold of the gate
+type GateCheck struct {
+	// Threshold describes what is counted, e.g. HIGH, provider aws or rule aws-s3-*
+	Threshold string
+	Count     int
+	Max       int
+	Passed    bool
+}
+
+func newGateOutcome(outcome gate.Outcome) *GateOutcome {
+	result := &GateOutcome{
+		Passed: outcome.Passed(),
+	}
+	for _, check := range outcome.Checks {
+		result.Checks = append(result.Checks, GateCheck{
+			Threshold: check.Threshold.String(),
+			Count:     check.Count,
+			Max:       check.Max,
+			Passed:    check.Passed(),
+		})
+	}
+	return result
+}
+
+// Result is the outcome of running a single rule against a single resource
+type Result struct {
+	// RuleID is the long ID of the rule, e.g. aws-s3-enable-versioning
+	RuleID string
+	// LegacyID is the ID used by older versions of tfsec, e.g. AWS077, if the rule has one
+	LegacyID string
+	// Rule holds the rule metadata, such as the provider, service, impact, resolution and links
+	Rule rules.Rule
+	// Severity is the severity of the result, after any overrides have been applied
+	Severity    severity.Severity
+	Status      rules.Status
+	Description string
+	// Resource is the full address of the resource the result applies to, including any modules, e.g. module.storage.aws_s3_bucket.logs
+	Resource string
+	// Module is the name of the module containing the resource, or "root"
+	Module string
+	// Range is the narrowest range of code which caused the result
+	Range Range
+	// CodeRange is the range of the whole resource block
+	CodeRange Range
+	// Suppression describes the ignore covering the result, if it was ignored. Ignored results are only reported with OptionIncludeIgnored.
+	Suppression *Suppression
+}
+
+// Suppression describes an ignore comment or ignore file entry, or the exclusion of the rule by the config
+type Suppression struct {
+	// InSource is true for ignore comments, and false for entries in ignore files and excluded rules
+	InSource bool
+	// Reason is the reason given by an ignore comment, or the justification of an ignore file entry
+	Reason string
+	// Owner is the owner of an ignore file entry
+	Owner string
+	// Range is the location of the ignore
+	Range Range
+}
+
+// Range is a range of lines within a file
+type Range struct {
+	Filename  string
+	StartLine int
+	EndLine   int
+}
+
+// DiagnosticSeverity describes whether a diagnostic prevented some of the code from being scanned
+type DiagnosticSeverity string
+
+const (
+	DiagnosticError   DiagnosticSeverity = "error"
+	DiagnosticWarning DiagnosticSeverity = "warning"
+)
+
+// Diagnostic is a problem found while parsing which did not stop the scan
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Summary  string
+	Detail   string
+	// Range is the location of the problem, if known
+	Range *Range
+}
+
+func newResult(r rules.Result, legacyID string, suppression *scanner.Suppression) Result {
+	result := Result{
+		RuleID:      r.Rule().LongID(),
+		LegacyID:    legacyID,
+		Rule:        r.Rule(),
+		Severity:    r.Rule().Severity,
+		Status:      r.Status(),
+		Description: r.Description(),
+		Range:       newRange(r.NarrowestRange()),
+	}
+	if code := r.CodeBlockMetadata(); code != nil {
+		result.CodeRange = newRange(code.Range())
+		result.Resource = scanner.ResourceAddress(r)
+	}
+	if hclRange, ok := r.NarrowestRange().(block.HCLRange); ok {
+		result.Module = hclRange.GetModule()
+	}
+	if suppression != nil {
+		result.Suppression = &Suppression{
+			InSource: suppression.Kind == scanner.SuppressionInSource,
+			Reason:   suppression.Reason,
+			Owner:    suppression.Owner,
+			Range: Range{
+				Filename:  suppression.Filename,
+				StartLine: suppression.Line,
+				EndLine:   suppression.Line,
+			},
+		}
+	}
+	return result
+}
+
+func newRange(r types.Range) Range {
+	if r == nil {
+		return Range{}
+	}
+	return Range{
+		Filename:  r.GetFilename(),
+		StartLine: r.GetStartLine(),
+		EndLine:   r.GetEndLine(),
+	}
+}
+
+func newDiagnostic(diag *hcl.Diagnostic) Diagnostic {
+	diagnostic := Diagnostic{
+		Severity: DiagnosticWarning,
+		Summary:  diag.Summary,
+		Detail:   diag.Detail,
+	}
+	if diag.Severity == hcl.DiagError {
+		diagnostic.Severity = DiagnosticError
+	}
+	if diag.Subject != nil {
+		diagnostic.Range = &Range{
+			Filename:  diag.Subject.Filename,
+			StartLine: diag.Subject.Start.Line,
+			EndLine:   diag.Subject.End.Line,
+		}
+	}
+	return diagnostic
+}
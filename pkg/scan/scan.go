@@ -0,0 +1,317 @@
+// Package scan provides an API for running tfsec from Go, supporting everything the tfsec command line supports.
+//
+//	scanner := scan.New(scan.OptionWithWorkspace("production"), scan.OptionWithTFVarsPaths("production.tfvars"))
+//	report, err := scanner.Scan("./infrastructure")
+//
+// A Scanner can be used for any number of scans, including concurrently.
+package scan
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/config"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/custom"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/debug"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/gate"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/parser"
+	_ "github.com/aquasecurity/tfsec/internal/app/tfsec/rules"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/scanner"
+	"github.com/aquasecurity/tfsec/internal/app/tfsec/settings"
+	"github.com/aquasecurity/tfsec/pkg/rule"
+)
+
+// Scanner scans directories of terraform code
+type Scanner struct {
+	tfvarsPaths       []string
+	workspace         string
+	configFile        string
+	customCheckDir    string
+	excludePaths      []string
+	excludedRuleIDs   []string
+	filteredRuleIDs   []string
+	severityOverrides map[string]string
+	excludeDownloaded bool
+	includePassed     bool
+	includeIgnored    bool
+	reportIgnores     bool
+	gateThresholds    []string
+	ignoreHCLErrors   bool
+	forceAllDirs      bool
+	singleThread      bool
+	stopOnCheckErrors bool
+	debugWriter       io.Writer
+	fsys              fs.FS
+}
+
+// New creates a Scanner with the given options
+func New(options ...Option) *Scanner {
+	s := &Scanner{
+		severityOverrides: make(map[string]string),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Scan parses and scans the terraform code in the given directory, which is a path within the filesystem given by OptionWithFS if set
+func (s *Scanner) Scan(dir string) (*Report, error) {
+
+	if s.fsys == nil {
+		var err error
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	}
+	if info, err := s.stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	conf, err := s.loadConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	gateConfig, err := gate.Override(conf.Gate, s.gateThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	var customRules []rule.Rule
+	customCheckDir := s.customCheckDir
+	if customCheckDir == "" && s.fsys == nil {
+		customCheckDir = filepath.Join(dir, ".tfsec")
+	}
+	if customCheckDir != "" {
+		if customRules, err = custom.Load(customCheckDir, s.customCheckOptions(conf)...); err != nil {
+			return nil, fmt.Errorf("failed to load custom checks: %w", err)
+		}
+	}
+
+	scopes, err := s.pathScopes(dir, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom checks: %w", err)
+	}
+
+	policies, err := s.ignorePolicies(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(dir, s.parserOptions()...)
+	modules, err := p.ParseDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	internal := scanner.New(append(s.scannerOptions(conf, customRules), scanner.OptionWithPathScopes(dir, scopes), scanner.OptionWithIgnorePolicies(policies))...)
+	results, suppressions, err := internal.ScanWithSuppressions(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Files: p.ParsedFiles(),
+	}
+	for _, diag := range p.Diagnostics() {
+		report.Diagnostics = append(report.Diagnostics, newDiagnostic(diag))
+	}
+
+	legacyIDs := make(map[string]string)
+	for _, r := range internal.Rules() {
+		legacyIDs[r.ID()] = r.LegacyID
+	}
+
+	for _, result := range s.filterResults(results) {
+		report.raw = append(report.raw, result)
+		report.Results = append(report.Results, newResult(result, legacyIDs[result.Rule().LongID()], suppressions.Lookup(result)))
+	}
+
+	if gateConfig.IsSet() {
+		gateRules := customRules
+		for _, scope := range scopes {
+			gateRules = append(gateRules, scope.CustomRules...)
+		}
+		report.Gate = newGateOutcome(gate.New(gateConfig, gateRules).Evaluate(report.raw, suppressions))
+	}
+
+	return report, nil
+}
+
+func (s *Scanner) stat(dir string) (fs.FileInfo, error) {
+	if s.fsys != nil {
+		return fs.Stat(s.fsys, dir)
+	}
+	return os.Stat(dir)
+}
+
+func (s *Scanner) loadConfig(dir string) (*config.Config, error) {
+	if s.configFile != "" {
+		return config.LoadConfig(s.configFile)
+	}
+	if s.fsys != nil {
+		return &config.Config{}, nil
+	}
+	return config.LoadConfigs(config.DiscoverConfigFiles(dir))
+}
+
+// pathScopes converts the path overrides in the config into scopes for the scanner, where custom check directories are left
+// unresolved when scanning an fs.FS
+func (s *Scanner) pathScopes(dir string, conf *config.Config) ([]scanner.PathScope, error) {
+	root := dir
+	if s.fsys != nil {
+		root = ""
+	}
+	return settings.PathScopes(root, conf.PathOverrides, debug.New(s.debugWriter), s.customCheckOptions(conf)...)
+}
+
+// ignorePolicies loads the ignores.yml files which apply to the directory, unless scanning an fs.FS
+func (s *Scanner) ignorePolicies(dir string) ([]scanner.IgnorePolicy, error) {
+	if s.fsys != nil {
+		return nil, nil
+	}
+	return settings.IgnorePolicies(config.DiscoverIgnoreFiles(dir), debug.New(s.debugWriter))
+}
+
+func (s *Scanner) customCheckOptions(conf *config.Config) []custom.Option {
+	return []custom.Option{
+		custom.OptionWithParams(conf.CustomCheckParams),
+		custom.OptionWithDebugWriter(s.debugWriter),
+	}
+}
+
+func (s *Scanner) parserOptions() []parser.Option {
+	options := []parser.Option{
+		parser.OptionWithWarningWriter(nil),
+	}
+	if s.fsys != nil {
+		options = append(options, parser.OptionWithFS(s.fsys))
+	}
+	if s.forceAllDirs {
+		options = append(options, parser.OptionDoNotSearchTfFiles())
+	}
+	if len(s.tfvarsPaths) > 0 {
+		options = append(options, parser.OptionWithTFVarsPaths(s.absPaths(s.tfvarsPaths)))
+	}
+	if len(s.excludePaths) > 0 {
+		options = append(options, parser.OptionWithExcludePaths(s.absPaths(s.excludePaths)))
+	}
+	if !s.ignoreHCLErrors {
+		options = append(options, parser.OptionStopOnHCLError())
+	}
+	if s.workspace != "" {
+		options = append(options, parser.OptionWithWorkspaceName(s.workspace))
+	}
+	if s.singleThread {
+		options = append(options, parser.OptionWithConcurrency(1))
+	}
+	if s.debugWriter != nil {
+		options = append(options, parser.OptionWithDebugWriter(s.debugWriter))
+	}
+	return options
+}
+
+// ruleAliases returns the long and legacy IDs of the rule with the given ID
+func ruleAliases(id string, customRules []rule.Rule) []string {
+	for _, r := range append(scanner.GetRegisteredRules(), customRules...) {
+		if r.ID() == id || (r.LegacyID != "" && r.LegacyID == id) {
+			return []string{r.ID(), r.LegacyID}
+		}
+	}
+	return nil
+}
+
+// absPaths makes the given paths absolute, unless they are paths within the filesystem given by OptionWithFS
+func (s *Scanner) absPaths(paths []string) []string {
+	if s.fsys != nil {
+		return paths
+	}
+	var absPaths []string
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			absPaths = append(absPaths, abs)
+		}
+	}
+	return absPaths
+}
+
+func (s *Scanner) scannerOptions(conf *config.Config, customRules []rule.Rule) []scanner.Option {
+	options := []scanner.Option{
+		scanner.OptionWithCustomRules(customRules),
+		scanner.OptionWithSingleThread(s.singleThread),
+		scanner.OptionExcludeRules(append(append([]string{}, s.excludedRuleIDs...), conf.ExcludedChecks...)),
+		scanner.OptionIncludeRules(conf.IncludedChecks),
+		scanner.OptionIncludeProviders(conf.IncludedProviders),
+		scanner.OptionExcludeProviders(conf.ExcludedProviders),
+		scanner.OptionIncludeServices(conf.IncludedServices),
+		scanner.OptionExcludeServices(conf.ExcludedServices),
+	}
+	if s.includePassed {
+		options = append(options, scanner.OptionIncludePassed())
+	}
+	if s.includeIgnored {
+		options = append(options, scanner.OptionIncludeIgnored())
+	}
+	if s.reportIgnores {
+		options = append(options, scanner.OptionReportIgnores())
+	}
+	if s.workspace != "" {
+		options = append(options, scanner.OptionWithWorkspaceName(s.workspace))
+	}
+	if conf.RequiresIgnoreReason() {
+		options = append(options, scanner.OptionRequireIgnoreReason())
+	}
+	if s.stopOnCheckErrors {
+		options = append(options, scanner.OptionStopOnErrors())
+	}
+	if s.debugWriter != nil {
+		options = append(options, scanner.OptionWithDebugWriter(s.debugWriter))
+	}
+
+	overrides := make(map[string]string)
+	for id, sev := range conf.SeverityOverrides {
+		overrides[id] = sev
+	}
+	for id, sev := range s.severityOverrides {
+		// the config may override the same rule by its other ID, which would otherwise be applied in no particular order
+		for _, alias := range ruleAliases(id, customRules) {
+			delete(overrides, alias)
+		}
+		overrides[id] = sev
+	}
+	options = append(options, scanner.OptionWithSeverityOverrides(overrides))
+	options = append(options, scanner.OptionWithMinimumSeverity(severity.StringToSeverity(conf.MinimumSeverity)))
+
+	return options
+}
+
+func (s *Scanner) filterResults(results rules.Results) rules.Results {
+	var filtered rules.Results
+	for _, result := range results {
+		if s.excludeDownloaded && strings.Contains(result.NarrowestRange().GetFilename(), fmt.Sprintf("%c.terraform", os.PathSeparator)) {
+			continue
+		}
+		if len(s.filteredRuleIDs) > 0 && !matchesAny(s.filteredRuleIDs, result.Rule().LongID()) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+func matchesAny(patterns []string, ruleID string) bool {
+	for _, pattern := range patterns {
+		if scanner.MatchRuleID(pattern, ruleID) {
+			return true
+		}
+	}
+	return false
+}
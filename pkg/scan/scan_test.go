@@ -0,0 +1,417 @@
+package scan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aquasecurity/defsec/rules"
+	"github.com/aquasecurity/defsec/severity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "tfsec-scan")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	for path, contents := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0700))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte(contents), 0600))
+	}
+	return dir
+}
+
+func findResult(report *Report, ruleID string) *Result {
+	for _, result := range report.Results {
+		if result.RuleID == ruleID {
+			return &result
+		}
+	}
+	return nil
+}
+
+const publicBucket = `
+variable "acl" {
+	default = "private"
+}
+
+resource "aws_s3_bucket" "logs" {
+	acl = var.acl
+}
+`
+
+func Test_ScanReturnsRichResults(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+
+	result := findResult(report, "aws-s3-no-public-access-with-acl")
+	require.NotNil(t, result)
+	assert.Equal(t, "AWS001", result.LegacyID)
+	assert.Equal(t, "aws_s3_bucket.logs", result.Resource)
+	assert.Equal(t, "root", result.Module)
+	assert.Equal(t, rules.StatusFailed, result.Status)
+	assert.Equal(t, "s3", result.Rule.Service)
+	assert.Equal(t, severity.High, result.Severity)
+	assert.Equal(t, Range{Filename: filepath.Join(dir, "main.tf"), StartLine: 3, EndLine: 3}, result.Range)
+	assert.Equal(t, Range{Filename: filepath.Join(dir, "main.tf"), StartLine: 2, EndLine: 4}, result.CodeRange)
+	assert.Equal(t, []string{filepath.Join(dir, "main.tf")}, report.Files)
+	assert.Len(t, report.RuleResults(), len(report.Results))
+}
+
+func Test_ScanResultsHaveFullResourceAddresses(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+module "first" {
+	source = "./storage"
+}
+
+module "second" {
+	source = "./storage"
+}
+`,
+		"storage/main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+
+	resources := make(map[string]bool)
+	for _, result := range report.Results {
+		if result.RuleID == "aws-s3-no-public-access-with-acl" {
+			resources[result.Resource] = true
+		}
+	}
+	// each module instance is given its own address
+	assert.Equal(t, map[string]bool{"module.first.aws_s3_bucket.logs": true, "module.second.aws_s3_bucket.logs": true}, resources)
+}
+
+func Test_ScanWithTFVars(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf":        publicBucket,
+		"public.tfvars":  `acl = "public-read"`,
+		"private.tfvars": `acl = "private"`,
+	})
+
+	report, err := New(OptionWithTFVarsPaths(filepath.Join(dir, "public.tfvars"))).Scan(dir)
+	require.NoError(t, err)
+	assert.NotNil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+
+	report, err = New(OptionWithTFVarsPaths(filepath.Join(dir, "private.tfvars"))).Scan(dir)
+	require.NoError(t, err)
+	assert.Nil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+}
+
+func Test_ScanWithWorkspace(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = terraform.workspace == "production" ? "private" : "public-read"
+}
+`,
+	})
+
+	report, err := New(OptionWithWorkspace("production")).Scan(dir)
+	require.NoError(t, err)
+	assert.Nil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+
+	report, err = New(OptionWithWorkspace("development")).Scan(dir)
+	require.NoError(t, err)
+	assert.NotNil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+}
+
+func Test_ScanWithDefaultConfig(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+		".tfsec/config.yml": `
+severity_overrides:
+  aws-s3-no-public-access-with-acl: LOW
+exclude:
+  - aws-s3-enable-versioning
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+	result := findResult(report, "aws-s3-no-public-access-with-acl")
+	require.NotNil(t, result)
+	assert.Equal(t, severity.Low, result.Severity)
+	assert.Nil(t, findResult(report, "aws-s3-enable-versioning"))
+
+	report, err = New(
+		OptionWithSeverityOverrides(map[string]severity.Severity{"AWS001": severity.Critical}),
+		OptionFilterResults("aws-s3-no-public-access-with-acl"),
+	).Scan(dir)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, severity.Critical, report.Results[0].Severity)
+}
+
+func Test_ScanWithGate(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+
+resource "aws_s3_bucket" "assets" {
+	#tfsec:ignore:aws-s3-no-public-access-with-acl
+	acl = "public-read"
+}
+`,
+		".tfsec/config.yml": `
+gate:
+  rules:
+    aws-s3-no-public-access-with-acl: 0
+`,
+	})
+
+	report, err := New(OptionIncludeIgnored()).Scan(dir)
+	require.NoError(t, err)
+	require.NotNil(t, report.Gate)
+	assert.False(t, report.Gate.Passed)
+	require.Len(t, report.Gate.Checks, 1)
+	// the ignored result is reported, but not counted
+	assert.Equal(t, GateCheck{Threshold: "rule aws-s3-no-public-access-with-acl", Count: 1, Max: 0, Passed: false}, report.Gate.Checks[0])
+
+	report, err = New(OptionWithGate("rule:aws-s3-no-public-access-with-acl=1", "CRITICAL=0")).Scan(dir)
+	require.NoError(t, err)
+	require.NotNil(t, report.Gate)
+	assert.True(t, report.Gate.Passed)
+	assert.Len(t, report.Gate.Checks, 2)
+
+	_, err = New(OptionWithGate("extreme=1")).Scan(dir)
+	assert.Error(t, err)
+
+	report, err = New().Scan(createFiles(t, map[string]string{"main.tf": publicBucket}))
+	require.NoError(t, err)
+	assert.Nil(t, report.Gate)
+}
+
+func Test_ScanReportsDiagnostics(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+		"broken.tf": `resource "aws_s3_bucket" "broken" {`,
+	})
+
+	_, err := New().Scan(dir)
+	assert.Error(t, err)
+
+	report, err := New(OptionIgnoreHCLErrors()).Scan(dir)
+	require.NoError(t, err)
+	assert.NotNil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+	require.NotEmpty(t, report.Diagnostics)
+	assert.Equal(t, DiagnosticError, report.Diagnostics[0].Severity)
+	require.NotNil(t, report.Diagnostics[0].Range)
+	assert.Equal(t, filepath.Join(dir, "broken.tf"), report.Diagnostics[0].Range.Filename)
+}
+
+func Test_ScanFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"infra/main.tf":       &fstest.MapFile{Data: []byte(publicBucket)},
+		"infra/public.tfvars": &fstest.MapFile{Data: []byte(`acl = "public-read"`)},
+	}
+
+	report, err := New(OptionWithFS(fsys)).Scan("infra")
+	require.NoError(t, err)
+	assert.Nil(t, findResult(report, "aws-s3-no-public-access-with-acl"))
+
+	report, err = New(OptionWithFS(fsys), OptionWithTFVarsPaths("infra/public.tfvars")).Scan("infra")
+	require.NoError(t, err)
+	result := findResult(report, "aws-s3-no-public-access-with-acl")
+	require.NotNil(t, result)
+	assert.Equal(t, filepath.Join("infra", "main.tf"), result.Range.Filename)
+
+	_, err = New(OptionWithFS(fsys)).Scan("missing")
+	assert.Error(t, err)
+}
+
+func Test_ScanWithIncludedChecks(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+		".tfsec/config.yml": `
+include:
+  - aws-s3-*-acl
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, "aws-s3-no-public-access-with-acl", report.Results[0].RuleID)
+
+	report, err = New(OptionFilterResults("*-versioning")).Scan(dir)
+	require.NoError(t, err)
+	assert.Empty(t, report.Results)
+}
+
+func Test_ScanWithPathOverrides(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+module "sandbox" {
+	source = "./sandbox"
+}
+module "prod" {
+	source = "./prod"
+}
+`,
+		"sandbox/main.tf": `resource "aws_instance" "web" {}`,
+		"prod/main.tf":    `resource "aws_instance" "web" {}`,
+		"checks/instance_tfchecks.yaml": `
+checks:
+  - code: NoInstances
+    description: Production instances must specify an AMI
+    requiredTypes:
+      - resource
+    requiredLabels:
+      - aws_instance
+    errorMessage: an instance without an AMI was found
+    matchSpec:
+      action: isPresent
+      name: ami
+    severity: ERROR
+`,
+		".tfsec/config.yml": `
+path_overrides:
+  - paths:
+      - prod/**
+    custom_check_dir: checks
+`,
+	})
+
+	report, err := New(OptionFilterResults("custom-custom-*")).Scan(dir)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, filepath.Join(dir, "prod", "main.tf"), report.Results[0].Range.Filename)
+}
+
+func Test_ScanWithIgnoreFile(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+module "network" {
+	source = "./network"
+}
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+		"network/main.tf": `
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read"
+}
+`,
+		".tfsec/ignores.yml": `
+ignores:
+  - rule: AWS001
+    resource: module.network.aws_s3_bucket.logs
+    justification: The network bucket only holds public keys
+    owner: security
+  - rule: aws-s3-enable-versioning
+    path: network/*.tf
+    justification: Keys are never overwritten
+    owner: security
+    expiry: 2099-12-31
+  - rule: aws-s3-enable-bucket-logging
+    justification: This has expired
+    owner: security
+    expiry: 2000-01-01
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for _, result := range report.Results {
+		counts[result.RuleID+" "+result.Module]++
+	}
+	assert.Equal(t, 0, counts["aws-s3-no-public-access-with-acl module.network"])
+	assert.NotZero(t, counts["aws-s3-no-public-access-with-acl root"])
+	assert.Equal(t, 0, counts["aws-s3-enable-versioning module.network"])
+	assert.NotZero(t, counts["aws-s3-enable-versioning root"])
+	assert.NotZero(t, counts["aws-s3-enable-bucket-logging module.network"])
+
+	report, err = New(OptionIncludeIgnored()).Scan(dir)
+	require.NoError(t, err)
+	var ignoredFound bool
+	for _, result := range report.Results {
+		if result.RuleID == "aws-s3-no-public-access-with-acl" && result.Module == "module.network" {
+			ignoredFound = true
+		}
+	}
+	assert.True(t, ignoredFound)
+
+	report, err = New(OptionReportIgnores()).Scan(dir)
+	require.NoError(t, err)
+	expired := findResult(report, "general-ignores-no-expired")
+	require.NotNil(t, expired)
+	assert.Equal(t, filepath.Join(dir, ".tfsec", "ignores.yml"), expired.Range.Filename)
+	assert.Equal(t, 12, expired.Range.StartLine)
+	assert.Nil(t, findResult(report, "general-ignores-no-unused"))
+}
+
+func Test_ScanWithRequiredIgnoreReasons(t *testing.T) {
+	dir := createFiles(t, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "assets" {
+	acl = "public-read" # tfsec:ignore:aws-s3-no-public-access-with-acl reason="static assets bucket"
+}
+resource "aws_s3_bucket" "logs" {
+	acl = "public-read" # tfsec:ignore:aws-s3-no-public-access-with-acl
+}
+`,
+		".tfsec/config.yml": `
+require_ignore_reason: true
+`,
+	})
+
+	report, err := New().Scan(dir)
+	require.NoError(t, err)
+	var lines []int
+	for _, result := range report.Results {
+		if result.RuleID == "aws-s3-no-public-access-with-acl" {
+			lines = append(lines, result.Range.StartLine)
+		}
+	}
+	assert.Equal(t, []int{6}, lines)
+
+	report, err = New(OptionIncludeIgnored()).Scan(dir)
+	require.NoError(t, err)
+	var suppressed []Suppression
+	for _, result := range report.Results {
+		if result.Suppression != nil {
+			suppressed = append(suppressed, *result.Suppression)
+		}
+	}
+	require.Len(t, suppressed, 1)
+	assert.True(t, suppressed[0].InSource)
+	assert.Equal(t, "static assets bucket", suppressed[0].Reason)
+	assert.Equal(t, 3, suppressed[0].Range.StartLine)
+}